@@ -17,7 +17,9 @@ import (
 	docs "github.com/tenteedee/gopher-social/docs" // required for swagger to work
 	"github.com/tenteedee/gopher-social/internal/auth"
 	"github.com/tenteedee/gopher-social/internal/mailer"
+	"github.com/tenteedee/gopher-social/internal/media"
 	ratelimiter "github.com/tenteedee/gopher-social/internal/rate-limiter"
+	"github.com/tenteedee/gopher-social/internal/search"
 	"github.com/tenteedee/gopher-social/internal/store"
 	"github.com/tenteedee/gopher-social/internal/store/cache"
 )
@@ -30,6 +32,8 @@ type application struct {
 	mailer        mailer.Client
 	authenticator auth.Authenticator
 	rateLimiter   ratelimiter.Limiter
+	searchIndexer search.Indexer
+	blobs         media.BlobStore
 }
 
 type config struct {
@@ -42,6 +46,47 @@ type config struct {
 	auth        authConfig
 	redisCfg    redisConfig
 	rateLimiter ratelimiter.Config
+	search      searchConfig
+	reactions   reactionsConfig
+	trash       trashConfig
+	media       mediaConfig
+	jobs        jobsConfig
+}
+
+type reactionsConfig struct {
+	types []string
+}
+
+type trashConfig struct {
+	// retention is how long deleted posts and comments can be restored before they are purged.
+	retention time.Duration
+}
+
+type mediaConfig struct {
+	blobs        media.Config
+	maxImageSize int64
+	maxVideoSize int64
+	// orphanTTL is how long an upload can wait to be attached to a post before it is deleted.
+	orphanTTL time.Duration
+}
+
+type jobsConfig struct {
+	enabled                 bool
+	trendsInterval          time.Duration
+	trendsRetention         time.Duration
+	suggestionsInterval     time.Duration
+	suggestionsMaxAge       time.Duration
+	suggestionsBatchSize    int
+	publisherInterval       time.Duration
+	purgeInterval           time.Duration
+	mediaCleanupInterval    time.Duration
+	mediaProcessingInterval time.Duration
+	pollsInterval           time.Duration
+}
+
+type searchConfig struct {
+	backend   string
+	indexPath string
 }
 
 type mailConfig struct {
@@ -102,9 +147,9 @@ func (app *application) mount() *chi.Mux {
 		// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
 		AllowedOrigins: []string{"https://*", "http://*"},
 		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "If-None-Match"},
+		ExposedHeaders:   []string{"Link", "ETag"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
@@ -139,7 +184,38 @@ func (app *application) mount() *chi.Mux {
 				r.Get("/", app.getPostByIdHandler)
 				r.Patch("/", app.CheckPostOwnership("moderator", app.updatePostHandler))
 				r.Delete("/", app.CheckPostOwnership("admin", app.deletePostHandler))
-				r.Post("/comments", app.createCommentHandler)
+				r.Get("/revisions", app.getPostRevisionsHandler)
+				r.Get("/revisions/diff", app.diffPostRevisionsHandler)
+				r.Put("/revisions/{version}/restore", app.RequireRole("moderator", app.restorePostRevisionHandler))
+				r.Put("/bookmark", app.bookmarkPostHandler)
+				r.Delete("/bookmark", app.removeBookmarkHandler)
+				r.Post("/repost", app.repostHandler)
+				r.Delete("/repost", app.undoRepostHandler)
+				r.Post("/quote", app.quotePostHandler)
+				r.Post("/poll/votes", app.votePollHandler)
+				r.Route("/comments", func(r chi.Router) {
+					r.Get("/", app.getCommentsHandler)
+					r.Post("/", app.createCommentHandler)
+
+					r.Route("/{commentID}", func(r chi.Router) {
+						r.With(app.shownCommentContextMiddleware).Get("/replies", app.getCommentRepliesHandler)
+
+						r.Group(func(r chi.Router) {
+							r.Use(app.commentContextMiddleware)
+
+							r.Patch("/", app.CheckCommentOwnership("moderator", app.updateCommentHandler))
+							r.Delete("/", app.CheckCommentOwnership("moderator", app.deleteCommentHandler))
+							r.Get("/reactions", app.getCommentReactionsHandler)
+							r.Put("/reactions/{reaction}", app.addCommentReactionHandler)
+							r.Delete("/reactions/{reaction}", app.removeCommentReactionHandler)
+						})
+					})
+				})
+				r.Get("/reactions", app.getPostReactionsHandler)
+				r.Put("/reactions/{reaction}", app.addPostReactionHandler)
+				r.Delete("/reactions/{reaction}", app.removePostReactionHandler)
+				r.Put("/moderate", app.RequireRole("moderator", app.moderatePostHandler))
+				r.Put("/unmoderate", app.RequireRole("moderator", app.unmoderatePostHandler))
 			})
 		})
 
@@ -155,12 +231,66 @@ func (app *application) mount() *chi.Mux {
 
 				r.Put("/follow", app.followUserHandler)
 				r.Put("/unfollow", app.unfollowUserHandler)
+				r.Get("/followers", app.getFollowersHandler)
+				r.Get("/following", app.getFollowingHandler)
+				r.Put("/block", app.blockUserHandler)
+				r.Put("/unblock", app.unblockUserHandler)
+				r.Put("/mute", app.muteUserHandler)
+				r.Put("/unmute", app.unmuteUserHandler)
 			})
 
 			r.Group(func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
 				r.Get("/feed", app.getUserFeedHandler)
 			})
+
+			r.Route("/me", func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+
+				r.Put("/privacy", app.updatePrivacyHandler)
+				r.Get("/follow-requests", app.getFollowRequestsHandler)
+				r.Put("/follow-requests/{requesterID}/approve", app.approveFollowRequestHandler)
+				r.Put("/follow-requests/{requesterID}/reject", app.rejectFollowRequestHandler)
+				r.Get("/blocks", app.getBlockedUsersHandler)
+				r.Get("/mutes", app.getMutedUsersHandler)
+				r.Get("/suggestions", app.getSuggestionsHandler)
+				r.Get("/drafts", app.getDraftsHandler)
+				r.Get("/trash", app.getTrashHandler)
+				r.Put("/trash/{kind}/{itemID}/restore", app.restoreTrashItemHandler)
+				r.Get("/bookmarks", app.getBookmarksHandler)
+				r.Get("/bookmark-collections", app.getBookmarkCollectionsHandler)
+				r.Post("/bookmark-collections", app.createBookmarkCollectionHandler)
+				r.Delete("/bookmark-collections/{collectionID}", app.deleteBookmarkCollectionHandler)
+				r.Get("/mute-filters", app.getMuteFiltersHandler)
+				r.Post("/mute-filters", app.createMuteFilterHandler)
+				r.Delete("/mute-filters/{filterID}", app.deleteMuteFilterHandler)
+				r.Get("/notifications", app.getNotificationsHandler)
+				r.Put("/notifications/read", app.markNotificationsReadHandler)
+			})
+		})
+
+		r.Route("/tags", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/following", app.getFollowedTagsHandler)
+			r.Get("/autocomplete", app.autocompleteTagsHandler)
+			r.Put("/{tag}/follow", app.followTagHandler)
+			r.Put("/{tag}/unfollow", app.unfollowTagHandler)
+		})
+
+		r.Route("/media", func(r chi.Router) {
+			r.With(app.AuthTokenMiddleware).Post("/", app.uploadMediaHandler)
+
+			if local, ok := app.blobs.(*media.LocalStore); ok {
+				r.Handle("/files/*", app.mediaFileServer(local.Dir()))
+			}
+		})
+
+		r.With(app.AuthTokenMiddleware).Get("/search", app.searchHandler)
+
+		r.Route("/trends", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/tags", app.getTrendingTagsHandler)
+			r.Get("/posts", app.getTrendingPostsHandler)
 		})
 
 		r.Route("/authentication", func(r chi.Router) {
@@ -188,6 +318,10 @@ func (app *application) serve(mux *chi.Mux) error {
 
 	shutdown := make(chan error)
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	jobs := app.startBackgroundJobs(jobsCtx)
+
 	go func() {
 		quit := make(chan os.Signal, 1)
 
@@ -199,6 +333,9 @@ func (app *application) serve(mux *chi.Mux) error {
 
 		app.logger.Infow("signal caught", "signal", s)
 
+		stopJobs()
+		jobs.Wait()
+
 		shutdown <- server.Shutdown(ctx)
 	}()
 
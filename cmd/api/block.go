@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+// Block User godoc
+//
+//	@Summary		Block a user
+//	@Description	Block a user. Follows between both users are removed and neither can follow, comment on or view the other
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		204	{object}	nil
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/block [put]
+func (app *application) blockUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.updateRelation(w, r, app.store.Block.Block, "cannot block yourself")
+}
+
+// Unblock User godoc
+//
+//	@Summary		Unblock a user
+//	@Description	Unblock a user. Follows removed by the block are not restored
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		204	{object}	nil
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/unblock [put]
+func (app *application) unblockUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.updateRelation(w, r, app.store.Block.Unblock, "cannot unblock yourself")
+}
+
+// Mute User godoc
+//
+//	@Summary		Mute a user
+//	@Description	Mute a user. Their posts and comments are hidden from your feed and comment lists without them knowing
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		204	{object}	nil
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/mute [put]
+func (app *application) muteUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.updateRelation(w, r, app.store.Block.Mute, "cannot mute yourself")
+}
+
+// Unmute User godoc
+//
+//	@Summary		Unmute a user
+//	@Description	Unmute a user
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		204	{object}	nil
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/unmute [put]
+func (app *application) unmuteUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.updateRelation(w, r, app.store.Block.Unmute, "cannot unmute yourself")
+}
+
+func (app *application) updateRelation(
+	w http.ResponseWriter,
+	r *http.Request,
+	update func(context.Context, int64, int64) error,
+	selfMessage string,
+) {
+	user := getUserFromContext(r)
+
+	targetID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	if user.ID == targetID {
+		app.badRequest(w, r, errors.New(selfMessage))
+		return
+	}
+
+	if err := update(r.Context(), user.ID, targetID); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		case store.ErrConflict:
+			app.conflictResponse(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	app.invalidateSuggestions(r.Context(), user.ID, targetID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get Blocked Users godoc
+//
+//	@Summary		Lists blocked users
+//	@Description	Lists the users the authenticated user blocked, most recent first
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.BlockedUser
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/blocks [get]
+func (app *application) getBlockedUsersHandler(w http.ResponseWriter, r *http.Request) {
+	app.listRelations(w, r, app.store.Block.GetBlocked)
+}
+
+// Get Muted Users godoc
+//
+//	@Summary		Lists muted users
+//	@Description	Lists the users the authenticated user muted, most recent first
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.BlockedUser
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/mutes [get]
+func (app *application) getMutedUsersHandler(w http.ResponseWriter, r *http.Request) {
+	app.listRelations(w, r, app.store.Block.GetMuted)
+}
+
+func (app *application) listRelations(
+	w http.ResponseWriter,
+	r *http.Request,
+	list func(context.Context, int64, store.PaginationQuery) ([]store.BlockedUser, error),
+) {
+	pq := store.PaginationQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	pq, err := pq.Parse(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(pq); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	users, err := list(r.Context(), user.ID, pq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, users); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
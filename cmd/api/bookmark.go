@@ -0,0 +1,290 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+type CreateBookmarkCollectionPayload struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type BookmarksResponse struct {
+	Bookmarks  []store.Bookmark `json:"bookmarks"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// Bookmark Post godoc
+//
+//	@Summary		Bookmarks a post
+//	@Description	Privately saves a post, optionally into a collection. Bookmarking again moves the bookmark to the given collection
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int	true	"Post ID"
+//	@Param			collection_id	query		int	false	"Collection ID"
+//	@Success		200				{object}	store.Bookmark
+//	@Failure		400				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/bookmark [put]
+func (app *application) bookmarkPostHandler(w http.ResponseWriter, r *http.Request) {
+	post, err := getPostFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	collectionID, err := parseOptionalID(r.URL.Query().Get("collection_id"))
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	bookmark, err := app.store.Bookmarks.Save(r.Context(), user.ID, post.ID, collectionID)
+	if err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	bookmark.Post = *post
+	bookmark.Post.Bookmarked = true
+
+	if err := app.jsonResponse(w, http.StatusOK, bookmark); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Remove Bookmark godoc
+//
+//	@Summary		Removes a bookmark
+//	@Description	Removes the bookmark of a post
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		204	{object}	nil
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/bookmark [delete]
+func (app *application) removeBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	post, err := getPostFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.Bookmarks.Remove(r.Context(), user.ID, post.ID); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get Bookmarks godoc
+//
+//	@Summary		Lists bookmarks
+//	@Description	Lists the bookmarks of the authenticated user, newest first. Pass next_cursor as cursor to get the next page
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Param			collection_id	query		int		false	"Only this collection"
+//	@Param			limit			query		int		false	"Limit"
+//	@Param			cursor			query		string	false	"Cursor"
+//	@Success		200				{object}	BookmarksResponse
+//	@Failure		400				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/bookmarks [get]
+func (app *application) getBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	cq := store.CursorQuery{
+		Limit: 20,
+	}
+
+	cq, err := cq.Parse(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(cq); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	collectionID, err := parseOptionalID(r.URL.Query().Get("collection_id"))
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	bookmarks, err := app.store.Bookmarks.List(r.Context(), user.ID, store.BookmarkQuery{
+		CursorQuery:  cq,
+		CollectionID: collectionID,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := BookmarksResponse{Bookmarks: bookmarks}
+	if int64(len(bookmarks)) == cq.Limit {
+		response.NextCursor = store.EncodeCursor(bookmarks[len(bookmarks)-1].ID)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Create Bookmark Collection godoc
+//
+//	@Summary		Creates a bookmark collection
+//	@Description	Creates a named collection to file bookmarks into
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateBookmarkCollectionPayload	true	"Collection"
+//	@Success		201		{object}	store.BookmarkCollection
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/bookmark-collections [post]
+func (app *application) createBookmarkCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateBookmarkCollectionPayload
+	if err := ReadJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	collection := store.BookmarkCollection{
+		UserID: user.ID,
+		Name:   payload.Name,
+	}
+
+	if err := app.store.Bookmarks.CreateCollection(r.Context(), &collection); err != nil {
+		switch err {
+		case store.ErrConflict:
+			app.conflictResponse(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, collection); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Get Bookmark Collections godoc
+//
+//	@Summary		Lists bookmark collections
+//	@Description	Lists the bookmark collections of the authenticated user
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	[]store.BookmarkCollection
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/bookmark-collections [get]
+func (app *application) getBookmarkCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	collections, err := app.store.Bookmarks.GetCollections(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, collections); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Delete Bookmark Collection godoc
+//
+//	@Summary		Deletes a bookmark collection
+//	@Description	Deletes a bookmark collection, its bookmarks are kept unfiled
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Param			collectionID	path		int	true	"Collection ID"
+//	@Success		204				{object}	nil
+//	@Failure		400				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/bookmark-collections/{collectionID} [delete]
+func (app *application) deleteBookmarkCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	collectionID, err := strconv.ParseInt(chi.URLParam(r, "collectionID"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.Bookmarks.DeleteCollection(r.Context(), user.ID, collectionID); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseOptionalID(s string) (*int64, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
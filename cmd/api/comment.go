@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tenteedee/gopher-social/internal/search"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+type commentKey string
+
+const commentContextKey commentKey = "comment"
+
+type UpdateCommentPayload struct {
+	Content string `json:"content" validate:"required,max=1000"`
+}
+
+type CommentsResponse struct {
+	Comments   []*store.Comment `json:"comments"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+func (app *application) commentContextMiddleware(next http.Handler) http.Handler {
+	return app.commentContext(next, app.store.Comment.GetByID)
+}
+
+// shownCommentContextMiddleware also finds the tombstones shown in a thread,
+// so the replies of a deleted comment can still be listed.
+func (app *application) shownCommentContextMiddleware(next http.Handler) http.Handler {
+	return app.commentContext(next, app.store.Comment.GetShownByID)
+}
+
+func (app *application) commentContext(
+	next http.Handler,
+	get func(ctx context.Context, postID, commentID, viewerID int64) (*store.Comment, error),
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		post, err := getPostFromContext(r)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+
+		viewer := getUserFromContext(r)
+
+		comment, err := get(r.Context(), post.ID, commentID, viewer.ID)
+		if err != nil {
+			switch err {
+			case store.ErrorNotFound:
+				app.notFound(w, r, err)
+				return
+			default:
+				app.internalServerError(w, r, err)
+				return
+			}
+		}
+		ctx := context.WithValue(r.Context(), commentContextKey, comment)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getCommentFromContext(r *http.Request) (*store.Comment, error) {
+	comment, ok := r.Context().Value(commentContextKey).(*store.Comment)
+	if !ok {
+		return nil, store.ErrorNotFound
+	}
+	return comment, nil
+}
+
+// Get Comments godoc
+//
+//	@Summary		Lists the comments of a post
+//	@Description	Lists the top-level comments of a post, oldest first, each with the first few of its replies on a few levels. Pass next_cursor as cursor to get the next page
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int		true	"Post ID"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			cursor	query		string	false	"Cursor"
+//	@Success		200		{object}	CommentsResponse
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments [get]
+func (app *application) getCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	post, err := getPostFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.listComments(w, r, func(ctx context.Context, viewerID int64, cq store.CursorQuery) ([]*store.Comment, error) {
+		return app.store.Comment.GetCommentByPostId(ctx, post.ID, viewerID, cq)
+	})
+}
+
+// Get Comment Replies godoc
+//
+//	@Summary		Lists the replies of a comment
+//	@Description	Lists the replies of a comment, oldest first, each with the first few of its replies on a few levels. Comments flagged has_more_replies have replies left out, to list the same way, deleted ones included
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int		true	"Post ID"
+//	@Param			commentID	path		int		true	"Comment ID"
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			cursor		query		string	false	"Cursor"
+//	@Success		200			{object}	CommentsResponse
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/{commentID}/replies [get]
+func (app *application) getCommentRepliesHandler(w http.ResponseWriter, r *http.Request) {
+	comment, err := getCommentFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.listComments(w, r, func(ctx context.Context, viewerID int64, cq store.CursorQuery) ([]*store.Comment, error) {
+		return app.store.Comment.GetReplies(ctx, comment.PostID, comment.ID, viewerID, cq)
+	})
+}
+
+func (app *application) listComments(
+	w http.ResponseWriter,
+	r *http.Request,
+	list func(ctx context.Context, viewerID int64, cq store.CursorQuery) ([]*store.Comment, error),
+) {
+	cq := store.CursorQuery{
+		Limit: 20,
+	}
+
+	cq, err := cq.Parse(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(cq); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	viewer := getUserFromContext(r)
+
+	comments, err := list(r.Context(), viewer.ID, cq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := CommentsResponse{Comments: comments}
+	if int64(len(comments)) == cq.Limit {
+		response.NextCursor = store.EncodeCursor(comments[len(comments)-1].ID)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Update Comment godoc
+//
+//	@Summary		Edits a comment
+//	@Description	Edits the content of a comment and marks it as edited. Only the author or a moderator can edit it
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int						true	"Post ID"
+//	@Param			commentID	path		int						true	"Comment ID"
+//	@Param			payload		body		UpdateCommentPayload	true	"Comment payload"
+//	@Success		200			{object}	store.Comment
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/{commentID} [patch]
+func (app *application) updateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment, err := getCommentFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	var payload UpdateCommentPayload
+	if err := ReadJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	comment.Content = payload.Content
+
+	if err := app.store.Comment.Update(r.Context(), comment); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	doc, err := search.CommentDocument(comment)
+	app.indexDocument(r.Context(), doc, err)
+
+	if err := app.jsonResponse(w, http.StatusOK, comment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Delete Comment godoc
+//
+//	@Summary		Deletes a comment
+//	@Description	Moves a comment to the trash of its author. While it has replies it stays in the thread as a tombstone. Only the author or a moderator can delete it
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int	true	"Post ID"
+//	@Param			commentID	path		int	true	"Comment ID"
+//	@Success		204			{object}	nil
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/{commentID} [delete]
+func (app *application) deleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment, err := getCommentFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.Comment.Delete(r.Context(), comment.ID, user.ID); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	app.publishSearchEvent(r.Context(), search.Event{
+		Action:   search.ActionDelete,
+		Document: search.Document{Type: store.SearchTypeComments, ID: comment.ID},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+func TestCreateComment(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	users := app.store.User.(*store.MockUserStore)
+	users.Users[1] = &store.User{ID: 1, Username: "author"}
+	users.Users[2] = &store.User{ID: 2, Username: "commenter"}
+
+	posts := app.store.Post.(*store.MockPostStore)
+	posts.Posts[1] = &store.Post{ID: 1, UserID: 1, Title: "public"}
+	posts.Posts[2] = &store.Post{ID: 2, UserID: 1, Title: "private"}
+	posts.Hidden = map[int64]bool{2: true}
+
+	comments := app.store.Comment.(*store.MockCommentStore)
+
+	newRequest := func(t *testing.T, postID, body string, userID int64) *http.Request {
+		return authedRequest(t, app, userID, http.MethodPost, "/v1/posts/"+postID+"/comments", strings.NewReader(body))
+	}
+
+	t.Run("should not allow unauthenticated requests", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/v1/posts/1/comments", strings.NewReader(`{"content":"hello"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should use the authenticated user as author", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "1", `{"content":"hello"}`, 2), mux)
+
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		var response struct {
+			Data store.Comment `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		if response.Data.UserID != 2 || response.Data.User.Username != "commenter" {
+			t.Errorf("expected the comment to be authored by user 2, got user %d", response.Data.UserID)
+		}
+		if last := comments.Comments[len(comments.Comments)-1]; last.UserID != 2 || last.PostID != 1 {
+			t.Errorf("expected a comment by user 2 on post 1, got user %d on post %d", last.UserID, last.PostID)
+		}
+	})
+
+	t.Run("should reject a user id in the payload", func(t *testing.T) {
+		count := len(comments.Comments)
+
+		rr := executeRequest(newRequest(t, "1", `{"user_id":1,"content":"hello"}`, 2), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+		if len(comments.Comments) != count {
+			t.Error("expected no comment to be created")
+		}
+	})
+
+	t.Run("should validate the content", func(t *testing.T) {
+		tests := map[string]string{
+			"missing":  `{}`,
+			"empty":    `{"content":""}`,
+			"too long": `{"content":"` + strings.Repeat("a", 1001) + `"}`,
+		}
+
+		for name, body := range tests {
+			t.Run(name, func(t *testing.T) {
+				rr := executeRequest(newRequest(t, "1", body, 2), mux)
+
+				checkResponseCode(t, http.StatusBadRequest, rr.Code)
+			})
+		}
+	})
+
+	t.Run("should not allow comments on posts the user cannot see", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "2", `{"content":"hello"}`, 2), mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should allow the author to comment on their hidden post", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "2", `{"content":"hello"}`, 1), mux)
+
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("should not reply to a comment of another post", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "2", `{"content":"hello","parent_comment_id":1}`, 1), mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestGetCommentReplies(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	users := app.store.User.(*store.MockUserStore)
+	users.Users[1] = &store.User{ID: 1, Username: "author"}
+
+	posts := app.store.Post.(*store.MockPostStore)
+	posts.Posts[1] = &store.Post{ID: 1, UserID: 1, Title: "public"}
+
+	parentID := int64(1)
+	comments := app.store.Comment.(*store.MockCommentStore)
+	comments.Comments = []*store.Comment{
+		{ID: 1, PostID: 1, Deleted: true, ReplyCount: 1},
+		{ID: 2, PostID: 1, UserID: 1, ParentCommentID: &parentID, Content: "reply"},
+		{ID: 3, PostID: 1, UserID: 1, Deleted: true},
+	}
+
+	newRequest := func(t *testing.T, method, path string) *http.Request {
+		return authedRequest(t, app, 1, method, path, nil)
+	}
+
+	t.Run("should list the replies of a tombstone", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodGet, "/v1/posts/1/comments/1/replies"), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var response struct {
+			Data struct {
+				Comments []store.Comment `json:"comments"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Data.Comments) != 1 || response.Data.Comments[0].ID != 2 {
+			t.Errorf("expected reply 2, got %+v", response.Data.Comments)
+		}
+	})
+
+	t.Run("should not find a deleted comment without replies", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodGet, "/v1/posts/1/comments/3/replies"), mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should not edit a tombstone", func(t *testing.T) {
+		req := newRequest(t, http.MethodPatch, "/v1/posts/1/comments/1")
+		req.Body = io.NopCloser(strings.NewReader(`{"content":"back"}`))
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+// publishBatchSize is how many due posts a publishing batch makes live at most.
+const publishBatchSize = 100
+
+// applyPostStatus sets the status and publish time asked for by a payload on
+// the post. New posts are published unless asked otherwise, a scheduled post
+// needs a publish time in the future and a published post stays published.
+func applyPostStatus(post *store.Post, status *string, publishAt *time.Time) error {
+	if post.Status == store.PostStatusPublished {
+		if (status != nil && *status != store.PostStatusPublished) || publishAt != nil {
+			return errors.New("a published post cannot go back to draft or be scheduled")
+		}
+		return nil
+	}
+
+	if status != nil {
+		post.Status = *status
+	} else if post.Status == "" {
+		post.Status = store.PostStatusPublished
+	}
+
+	if post.Status != store.PostStatusScheduled {
+		if publishAt != nil {
+			return errors.New("publish_at is only accepted for scheduled posts")
+		}
+		post.PublishAt = nil
+		return nil
+	}
+
+	if publishAt != nil {
+		if !publishAt.After(time.Now()) {
+			return errors.New("publish_at must be in the future")
+		}
+		at := publishAt.UTC().Format(time.RFC3339)
+		post.PublishAt = &at
+	}
+	if post.PublishAt == nil {
+		return errors.New("publish_at is required to schedule a post")
+	}
+
+	return nil
+}
+
+// Get Drafts godoc
+//
+//	@Summary		Lists drafts
+//	@Description	Lists the drafts and scheduled posts of the authenticated user, the scheduled ones due first. They are edited through PATCH /posts/{id}
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.Post
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/drafts [get]
+func (app *application) getDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	page := store.PaginationQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	page, err := page.Parse(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(page); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	drafts, err := app.store.Post.GetDrafts(r.Context(), user.ID, page)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, drafts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// publishScheduledJob makes the scheduled posts that are due live, in batches
+// of publishBatchSize so a backlog left by downtime is caught up statement by
+// statement. Feeds pick them up from their publish time, and they are indexed
+// for search only now.
+func (app *application) publishScheduledJob(ctx context.Context) error {
+	now := time.Now()
+
+	for {
+		posts, err := app.store.Post.PublishScheduled(ctx, now, publishBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(posts) == 0 {
+			return nil
+		}
+
+		for _, post := range posts {
+			app.indexPost(ctx, post)
+		}
+	}
+}
@@ -97,3 +97,47 @@ func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http
 
 	WriteErrorJSON(w, http.StatusTooManyRequests, "rate limit exceeded, retry after: "+retryAfter)
 }
+
+func (app *application) preconditionFailed(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw(
+		"precondition failed",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"error", err.Error(),
+	)
+
+	WriteErrorJSON(w, http.StatusPreconditionFailed, "precondition failed")
+}
+
+func (app *application) preconditionRequired(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw(
+		"precondition required",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"error", err.Error(),
+	)
+
+	WriteErrorJSON(w, http.StatusPreconditionRequired, "precondition required")
+}
+
+func (app *application) payloadTooLarge(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw(
+		"payload too large",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"error", err.Error(),
+	)
+
+	WriteErrorJSON(w, http.StatusRequestEntityTooLarge, "payload too large")
+}
+
+func (app *application) unsupportedMediaType(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw(
+		"unsupported media type",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"error", err.Error(),
+	)
+
+	WriteErrorJSON(w, http.StatusUnsupportedMediaType, "unsupported media type")
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+var (
+	errMissingIfMatch = errors.New("If-Match header is required")
+	errETagMismatch   = errors.New("If-Match does not match the current version")
+)
+
+// postBodyETag is the entity tag of the post as fetched by a viewer: the
+// version followed by a hash of the body, so it also changes with the
+// comments, reactions, bookmark and poll tallies that make no new version.
+func postBodyETag(post *store.Post, body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + strconv.FormatInt(post.Version, 10) + "." + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// writePost answers a change to a post with the post, tagged by postBodyETag
+// over the body sent like a GET tags it. The tag names the new version, so it
+// is what If-Match takes for the next change.
+func writePost(w http.ResponseWriter, post *store.Post) error {
+	body, err := encodeJSONResponse(post)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("ETag", postBodyETag(post, body))
+	return writeJSONBody(w, http.StatusOK, body)
+}
+
+// checkIfMatch requires an If-Match header naming the current version of the
+// post, so the client proves it saw the version it is about to change. Only
+// the version of a tag counts, any tag handed out for it matches.
+func checkIfMatch(r *http.Request, post *store.Post) error {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return errMissingIfMatch
+	}
+
+	version := strconv.FormatInt(post.Version, 10)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil
+		}
+		// weak tags never match If-Match
+		if !strings.HasPrefix(tag, `"`) {
+			continue
+		}
+
+		tag, _, _ = strings.Cut(strings.Trim(tag, `"`), ".")
+		if tag == version {
+			return nil
+		}
+	}
+
+	return errETagMismatch
+}
+
+// notModified tells whether If-None-Match already names etag.
+func notModified(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	return matchETag(header, etag)
+}
+
+// matchETag matches a list of entity tags, or "*", against etag. Tags are
+// compared weakly, as If-None-Match does.
+func matchETag(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (app *application) ifMatchError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case errMissingIfMatch:
+		app.preconditionRequired(w, r, err)
+	default:
+		app.preconditionFailed(w, r, err)
+	}
+}
@@ -9,7 +9,7 @@ import (
 // Get User Feed godoc
 //
 //	@Summary		Fetches the user feed
-//	@Description	Fetches the user feed
+//	@Description	Fetches the user feed: own posts, posts of followed users and posts with followed tags, minus content hidden by the viewer's mutes and mute filters
 //	@Tags			feed
 //	@Accept			json
 //	@Produce		json
@@ -26,8 +26,7 @@ import (
 //	@Security		ApiKeyAuth
 //	@Router			/users/feed [get]
 func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Request) {
-	// user := app.getCurrentUser(r)
-	// userID := user.ID
+	user := getUserFromContext(r)
 
 	fq := store.PaginationFeedQuery{
 		Limit:  10,
@@ -46,7 +45,7 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	posts, err := app.store.Post.GetByUserId(r.Context(), int64(20), fq)
+	posts, err := app.store.Post.GetByUserId(r.Context(), user.ID, fq)
 	if err != nil {
 		switch err {
 		case store.ErrorNotFound:
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type backgroundJob struct {
+	name     string
+	interval time.Duration
+	run      func(context.Context) error
+}
+
+func (app *application) backgroundJobs() []backgroundJob {
+	return []backgroundJob{
+		{
+			name:     "trends",
+			interval: app.config.jobs.trendsInterval,
+			run:      app.computeTrendsJob,
+		},
+		{
+			name:     "suggestions",
+			interval: app.config.jobs.suggestionsInterval,
+			run:      app.computeSuggestionsJob,
+		},
+		{
+			name:     "publisher",
+			interval: app.config.jobs.publisherInterval,
+			run:      app.publishScheduledJob,
+		},
+		{
+			name:     "purge",
+			interval: app.config.jobs.purgeInterval,
+			run:      app.purgeTrashJob,
+		},
+		{
+			name:     "media-cleanup",
+			interval: app.config.jobs.mediaCleanupInterval,
+			run:      app.cleanupMediaJob,
+		},
+		{
+			name:     "media-processing",
+			interval: app.config.jobs.mediaProcessingInterval,
+			run:      app.processMediaJob,
+		},
+		{
+			name:     "polls",
+			interval: app.config.jobs.pollsInterval,
+			run:      app.closePollsJob,
+		},
+	}
+}
+
+// startBackgroundJobs runs every job once right away and then on its interval
+// until ctx is cancelled. The returned WaitGroup is done once all jobs stopped.
+func (app *application) startBackgroundJobs(ctx context.Context) *sync.WaitGroup {
+	var wg sync.WaitGroup
+
+	if !app.config.jobs.enabled {
+		return &wg
+	}
+
+	for _, job := range app.backgroundJobs() {
+		wg.Add(1)
+		go func(job backgroundJob) {
+			defer wg.Done()
+
+			ticker := time.NewTicker(job.interval)
+			defer ticker.Stop()
+
+			for {
+				app.runBackgroundJob(ctx, job)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(job)
+	}
+
+	return &wg
+}
+
+func (app *application) runBackgroundJob(ctx context.Context, job backgroundJob) {
+	start := time.Now()
+
+	if err := job.run(ctx); err != nil {
+		app.logger.Errorw("background job failed", "job", job.name, "error", err)
+		return
+	}
+
+	app.logger.Infow("background job finished", "job", job.name, "duration", time.Since(start).String())
+}
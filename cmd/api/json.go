@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"net/http"
 )
@@ -39,10 +40,27 @@ func WriteErrorJSON(w http.ResponseWriter, status int, message string) error {
 	return WriteJSON(w, status, envelope{Error: message})
 }
 
+type dataEnvelope struct {
+	Data any `json:"data"`
+}
+
 func (app *application) jsonResponse(w http.ResponseWriter, status int, data any) error {
-	type envelope struct {
-		Data any `json:"data"`
+	return WriteJSON(w, status, dataEnvelope{Data: data})
+}
+
+// encodeJSONResponse encodes data the way jsonResponse writes it, for handlers
+// that need the body before answering.
+func encodeJSONResponse(data any) ([]byte, error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(dataEnvelope{Data: data}); err != nil {
+		return nil, err
 	}
+	return body.Bytes(), nil
+}
 
-	return WriteJSON(w, status, envelope{Data: data})
+func writeJSONBody(w http.ResponseWriter, status int, body []byte) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err := w.Write(body)
+	return err
 }
@@ -10,7 +10,9 @@ import (
 	"github.com/tenteedee/gopher-social/internal/db"
 	"github.com/tenteedee/gopher-social/internal/env"
 	"github.com/tenteedee/gopher-social/internal/mailer"
+	"github.com/tenteedee/gopher-social/internal/media"
 	ratelimiter "github.com/tenteedee/gopher-social/internal/rate-limiter"
+	"github.com/tenteedee/gopher-social/internal/search"
 	"github.com/tenteedee/gopher-social/internal/store"
 	"github.com/tenteedee/gopher-social/internal/store/cache"
 	"go.uber.org/zap"
@@ -87,6 +89,44 @@ func main() {
 			TimeFrame:            env.RateLimiterTimeFrame,
 			Enabled:              env.RateLimiterEnabled,
 		},
+		search: searchConfig{
+			backend:   env.SearchBackend,
+			indexPath: env.SearchIndexPath,
+		},
+		reactions: reactionsConfig{
+			types: append([]string{store.ReactionLike}, env.ReactionTypes...),
+		},
+		trash: trashConfig{
+			retention: env.TrashRetention,
+		},
+		media: mediaConfig{
+			blobs: media.Config{
+				Backend:     env.MediaBackend,
+				LocalDir:    env.MediaLocalDir,
+				PublicURL:   env.MediaPublicURL,
+				S3Endpoint:  env.S3Endpoint,
+				S3Region:    env.S3Region,
+				S3Bucket:    env.S3Bucket,
+				S3AccessKey: env.S3AccessKey,
+				S3SecretKey: env.S3SecretKey,
+			},
+			maxImageSize: int64(env.MediaMaxImageSize),
+			maxVideoSize: int64(env.MediaMaxVideoSize),
+			orphanTTL:    env.MediaOrphanTTL,
+		},
+		jobs: jobsConfig{
+			enabled:                 env.JobsEnabled,
+			trendsInterval:          env.TrendsInterval,
+			trendsRetention:         env.TrendsRetention,
+			suggestionsInterval:     env.SuggestionsInterval,
+			suggestionsMaxAge:       env.SuggestionsMaxAge,
+			suggestionsBatchSize:    env.SuggestionsBatchSize,
+			publisherInterval:       env.PublisherInterval,
+			purgeInterval:           env.PurgeInterval,
+			mediaCleanupInterval:    env.MediaCleanupInterval,
+			mediaProcessingInterval: env.MediaProcessingInterval,
+			pollsInterval:           env.PollsInterval,
+		},
 	}
 
 	// Logger
@@ -127,6 +167,24 @@ func main() {
 	storage := store.NewStorage(db)
 	cacheStorage := cache.NewRedisStorage(redisDB)
 
+	// Search
+	searchIndexer, err := search.New(cfg.search.backend, cfg.search.indexPath, db, storage)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer searchIndexer.Close()
+	logger.Infow("search backend ready", "backend", cfg.search.backend)
+
+	// Media
+	if cfg.media.blobs.Backend == "local" && cfg.media.blobs.PublicURL == "" {
+		cfg.media.blobs.PublicURL = "http://" + cfg.apiURL + "/v1/media/files"
+	}
+	blobs, err := media.NewBlobStore(cfg.media.blobs)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infow("media backend ready", "backend", cfg.media.blobs.Backend)
+
 	mailer := mailer.NewSendGridMailer(cfg.mail.sendgrid.apikey, cfg.mail.fromEmail)
 	// mailtrap, err := mailer.NewMailTrapClient(cfg.mail.mailTrap.apikey, cfg.mail.fromEmail)
 	// if err != nil {
@@ -143,6 +201,8 @@ func main() {
 		mailer:        mailer,
 		authenticator: JwtAuthenticator,
 		rateLimiter:   rateLimiter,
+		searchIndexer: searchIndexer,
+		blobs:         blobs,
 	}
 	mux := app.mount()
 
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tenteedee/gopher-social/internal/media"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+// mediaCleanupBatchSize is how many orphaned uploads a cleanup run removes at most.
+const mediaCleanupBatchSize = 500
+
+// mediaProcessingBatchSize is how many pending images a processing run handles at most.
+const mediaProcessingBatchSize = 50
+
+// Upload Media godoc
+//
+//	@Summary		Upload media
+//	@Description	Upload an image (JPEG, PNG, GIF, WebP) or a short video (MP4, WebM) as the multipart field "file". The type is told from the content, not the file name. EXIF and XMP metadata are stripped from images. Images are pending until their variants (thumbnail, feed, full), dimensions and blurhash are generated in the background. Attach the upload to a post through media_ids within MEDIA_ORPHAN_TTL, unattached uploads are deleted after that
+//	@Tags			media
+//	@Accept			mpfd
+//	@Produce		json
+//	@Param			file	formData	file	true	"Image or video"
+//	@Success		201		{object}	store.Media
+//	@Failure		400		{object}	error
+//	@Failure		413		{object}	error
+//	@Failure		415		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media [post]
+func (app *application) uploadMediaHandler(w http.ResponseWriter, r *http.Request) {
+	limit := max(app.config.media.maxImageSize, app.config.media.maxVideoSize)
+	// leave room for the multipart boundaries and headers
+	r.Body = http.MaxBytesReader(w, r.Body, limit+1<<20)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			app.payloadTooLarge(w, r, err)
+			return
+		}
+		app.badRequest(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	fileType, err := media.Sniff(head[:n])
+	if err != nil {
+		app.unsupportedMediaType(w, r, err)
+		return
+	}
+
+	maxSize := app.config.media.maxImageSize
+	if fileType.Kind == media.KindVideo {
+		maxSize = app.config.media.maxVideoSize
+	}
+	if header.Size > maxSize {
+		app.payloadTooLarge(w, r, fmt.Errorf("%s of %d bytes over the %d bytes limit", fileType.Kind, header.Size, maxSize))
+		return
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	var body io.Reader = file
+	size := header.Size
+	if fileType.Kind == media.KindImage {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		data, err = media.StripMetadata(fileType.ContentType, data)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+		body = bytes.NewReader(data)
+		size = int64(len(data))
+	}
+
+	user := getUserFromContext(r)
+	key := "uploads/" + uuid.New().String() + fileType.Extension
+
+	if err := app.blobs.Put(r.Context(), key, body, size, fileType.ContentType); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	upload := &store.Media{
+		UserID:      user.ID,
+		Kind:        fileType.Kind,
+		ContentType: fileType.ContentType,
+		URL:         app.blobs.URL(key),
+		Size:        size,
+		Variants:    store.MediaVariants{},
+		StorageKey:  key,
+	}
+	if err := app.store.Media.Create(r.Context(), upload); err != nil {
+		if err := app.blobs.Delete(r.Context(), key); err != nil {
+			app.logger.Errorw("failed to delete blob of failed upload", "key", key, "error", err)
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, upload); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// mediaFileServer serves the files of the local blob store. Directory
+// listings are refused so uploads can only be fetched by their key.
+func (app *application) mediaFileServer(dir string) http.Handler {
+	files := http.StripPrefix("/v1/media/files/", http.FileServer(http.Dir(dir)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			app.notFound(w, r, errors.New("directory listing"))
+			return
+		}
+
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		files.ServeHTTP(w, r)
+	})
+}
+
+// cleanupMediaJob deletes the uploads left unattached past MEDIA_ORPHAN_TTL,
+// with their variants. The row goes first so an upload attached in the
+// meantime is kept, a blob that then fails to delete is logged and left behind.
+func (app *application) cleanupMediaJob(ctx context.Context) error {
+	orphans, err := app.store.Media.GetOrphans(ctx, time.Now().Add(-app.config.media.orphanTTL), mediaCleanupBatchSize)
+	if err != nil {
+		return err
+	}
+
+	deleted := 0
+	for _, orphan := range orphans {
+		keys, err := app.store.Media.Delete(ctx, orphan.ID)
+		if err != nil {
+			if err == store.ErrorNotFound {
+				continue
+			}
+			return err
+		}
+		app.deleteBlobs(ctx, keys)
+		deleted++
+	}
+
+	if deleted > 0 {
+		app.logger.Infow("deleted orphaned media", "count", deleted)
+	}
+
+	return nil
+}
+
+// processMediaJob generates the variants, dimensions and placeholder of the
+// images uploaded since the last run. An image that fails is logged and left
+// pending for the next runs, up to store.MaxMediaProcessingAttempts, while the
+// rest of the batch goes on.
+func (app *application) processMediaJob(ctx context.Context) error {
+	pending, err := app.store.Media.GetPending(ctx, mediaProcessingBatchSize)
+	if err != nil {
+		return err
+	}
+
+	processed := 0
+	for i := range pending {
+		if err := app.processImage(ctx, &pending[i]); err != nil {
+			app.logger.Errorw("failed to process media", "media_id", pending[i].ID, "error", err)
+			if err := app.store.Media.SetAttemptFailed(ctx, pending[i].ID); err != nil && err != store.ErrorNotFound {
+				return err
+			}
+			continue
+		}
+		processed++
+	}
+
+	if processed > 0 {
+		app.logger.Infow("processed media", "count", processed)
+	}
+
+	return nil
+}
+
+// processImage stores the variants of a pending image and records them.
+// Images that cannot be decoded are marked failed, formats without a decoder
+// such as WebP are ready without variants. Either way the upload itself is
+// still served. Each attempt stores its variants under keys of its own, so a
+// run overlapping with another one only ever deletes what it stored.
+func (app *application) processImage(ctx context.Context, upload *store.Media) error {
+	data, err := app.readBlob(ctx, upload.StorageKey)
+	if err != nil && err != media.ErrNotFound {
+		return err
+	}
+
+	var processed *media.ProcessedImage
+	if err == nil {
+		processed, err = media.ProcessImage(data)
+	}
+
+	upload.Variants = store.MediaVariants{}
+	switch {
+	case err == media.ErrUnsupportedType:
+		upload.Status = store.MediaStatusReady
+	case err != nil:
+		app.logger.Warnw("failed to process image", "media_id", upload.ID, "error", err)
+		upload.Status = store.MediaStatusFailed
+	default:
+		upload.Status = store.MediaStatusReady
+		upload.Width = &processed.Width
+		upload.Height = &processed.Height
+		upload.BlurHash = &processed.BlurHash
+
+		attempt := uuid.NewString()
+		for _, variant := range processed.Variants {
+			key := variantKey(upload.StorageKey, attempt, variant.Name, variant.Extension)
+			if err := app.blobs.Put(ctx, key, bytes.NewReader(variant.Data), int64(len(variant.Data)), variant.ContentType); err != nil {
+				app.deleteBlobs(ctx, upload.VariantKeys)
+				return err
+			}
+			upload.VariantKeys = append(upload.VariantKeys, key)
+			upload.Variants[variant.Name] = store.MediaVariant{
+				URL:         app.blobs.URL(key),
+				Width:       variant.Width,
+				Height:      variant.Height,
+				ContentType: variant.ContentType,
+			}
+		}
+	}
+
+	err = app.store.Media.SetProcessed(ctx, upload)
+	if err == store.ErrorNotFound {
+		// the upload was cleaned up, or processed by another run, in the meantime
+		app.deleteBlobs(ctx, upload.VariantKeys)
+		return nil
+	}
+	return err
+}
+
+// deleteBlobs deletes stored files, a file that fails to delete is logged and
+// left behind.
+func (app *application) deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := app.blobs.Delete(ctx, key); err != nil {
+			app.logger.Errorw("failed to delete media blob", "key", key, "error", err)
+		}
+	}
+}
+
+func (app *application) readBlob(ctx context.Context, key string) ([]byte, error) {
+	body, err := app.blobs.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+// variantKey stores the variants made by an attempt at processing an upload
+// next to each other, as variants/<upload>/<attempt>/<variant><extension>.
+func variantKey(key, attempt, name, extension string) string {
+	base := path.Base(key)
+	return "variants/" + strings.TrimSuffix(base, path.Ext(base)) + "/" + attempt + "/" + name + extension
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/tenteedee/gopher-social/internal/media"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+// pngWithText encodes a small PNG carrying a tEXt chunk with the given text.
+func pngWithText(t *testing.T, text string) []byte {
+	t.Helper()
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("tEXtComment\x00" + text)
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)-4))
+	chunk = append(chunk, data...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(data))
+
+	// right after the signature and the IHDR chunk
+	head := encoded.Bytes()[:33]
+	return append(append(append([]byte{}, head...), chunk...), encoded.Bytes()[33:]...)
+}
+
+func TestUploadMedia(t *testing.T) {
+	app := newTestApplication(t, config{
+		media: mediaConfig{maxImageSize: 1024, maxVideoSize: 1024},
+	})
+	blobs, err := media.NewLocalStore(t.TempDir(), "http://localhost:8080/v1/media/files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.blobs = blobs
+	mux := app.mount()
+
+	users := app.store.User.(*store.MockUserStore)
+	users.Users[1] = &store.User{ID: 1, Username: "author"}
+
+	upload := func(t *testing.T, name string, content []byte) *http.Request {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(content)
+		form.Close()
+
+		req := authedRequest(t, app, 1, http.MethodPost, "/v1/media", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		return req
+	}
+
+	t.Run("should store an image without its metadata", func(t *testing.T) {
+		rr := executeRequest(upload(t, "photo.txt", pngWithText(t, "GPS 48.8584 N")), mux)
+
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		var response struct {
+			Data store.Media `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		if response.Data.ContentType != "image/png" || response.Data.Kind != media.KindImage {
+			t.Errorf("expected a PNG image, got %s %s", response.Data.Kind, response.Data.ContentType)
+		}
+
+		location, err := url.Parse(response.Data.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodGet, location.Path, nil)
+		file := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, file.Code)
+		if bytes.Contains(file.Body.Bytes(), []byte("GPS")) {
+			t.Error("expected the text chunk to be stripped")
+		}
+	})
+
+	t.Run("should generate the variants of a pending image", func(t *testing.T) {
+		if err := app.processMediaJob(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		uploads := app.store.Media.(*store.MockMediaStore)
+		processed := uploads.Media[0]
+		if processed.Status != store.MediaStatusReady {
+			t.Fatalf("expected the image to be ready, got %s", processed.Status)
+		}
+		if processed.BlurHash == nil || processed.Width == nil || *processed.Width != 4 {
+			t.Error("expected the dimensions and blurhash to be set")
+		}
+		for _, variant := range media.Variants {
+			if _, ok := processed.Variants[variant.Name]; !ok {
+				t.Errorf("expected a %s variant", variant.Name)
+			}
+		}
+		for _, key := range processed.VariantKeys {
+			body, err := blobs.Get(context.Background(), key)
+			if err != nil {
+				t.Errorf("expected %s to be stored: %v", key, err)
+				continue
+			}
+			body.Close()
+		}
+	})
+
+	t.Run("should keep the variants of an overlapping run", func(t *testing.T) {
+		rr := executeRequest(upload(t, "photo.png", pngWithText(t, "")), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		uploads := app.store.Media.(*store.MockMediaStore)
+		pending, err := uploads.GetPending(context.Background(), 1)
+		if err != nil || len(pending) != 1 {
+			t.Fatalf("expected a pending image, got %v (%v)", pending, err)
+		}
+
+		// both runs picked the same pending image
+		winner, loser := pending[0], pending[0]
+		for _, run := range []*store.Media{&winner, &loser} {
+			if err := app.processImage(context.Background(), run); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, key := range winner.VariantKeys {
+			body, err := blobs.Get(context.Background(), key)
+			if err != nil {
+				t.Errorf("expected %s to be kept: %v", key, err)
+				continue
+			}
+			body.Close()
+		}
+		for _, key := range loser.VariantKeys {
+			if _, err := blobs.Get(context.Background(), key); err != media.ErrNotFound {
+				t.Errorf("expected %s to be deleted, got %v", key, err)
+			}
+		}
+	})
+
+	t.Run("should reject an unsupported type", func(t *testing.T) {
+		rr := executeRequest(upload(t, "photo.png", []byte("just some text")), mux)
+
+		checkResponseCode(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+
+	t.Run("should reject an image over the size limit", func(t *testing.T) {
+		rr := executeRequest(upload(t, "photo.png", pngWithText(t, strings.Repeat("x", 2048))), mux)
+
+		checkResponseCode(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+
+	t.Run("should not list the uploads", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/v1/media/files/uploads/", nil)
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+// brokenBlobStore fails to read the blobs under broken.
+type brokenBlobStore struct {
+	media.BlobStore
+	broken string
+}
+
+func (s *brokenBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if key == s.broken {
+		return nil, errors.New("connection reset")
+	}
+	return s.BlobStore.Get(ctx, key)
+}
+
+func TestProcessMediaJob(t *testing.T) {
+	app := newTestApplication(t, config{})
+	blobs, err := media.NewLocalStore(t.TempDir(), "http://localhost:8080/v1/media/files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.blobs = &brokenBlobStore{BlobStore: blobs, broken: "uploads/broken.png"}
+
+	data := pngWithText(t, "")
+	if err := blobs.Put(context.Background(), "uploads/fine.png", bytes.NewReader(data), int64(len(data)), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	uploads := app.store.Media.(*store.MockMediaStore)
+	broken := &store.Media{Kind: media.KindImage, StorageKey: "uploads/broken.png"}
+	fine := &store.Media{Kind: media.KindImage, StorageKey: "uploads/fine.png"}
+	for _, upload := range []*store.Media{broken, fine} {
+		if err := uploads.Create(context.Background(), upload); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("should process the rest of the batch past a failing image", func(t *testing.T) {
+		if err := app.processMediaJob(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if fine := uploads.Media[1]; fine.Status != store.MediaStatusReady {
+			t.Errorf("expected the image after the failing one to be ready, got %s", fine.Status)
+		}
+		if broken := uploads.Media[0]; broken.Status != store.MediaStatusPending || uploads.Attempts[broken.ID] != 1 {
+			t.Errorf("expected the failing image to be pending after an attempt, got %s after %d", broken.Status, uploads.Attempts[broken.ID])
+		}
+	})
+
+	t.Run("should mark an image failed after too many attempts", func(t *testing.T) {
+		for range store.MaxMediaProcessingAttempts {
+			if err := app.processMediaJob(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if broken := uploads.Media[0]; broken.Status != store.MediaStatusFailed {
+			t.Errorf("expected the image to be failed, got %s", broken.Status)
+		}
+	})
+}
@@ -146,6 +146,56 @@ func (app *application) CheckPostOwnership(roles string, next http.HandlerFunc)
 	})
 }
 
+// CheckCommentOwnership lets the author of the comment through, or users with
+// at least the given role.
+func (app *application) CheckCommentOwnership(role string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := getUserFromContext(r)
+		comment, err := getCommentFromContext(r)
+		if err != nil {
+			app.notFound(w, r, err)
+			return
+		}
+
+		if comment.UserID == user.ID {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, err := app.checkRolePrecedence(r.Context(), user, role)
+		if err != nil {
+			app.internalServerError(w, r, fmt.Errorf("invalid role: %w", err))
+			return
+		}
+
+		if !allowed {
+			app.forbidden(w, r, fmt.Errorf("forbidden"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := getUserFromContext(r)
+
+		allowed, err := app.checkRolePrecedence(r.Context(), user, role)
+		if err != nil {
+			app.internalServerError(w, r, fmt.Errorf("invalid role: %w", err))
+			return
+		}
+
+		if !allowed {
+			app.forbidden(w, r, fmt.Errorf("forbidden"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
 	role, err := app.store.Roles.GetByName(ctx, roleName)
 	if err != nil {
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+type CreateMuteFilterPayload struct {
+	Kind      string     `json:"kind" validate:"required,oneof=word phrase tag"`
+	Value     string     `json:"value" validate:"required,max=100"`
+	WholeWord bool       `json:"whole_word"`
+	Action    string     `json:"action" validate:"omitempty,oneof=hide warn"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Create Mute Filter godoc
+//
+//	@Summary		Creates a mute filter
+//	@Description	Creates a word, phrase or tag filter. Matching posts and comments are dropped from the feed, trends and comment lists, or only marked with hidden_by when the action is warn
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateMuteFilterPayload	true	"Mute filter"
+//	@Success		201		{object}	store.MuteFilter
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/mute-filters [post]
+func (app *application) createMuteFilterHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateMuteFilterPayload
+	if err := ReadJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	value := store.NormalizeMuteFilterValue(payload.Kind, payload.Value)
+	if value == "" {
+		app.badRequest(w, r, errors.New("value must not be blank"))
+		return
+	}
+	if payload.Kind != store.MuteFilterKindPhrase && strings.Contains(value, " ") {
+		app.badRequest(w, r, errors.New("only phrases may contain spaces"))
+		return
+	}
+	if payload.ExpiresAt != nil && !payload.ExpiresAt.After(time.Now()) {
+		app.badRequest(w, r, errors.New("expires_at must be in the future"))
+		return
+	}
+
+	if payload.Action == "" {
+		payload.Action = store.MuteActionHide
+	}
+
+	user := getUserFromContext(r)
+
+	filter := store.MuteFilter{
+		UserID:    user.ID,
+		Kind:      payload.Kind,
+		Value:     value,
+		WholeWord: payload.WholeWord,
+		Action:    payload.Action,
+		ExpiresAt: payload.ExpiresAt,
+	}
+
+	if err := app.store.MuteFilter.Create(r.Context(), &filter); err != nil {
+		switch err {
+		case store.ErrConflict:
+			app.conflictResponse(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, filter); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Get Mute Filters godoc
+//
+//	@Summary		Lists mute filters
+//	@Description	Lists the mute filters of the authenticated user, expired ones included
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	[]store.MuteFilter
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/mute-filters [get]
+func (app *application) getMuteFiltersHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	filters, err := app.store.MuteFilter.GetByUserID(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, filters); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Delete Mute Filter godoc
+//
+//	@Summary		Deletes a mute filter
+//	@Description	Deletes a mute filter of the authenticated user
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			filterID	path		int	true	"Mute filter ID"
+//	@Success		204			{object}	nil
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/mute-filters/{filterID} [delete]
+func (app *application) deleteMuteFilterHandler(w http.ResponseWriter, r *http.Request) {
+	filterID, err := strconv.ParseInt(chi.URLParam(r, "filterID"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.MuteFilter.Delete(r.Context(), user.ID, filterID); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+type NotificationsResponse struct {
+	Notifications []store.Notification `json:"notifications"`
+	NextCursor    string               `json:"next_cursor,omitempty"`
+}
+
+// Get Notifications godoc
+//
+//	@Summary		Lists notifications
+//	@Description	Lists the notifications of the authenticated user, newest first, such as mentions in posts and comments. Pass next_cursor as cursor to get the next page
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			cursor	query		string	false	"Cursor"
+//	@Success		200		{object}	NotificationsResponse
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/notifications [get]
+func (app *application) getNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	cq := store.CursorQuery{
+		Limit: 20,
+	}
+
+	cq, err := cq.Parse(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(cq); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	notifications, err := app.store.Notifications.List(r.Context(), user.ID, cq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := NotificationsResponse{Notifications: notifications}
+	if int64(len(notifications)) == cq.Limit {
+		response.NextCursor = store.EncodeCursor(notifications[len(notifications)-1].ID)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Mark Notifications Read godoc
+//
+//	@Summary		Marks notifications as read
+//	@Description	Marks the notifications of the authenticated user as read, up to the given one so those that arrived since stay unread, or all of them
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Param			up_to	query	int	false	"Latest notification ID read"
+//	@Success		204
+//	@Failure		400	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/notifications/read [put]
+func (app *application) markNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	upToID, err := parseOptionalID(r.URL.Query().Get("up_to"))
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	var upTo int64
+	if upToID != nil {
+		upTo = *upToID
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.Notifications.MarkRead(r.Context(), user.ID, upTo); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
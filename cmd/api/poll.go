@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+// maxPollDuration is how far in the future a poll can close.
+const maxPollDuration = 30 * 24 * time.Hour
+
+type CreatePollPayload struct {
+	Options  []string `json:"options" validate:"min=2,max=6,unique,dive,required,max=100"`
+	Multiple bool     `json:"multiple"`
+	// HideResults hides the tallies from those who did not vote until the poll closes.
+	HideResults bool      `json:"hide_results"`
+	ClosesAt    time.Time `json:"closes_at" validate:"required"`
+}
+
+type VotePollPayload struct {
+	OptionIDs []int64 `json:"option_ids" validate:"min=1,max=6,unique"`
+}
+
+// newPoll builds the poll of a new post. It must close in the future, after
+// the post is published when it is scheduled, and within maxPollDuration.
+func newPoll(payload *CreatePollPayload, post *store.Post) (*store.Poll, error) {
+	if payload == nil {
+		return nil, nil
+	}
+
+	opensAt, err := pollOpensAt(post)
+	if err != nil {
+		return nil, err
+	}
+	if !payload.ClosesAt.After(opensAt) {
+		return nil, errors.New("closes_at must be after the post is published")
+	}
+	if payload.ClosesAt.After(opensAt.Add(maxPollDuration)) {
+		return nil, errors.New("closes_at is too far in the future")
+	}
+
+	poll := &store.Poll{
+		Multiple:    payload.Multiple,
+		HideResults: payload.HideResults,
+		ClosesAt:    payload.ClosesAt.UTC().Format(time.RFC3339),
+	}
+	for _, text := range payload.Options {
+		poll.Options = append(poll.Options, store.PollOption{Text: text})
+	}
+
+	return poll, nil
+}
+
+// pollOpensAt is when the poll of a post opens: when the post is published if
+// it is scheduled, now otherwise.
+func pollOpensAt(post *store.Post) (time.Time, error) {
+	if post.PublishAt == nil {
+		return time.Now(), nil
+	}
+	return time.Parse(time.RFC3339, *post.PublishAt)
+}
+
+// checkPollOpen makes sure the poll of a post about to be published or
+// scheduled is still open by then. Drafts are not bound to a publish time, so
+// their poll is checked again when they go out.
+func checkPollOpen(post *store.Post) error {
+	if post.Poll == nil {
+		return nil
+	}
+
+	opensAt, err := pollOpensAt(post)
+	if err != nil {
+		return err
+	}
+	closesAt, err := time.Parse(time.RFC3339, post.Poll.ClosesAt)
+	if err != nil {
+		return err
+	}
+	if !closesAt.After(opensAt) {
+		return errors.New("the poll closes before the post is published")
+	}
+
+	return nil
+}
+
+// Vote Poll godoc
+//
+//	@Summary		Votes on a poll
+//	@Description	Votes on the poll of a post, for one option or several when the poll allows it. A vote cannot be changed. Returns the poll with its tallies
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Post ID"
+//	@Param			payload	body		VotePollPayload	true	"Options"
+//	@Success		201		{object}	store.Poll
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/poll/votes [post]
+func (app *application) votePollHandler(w http.ResponseWriter, r *http.Request) {
+	post, err := getPostFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	var payload VotePollPayload
+	if err := ReadJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.Polls.Vote(r.Context(), post.ID, user.ID, payload.OptionIDs); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		case store.ErrInvalidVote, store.ErrPollClosed:
+			app.badRequest(w, r, err)
+			return
+		case store.ErrConflict:
+			app.conflictResponse(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	post, err = app.store.Post.GetVisibleByID(r.Context(), post.ID, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, post.Poll); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// closePollsJob closes the polls whose closing time passed.
+func (app *application) closePollsJob(ctx context.Context) error {
+	closed, err := app.store.Polls.Close(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if closed > 0 {
+		app.logger.Infow("closed polls", "count", closed)
+	}
+
+	return nil
+}
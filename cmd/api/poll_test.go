@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+func TestVotePoll(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	users := app.store.User.(*store.MockUserStore)
+	users.Users[1] = &store.User{ID: 1, Username: "author"}
+	users.Users[2] = &store.User{ID: 2, Username: "voter"}
+
+	newPoll := func(multiple, closed bool) *store.Poll {
+		return &store.Poll{
+			Multiple: multiple,
+			Closed:   closed,
+			Options:  []store.PollOption{{ID: 11, Text: "yes"}, {ID: 12, Text: "no"}, {ID: 13, Text: "maybe"}},
+		}
+	}
+
+	posts := app.store.Post.(*store.MockPostStore)
+	polls := app.store.Polls.(*store.MockPollStore)
+	for id, poll := range map[int64]*store.Poll{1: newPoll(false, false), 2: newPoll(true, false), 3: newPoll(false, true)} {
+		posts.Posts[id] = &store.Post{ID: id, UserID: 1, Title: "poll", Poll: poll}
+		polls.Polls[id] = poll
+	}
+	posts.Posts[4] = &store.Post{ID: 4, UserID: 1, Title: "no poll"}
+
+	newRequest := func(t *testing.T, postID, body string) *http.Request {
+		return authedRequest(t, app, 2, http.MethodPost, "/v1/posts/"+postID+"/poll/votes", strings.NewReader(body))
+	}
+
+	t.Run("should take a single option on a single choice poll", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "1", `{"option_ids":[11,12]}`), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+		if polls.Polls[1].Voted {
+			t.Error("expected no vote to be recorded")
+		}
+	})
+
+	t.Run("should reject an option of another poll", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "1", `{"option_ids":[21]}`), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should return the poll with the vote counted", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "1", `{"option_ids":[11]}`), mux)
+
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		var response struct {
+			Data store.Poll `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		if !response.Data.Voted || len(response.Data.MyVotes) != 1 || response.Data.MyVotes[0] != 11 {
+			t.Errorf("expected a vote for option 11, got %+v", response.Data)
+		}
+		if votes := response.Data.Options[0].VotesCount; votes == nil || *votes != 1 {
+			t.Errorf("expected option 11 to have a vote, got %v", votes)
+		}
+	})
+
+	t.Run("should not vote twice", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "1", `{"option_ids":[12]}`), mux)
+
+		checkResponseCode(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("should take several options when the poll allows it", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "2", `{"option_ids":[11,13]}`), mux)
+
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+		if votes := polls.Polls[2].MyVotes; len(votes) != 2 {
+			t.Errorf("expected two options voted for, got %v", votes)
+		}
+	})
+
+	t.Run("should not vote on a closed poll", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "3", `{"option_ids":[11]}`), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should not find the poll of a post without one", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "4", `{"option_ids":[11]}`), mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestPublishDraftPoll(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	users := app.store.User.(*store.MockUserStore)
+	users.Users[1] = &store.User{ID: 1, Username: "author"}
+
+	posts := app.store.Post.(*store.MockPostStore)
+
+	newDraft := func(closesIn time.Duration) {
+		posts.Posts[1] = &store.Post{
+			ID:      1,
+			UserID:  1,
+			Title:   "draft",
+			Status:  store.PostStatusDraft,
+			Version: 1,
+			Poll:    &store.Poll{ClosesAt: time.Now().Add(closesIn).UTC().Format(time.RFC3339)},
+		}
+	}
+
+	newRequest := func(t *testing.T, body string) *http.Request {
+		req := authedRequest(t, app, 1, http.MethodPatch, "/v1/posts/1", strings.NewReader(body))
+		req.Header.Set("If-Match", `"1"`)
+		return req
+	}
+
+	t.Run("should not publish a draft whose poll closed", func(t *testing.T) {
+		newDraft(-time.Hour)
+
+		rr := executeRequest(newRequest(t, `{"status":"published"}`), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should keep editing a draft whose poll closed", func(t *testing.T) {
+		newDraft(-time.Hour)
+
+		rr := executeRequest(newRequest(t, `{"title":"still a draft"}`), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should not schedule a draft past the close of its poll", func(t *testing.T) {
+		newDraft(time.Hour)
+		publishAt := time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)
+
+		rr := executeRequest(newRequest(t, `{"status":"scheduled","publish_at":"`+publishAt+`"}`), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should publish a draft whose poll is open", func(t *testing.T) {
+		newDraft(time.Hour)
+
+		rr := executeRequest(newRequest(t, `{"status":"published"}`), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/tenteedee/gopher-social/internal/search"
 	"github.com/tenteedee/gopher-social/internal/store"
 )
 
@@ -14,20 +17,33 @@ type postKey string
 const postContextKey postKey = "post"
 
 type CreatePostPayload struct {
-	Title   string   `json:"title" validate:"required,max=100"`
-	Content string   `json:"content" validate:"required,max=1000"`
-	Tags    []string `json:"tags"`
+	Title   string `json:"title" validate:"required,max=100"`
+	Content string `json:"content" validate:"required,max=1000"`
+	// Tags are merged with the #hashtags of the content, up to 10 in all.
+	Tags []string `json:"tags"`
+	// Status defaults to published, scheduled posts need PublishAt.
+	Status    *string    `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt *time.Time `json:"publish_at"`
+	// Visibility defaults to public. Mentioned-only posts are seen by the @users in the content.
+	Visibility string `json:"visibility" validate:"omitempty,oneof=public followers mentioned private" enums:"public,followers,mentioned,private"`
+	// MediaIDs are uploads from POST /media to attach, in order.
+	MediaIDs []int64 `json:"media_ids" validate:"max=4,unique"`
+	// Poll attaches a poll of 2 to 6 options.
+	Poll *CreatePollPayload `json:"poll"`
 }
 
 type UpdatePostPayload struct {
-	Title   *string   `json:"title" validate:"omitempty,max=100"`
-	Content *string   `json:"content" validate:"omitempty,max=1000"`
-	Tags    *[]string `json:"tags"`
+	Title      *string    `json:"title" validate:"omitempty,max=100"`
+	Content    *string    `json:"content" validate:"omitempty,max=1000"`
+	Tags       *[]string  `json:"tags"`
+	Status     *string    `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt  *time.Time `json:"publish_at"`
+	Visibility *string    `json:"visibility" validate:"omitempty,oneof=public followers mentioned private" enums:"public,followers,mentioned,private"`
 }
 
 type CreateCommentPayload struct {
-	UserID  int64  `json:"user_id"`
-	Content string `json:"content"`
+	Content         string `json:"content" validate:"required,max=1000"`
+	ParentCommentID *int64 `json:"parent_comment_id"`
 }
 
 func (app *application) postContextMiddleware(next http.Handler) http.Handler {
@@ -38,7 +54,9 @@ func (app *application) postContextMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		post, err := app.store.Post.GetByID(r.Context(), postID)
+		viewer := getUserFromContext(r)
+
+		post, err := app.store.Post.GetVisibleByID(r.Context(), postID, viewer.ID)
 		if err != nil {
 			switch err {
 			case store.ErrorNotFound:
@@ -66,7 +84,7 @@ func getPostFromContext(r *http.Request) (*store.Post, error) {
 // Create Post godoc
 //
 //	@Summary		Create a post
-//	@Description	Create a post
+//	@Description	Create a post, published right away unless status is draft or scheduled. Scheduled posts go live at publish_at. Visibility limits who sees it: everyone, followers, the mentioned @users or only the author. Up to 4 uploads can be attached through media_ids, and a poll closing within 30 days. The #hashtags of the content are added to the tags
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
@@ -89,21 +107,55 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	tags, err := store.PostTags(payload.Tags, payload.Content)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
 	user := getUserFromContext(r)
 
 	post := store.Post{
-		Title:   payload.Title,
-		Content: payload.Content,
-		UserID:  user.ID,
-		Tags:    payload.Tags,
+		Title:      payload.Title,
+		Content:    payload.Content,
+		UserID:     user.ID,
+		Tags:       tags,
+		Visibility: payload.Visibility,
+		MediaIDs:   payload.MediaIDs,
 	}
 
-	response, err := app.store.Post.Create(r.Context(), &post)
+	if err := applyPostStatus(&post, payload.Status, payload.PublishAt); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
 
+	poll, err := newPoll(payload.Poll, &post)
 	if err != nil {
-		app.internalServerError(w, r, err)
+		app.badRequest(w, r, err)
 		return
 	}
+	post.Poll = poll
+
+	response, err := app.store.Post.Create(r.Context(), &post)
+
+	if err != nil {
+		switch err {
+		case store.ErrInvalidMedia:
+			app.badRequest(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	post.ID = response.ID
+	post.CreatedAt = response.CreatedAt
+	post.UpdatedAt = response.UpdatedAt
+	if post.Status == store.PostStatusPublished {
+		doc, err := search.PostDocument(&post, user.Username)
+		app.indexDocument(r.Context(), doc, err)
+	}
 	if err := app.jsonResponse(w, http.StatusCreated, response); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -114,15 +166,17 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 // Get Post by ID godoc
 //
 //	@Summary		Fetch a Post by ID
-//	@Description	Fetch a Post by ID
+//	@Description	Fetch a Post by ID with a preview of its first comments, the rest are listed by /posts/{id}/comments. The ETag header carries the version of the post, and changes as well with the comments, reactions and poll tallies shown
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"Post ID"
-//	@Success		200	{object}	store.Post
-//	@Failure		400	{object}	error
-//	@Failure		404	{object}	error
-//	@Failure		500	{object}	error
+//	@Param			id				path		int		true	"Post ID"
+//	@Param			If-None-Match	header		string	false	"ETag of a cached version"
+//	@Success		200				{object}	store.Post
+//	@Success		304				{object}	nil
+//	@Failure		400				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [get]
 func (app *application) getPostByIdHandler(w http.ResponseWriter, r *http.Request) {
@@ -138,7 +192,11 @@ func (app *application) getPostByIdHandler(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
-	comments, err := app.store.Comment.GetCommentByPostId(r.Context(), post.ID)
+	viewer := getUserFromContext(r)
+
+	comments, err := app.store.Comment.GetCommentByPostId(r.Context(), post.ID, viewer.ID, store.CursorQuery{
+		Limit: store.CommentPreviewSize,
+	})
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -154,7 +212,22 @@ func (app *application) getPostByIdHandler(w http.ResponseWriter, r *http.Reques
 	// 	}
 	// }
 
-	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
+	body, err := encodeJSONResponse(post)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// the body depends on who asks, and changes without a new version
+	etag := postBodyETag(post, body)
+	w.Header().Set("Vary", "Authorization")
+	w.Header().Set("ETag", etag)
+	if notModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if err := writeJSONBody(w, http.StatusOK, body); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -163,14 +236,17 @@ func (app *application) getPostByIdHandler(w http.ResponseWriter, r *http.Reques
 // DeletePost godoc
 //
 //	@Summary		Deletes a post
-//	@Description	Delete a post by ID
+//	@Description	Moves a post to the trash of its author, from where it can be restored until purged. If-Match must carry the ETag of the current version
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"Post ID"
-//	@Success		204	{object}	string
-//	@Failure		404	{object}	error
-//	@Failure		500	{object}	error
+//	@Param			id			path		int		true	"Post ID"
+//	@Param			If-Match	header		string	true	"ETag of the current version"
+//	@Success		204			{object}	string
+//	@Failure		404			{object}	error
+//	@Failure		412			{object}	error
+//	@Failure		428			{object}	error
+//	@Failure		500			{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [delete]
 func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request) {
@@ -186,33 +262,52 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 		}
 	}
 
-	if err := app.store.Post.Delete(r.Context(), post.ID); err != nil {
+	if err := checkIfMatch(r, post); err != nil {
+		app.ifMatchError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.Post.Delete(r.Context(), post.ID, post.Version, user.ID); err != nil {
 		switch err {
 		case store.ErrorNotFound:
 			app.notFound(w, r, err)
 			return
+		case store.ErrVersionMismatch:
+			app.preconditionFailed(w, r, err)
+			return
 		default:
 			app.internalServerError(w, r, err)
 			return
 		}
 	}
+
+	app.publishSearchEvent(r.Context(), search.Event{
+		Action:   search.ActionDelete,
+		Document: search.Document{Type: store.SearchTypePosts, ID: post.ID},
+	})
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // UpdatePost godoc
 //
 //	@Summary		Updates a post
-//	@Description	Updates a post by ID
+//	@Description	Updates a post by ID. If-Match must carry the ETag of the current version, the ETag of the new version is returned. Editing the content without tags keeps the explicit tags and picks up its new #hashtags. A draft with a poll is only published or scheduled while the poll is still open by then
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			id		path		int					true	"Post ID"
-//	@Param			payload	body		UpdatePostPayload	true	"Post payload"
-//	@Success		200		{object}	store.Post
-//	@Failure		400		{object}	error
-//	@Failure		401		{object}	error
-//	@Failure		404		{object}	error
-//	@Failure		500		{object}	error
+//	@Param			id			path		int					true	"Post ID"
+//	@Param			If-Match	header		string				true	"ETag of the current version"
+//	@Param			payload		body		UpdatePostPayload	true	"Post payload"
+//	@Success		200			{object}	store.Post
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		412			{object}	error
+//	@Failure		428			{object}	error
+//	@Failure		500			{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [patch]
 func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request) {
@@ -228,6 +323,16 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 		}
 	}
 
+	if post.Kind == store.PostKindRepost {
+		app.badRequest(w, r, errors.New("reposts have no content to update"))
+		return
+	}
+
+	if err := checkIfMatch(r, post); err != nil {
+		app.ifMatchError(w, r, err)
+		return
+	}
+
 	// read the request body into a CreatePostPayload struct
 	var payload UpdatePostPayload
 	if err := ReadJSON(w, r, &payload); err != nil {
@@ -241,23 +346,60 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	tags := post.Tags
+	if payload.Tags != nil {
+		tags = *payload.Tags
+	} else if payload.Content != nil {
+		tags = store.ExplicitTags(post.Tags, post.Content)
+	}
+
 	if payload.Title != nil {
 		post.Title = *payload.Title
 	}
 	if payload.Content != nil {
 		post.Content = *payload.Content
 	}
-	if payload.Tags != nil {
-		post.Tags = *payload.Tags
+	if payload.Tags != nil || payload.Content != nil {
+		if post.Tags, err = store.PostTags(tags, post.Content); err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+	}
+	if payload.Visibility != nil {
+		post.Visibility = *payload.Visibility
+	}
+	published := post.Status == store.PostStatusPublished
+	if err := applyPostStatus(post, payload.Status, payload.PublishAt); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	if !published && post.Status != store.PostStatusDraft {
+		if err := checkPollOpen(post); err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
 	}
 
+	user := getUserFromContext(r)
+
 	// update the post struct with the payload data
-	if err := app.store.Post.Update(r.Context(), post); err != nil {
-		app.internalServerError(w, r, err)
-		return
+	if err := app.store.Post.Update(r.Context(), post, user.ID); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		case store.ErrVersionMismatch:
+			app.preconditionFailed(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
+	app.indexPost(r.Context(), post)
+
+	if err := writePost(w, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -267,15 +409,16 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 // Create Comment godoc
 //
 //	@Summary		Create a comment
-//	@Description	Create a comment for a Post
+//	@Description	Create a comment for a Post as the authenticated user, or a reply to one of its comments when parent_comment_id is set
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
 //	@Param			id		path		int						true	"Post ID"
 //	@Param			payload	body		CreateCommentPayload	true	"Comment payload"
-//	@Success		200		{object}	store.Comment
+//	@Success		201		{object}	store.Comment
 //	@Failure		400		{object}	error
 //	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
 //	@Failure		404		{object}	error
 //	@Failure		500		{object}	error
 //	@Security		ApiKeyAuth
@@ -304,25 +447,93 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	user, err := app.store.User.GetById(r.Context(), payload.UserID)
-	if err != nil {
-		app.internalServerError(w, r, err)
-		return
-	}
+	user := getUserFromContext(r)
 
 	var comment store.Comment
 	comment.PostID = post.ID
-	comment.UserID = payload.UserID
+	comment.UserID = user.ID
 	comment.Content = payload.Content
+	comment.ParentCommentID = payload.ParentCommentID
 	comment.User = *user
 
 	if err := app.store.Comment.Create(r.Context(), &comment); err != nil {
-		app.internalServerError(w, r, err)
-		return
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		case store.ErrBlocked:
+			app.forbidden(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
 	}
 
+	doc, err := search.CommentDocument(&comment)
+	app.indexDocument(r.Context(), doc, err)
+
 	if err := app.jsonResponse(w, http.StatusCreated, comment); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
+
+// Moderate Post godoc
+//
+//	@Summary		Hides a post
+//	@Description	Hides a post from trends, requires the moderator role
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		204	{object}	nil
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/moderate [put]
+func (app *application) moderatePostHandler(w http.ResponseWriter, r *http.Request) {
+	app.setPostModerated(w, r, true)
+}
+
+// Unmoderate Post godoc
+//
+//	@Summary		Unhides a post
+//	@Description	Reverts a moderation, requires the moderator role
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		204	{object}	nil
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/unmoderate [put]
+func (app *application) unmoderatePostHandler(w http.ResponseWriter, r *http.Request) {
+	app.setPostModerated(w, r, false)
+}
+
+func (app *application) setPostModerated(w http.ResponseWriter, r *http.Request, moderated bool) {
+	post, err := getPostFromContext(r)
+	if err != nil {
+		app.notFound(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.Post.SetModerated(r.Context(), post.ID, user.ID, moderated); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
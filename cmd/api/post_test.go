@@ -0,0 +1,365 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+func TestPostConditionalRequests(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	users := app.store.User.(*store.MockUserStore)
+	users.Users[1] = &store.User{ID: 1, Username: "author"}
+
+	posts := app.store.Post.(*store.MockPostStore)
+	posts.Posts[1] = &store.Post{ID: 1, UserID: 1, Title: "title", Content: "content", Version: 3}
+
+	newRequest := func(t *testing.T, method, body string, headers map[string]string) *http.Request {
+		req := authedRequest(t, app, 1, method, "/v1/posts/1", strings.NewReader(body))
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		return req
+	}
+
+	var etag string
+
+	t.Run("should return a tag of the version as ETag", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodGet, "", nil), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		etag = rr.Header().Get("ETag")
+		if !strings.HasPrefix(etag, `"3.`) {
+			t.Errorf(`expected the ETag to tag version 3, got %s`, etag)
+		}
+		if vary := rr.Header().Get("Vary"); vary != "Authorization" {
+			t.Errorf("expected the response to vary by Authorization, got %s", vary)
+		}
+	})
+
+	t.Run("should answer not modified for the same body", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodGet, "", map[string]string{"If-None-Match": "W/" + etag}), mux)
+
+		checkResponseCode(t, http.StatusNotModified, rr.Code)
+		if rr.Body.Len() != 0 {
+			t.Error("expected an empty body")
+		}
+	})
+
+	t.Run("should return the post once its comments changed", func(t *testing.T) {
+		comments := app.store.Comment.(*store.MockCommentStore)
+		comments.Comments = append(comments.Comments, &store.Comment{ID: 1, PostID: 1, UserID: 1, Content: "first"})
+		defer func() { comments.Comments = nil }()
+
+		rr := executeRequest(newRequest(t, http.MethodGet, "", map[string]string{"If-None-Match": etag}), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		if rr.Header().Get("ETag") == etag {
+			t.Error("expected the ETag to change with the comments")
+		}
+	})
+
+	t.Run("should return the post for an older version", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodGet, "", map[string]string{"If-None-Match": `"2"`}), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should require If-Match to update", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodPatch, `{"title":"new"}`, nil), mux)
+
+		checkResponseCode(t, http.StatusPreconditionRequired, rr.Code)
+	})
+
+	t.Run("should not update a stale version", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodPatch, `{"title":"new"}`, map[string]string{"If-Match": `"2"`}), mux)
+
+		checkResponseCode(t, http.StatusPreconditionFailed, rr.Code)
+		if posts.Posts[1].Title != "title" {
+			t.Error("expected the post to be left untouched")
+		}
+	})
+
+	t.Run("should not update with a weak tag", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodPatch, `{"title":"new"}`, map[string]string{"If-Match": "W/" + etag}), mux)
+
+		checkResponseCode(t, http.StatusPreconditionFailed, rr.Code)
+	})
+
+	t.Run("should update the version fetched", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodPatch, `{"title":"new"}`, map[string]string{"If-Match": etag}), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		etag = rr.Header().Get("ETag")
+		if !strings.HasPrefix(etag, `"4.`) {
+			t.Errorf(`expected the ETag to tag version 4 like a GET does, got %s`, etag)
+		}
+	})
+
+	t.Run("should not delete a stale version", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodDelete, "", map[string]string{"If-Match": `"3"`}), mux)
+
+		checkResponseCode(t, http.StatusPreconditionFailed, rr.Code)
+		if _, ok := posts.Posts[1]; !ok {
+			t.Error("expected the post to be kept")
+		}
+	})
+
+	t.Run("should delete the current version", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, http.MethodDelete, "", map[string]string{"If-Match": etag}), mux)
+
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+	})
+}
+
+func TestRestorePostRevision(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	users := app.store.User.(*store.MockUserStore)
+	users.Users[1] = &store.User{ID: 1, Username: "author"}
+	users.Users[2] = &store.User{ID: 2, Username: "moderator", Role: store.Role{Name: "moderator", Level: 2}}
+
+	posts := app.store.Post.(*store.MockPostStore)
+	posts.Posts[1] = &store.Post{ID: 1, UserID: 1, Title: "title", Content: "content", Version: 3}
+
+	newRequest := func(t *testing.T, headers map[string]string) *http.Request {
+		req := authedRequest(t, app, 2, http.MethodPut, "/v1/posts/1/revisions/1/restore", nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		return req
+	}
+
+	t.Run("should require If-Match", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, nil), mux)
+
+		checkResponseCode(t, http.StatusPreconditionRequired, rr.Code)
+	})
+
+	t.Run("should not restore over a stale version", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, map[string]string{"If-Match": `"2"`}), mux)
+
+		checkResponseCode(t, http.StatusPreconditionFailed, rr.Code)
+		if posts.Posts[1].Version != 3 {
+			t.Error("expected the post to be left untouched")
+		}
+	})
+
+	t.Run("should restore over the current version", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, map[string]string{"If-Match": `"3"`}), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		if etag := rr.Header().Get("ETag"); !strings.HasPrefix(etag, `"4.`) {
+			t.Errorf(`expected the ETag to tag version 4 like a GET does, got %s`, etag)
+		}
+	})
+}
+
+func TestCreatePostStatus(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	users := app.store.User.(*store.MockUserStore)
+	users.Users[1] = &store.User{ID: 1, Username: "author"}
+
+	newRequest := func(t *testing.T, body string) *http.Request {
+		return authedRequest(t, app, 1, http.MethodPost, "/v1/posts", strings.NewReader(body))
+	}
+
+	t.Run("should create a draft", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, `{"title":"t","content":"c","status":"draft"}`), mux)
+
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("should require publish_at to schedule", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, `{"title":"t","content":"c","status":"scheduled"}`), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should not schedule in the past", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, `{"title":"t","content":"c","status":"scheduled","publish_at":"2020-01-01T00:00:00Z"}`), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should reject an unknown status", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, `{"title":"t","content":"c","status":"hidden"}`), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestCreatePostPoll(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	users := app.store.User.(*store.MockUserStore)
+	users.Users[1] = &store.User{ID: 1, Username: "author"}
+
+	posts := app.store.Post.(*store.MockPostStore)
+
+	newRequest := func(t *testing.T, poll string) *http.Request {
+		body := `{"title":"t","content":"c","poll":` + poll + `}`
+		return authedRequest(t, app, 1, http.MethodPost, "/v1/posts", strings.NewReader(body))
+	}
+	closesAt := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+
+	t.Run("should attach the poll to the post", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, `{"options":["yes","no"],"closes_at":"`+closesAt+`"}`), mux)
+
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+		poll := posts.Posts[int64(len(posts.Posts))].Poll
+		if poll == nil || len(poll.Options) != 2 || poll.Options[1].Text != "no" {
+			t.Errorf("expected a poll with the two options, got %+v", poll)
+		}
+	})
+
+	t.Run("should require at least two options", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, `{"options":["yes"],"closes_at":"`+closesAt+`"}`), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should reject more than six options", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, `{"options":["1","2","3","4","5","6","7"],"closes_at":"`+closesAt+`"}`), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should reject duplicate options", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, `{"options":["yes","yes"],"closes_at":"`+closesAt+`"}`), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should not close in the past", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, `{"options":["yes","no"],"closes_at":"2020-01-01T00:00:00Z"}`), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestCreatePostTags(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	users := app.store.User.(*store.MockUserStore)
+	users.Users[1] = &store.User{ID: 1, Username: "author"}
+
+	posts := app.store.Post.(*store.MockPostStore)
+
+	newRequest := func(t *testing.T, content, tags string) *http.Request {
+		body := `{"title":"t","content":"` + content + `","tags":` + tags + `}`
+		return authedRequest(t, app, 1, http.MethodPost, "/v1/posts", strings.NewReader(body))
+	}
+
+	t.Run("should merge the hashtags with the tags", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "Learning #Go and #Café, see issue #42 or mail a#b", `["go","#Gophers"]`), mux)
+
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+		tags := posts.Posts[int64(len(posts.Posts))].Tags
+		if got := strings.Join(tags, ","); got != "go,gophers,café" {
+			t.Errorf("expected go,gophers,café, got %s", got)
+		}
+	})
+
+	t.Run("should fold compatibility forms", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "#ＧＯ", `[]`), mux)
+
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+		tags := posts.Posts[int64(len(posts.Posts))].Tags
+		if got := strings.Join(tags, ","); got != "go" {
+			t.Errorf("expected go, got %s", got)
+		}
+	})
+
+	t.Run("should reject an invalid tag", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "c", `["go lang"]`), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should reject too many tags", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "#a #b #c #d #e #f", `["g","h","i","j","k"]`), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestUpdatePostTags(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	users := app.store.User.(*store.MockUserStore)
+	users.Users[1] = &store.User{ID: 1, Username: "author"}
+
+	posts := app.store.Post.(*store.MockPostStore)
+
+	// newPost stores a post written before tags were checked, with a tag that
+	// is no longer valid and one in the wrong case
+	newPost := func() {
+		posts.Posts[1] = &store.Post{
+			ID:      1,
+			UserID:  1,
+			Title:   "title",
+			Content: "about #go",
+			Tags:    []string{"go", "c++", "Web"},
+			Version: 1,
+		}
+	}
+
+	newRequest := func(t *testing.T, body string) *http.Request {
+		req := authedRequest(t, app, 1, http.MethodPatch, "/v1/posts/1", strings.NewReader(body))
+		req.Header.Set("If-Match", `"1"`)
+		return req
+	}
+
+	t.Run("should keep the tags when the content is edited", func(t *testing.T) {
+		newPost()
+
+		rr := executeRequest(newRequest(t, `{"content":"about #rust"}`), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		if got := strings.Join(posts.Posts[1].Tags, ","); got != "web,rust" {
+			t.Errorf("expected web,rust, got %s", got)
+		}
+	})
+
+	t.Run("should leave the tags alone when the content is not edited", func(t *testing.T) {
+		newPost()
+
+		rr := executeRequest(newRequest(t, `{"title":"new"}`), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		if got := strings.Join(posts.Posts[1].Tags, ","); got != "go,c++,Web" {
+			t.Errorf("expected go,c++,Web, got %s", got)
+		}
+	})
+
+	t.Run("should replace the tags", func(t *testing.T) {
+		newPost()
+
+		rr := executeRequest(newRequest(t, `{"tags":["Zig"]}`), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		if got := strings.Join(posts.Posts[1].Tags, ","); got != "zig,go" {
+			t.Errorf("expected zig,go, got %s", got)
+		}
+	})
+
+	t.Run("should reject an invalid tag", func(t *testing.T) {
+		newPost()
+
+		rr := executeRequest(newRequest(t, `{"tags":["c++"]}`), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+// React To Post godoc
+//
+//	@Summary		Reacts to a post
+//	@Description	Adds a reaction to a post. Reacting twice with the same reaction is a no-op
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int		true	"Post ID"
+//	@Param			reaction	path		string	true	"Reaction, like or one of the configured emoji"
+//	@Success		204			{object}	nil
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/reactions/{reaction} [put]
+func (app *application) addPostReactionHandler(w http.ResponseWriter, r *http.Request) {
+	post, err := getPostFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.updateReaction(w, r, func(ctx context.Context, userID int64, reaction string) error {
+		return app.store.Reactions.AddToPost(ctx, post.ID, userID, reaction)
+	})
+}
+
+// Remove Post Reaction godoc
+//
+//	@Summary		Removes a reaction from a post
+//	@Description	Removes a reaction from a post. Removing a missing reaction is a no-op
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int		true	"Post ID"
+//	@Param			reaction	path		string	true	"Reaction"
+//	@Success		204			{object}	nil
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/reactions/{reaction} [delete]
+func (app *application) removePostReactionHandler(w http.ResponseWriter, r *http.Request) {
+	post, err := getPostFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.updateReaction(w, r, func(ctx context.Context, userID int64, reaction string) error {
+		return app.store.Reactions.RemoveFromPost(ctx, post.ID, userID, reaction)
+	})
+}
+
+// Get Post Reactions godoc
+//
+//	@Summary		Lists who reacted to a post
+//	@Description	Lists who reacted to a post, most recent first
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int		true	"Post ID"
+//	@Param			reaction	query		string	false	"Only this reaction"
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			offset		query		int		false	"Offset"
+//	@Success		200			{object}	[]store.Reactor
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/reactions [get]
+func (app *application) getPostReactionsHandler(w http.ResponseWriter, r *http.Request) {
+	post, err := getPostFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.listReactors(w, r, func(ctx context.Context, viewerID int64, rq store.ReactionQuery) ([]store.Reactor, error) {
+		return app.store.Reactions.GetPostReactors(ctx, post.ID, viewerID, rq)
+	})
+}
+
+// React To Comment godoc
+//
+//	@Summary		Reacts to a comment
+//	@Description	Adds a reaction to a comment. Reacting twice with the same reaction is a no-op
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int		true	"Post ID"
+//	@Param			commentID	path		int		true	"Comment ID"
+//	@Param			reaction	path		string	true	"Reaction, like or one of the configured emoji"
+//	@Success		204			{object}	nil
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/{commentID}/reactions/{reaction} [put]
+func (app *application) addCommentReactionHandler(w http.ResponseWriter, r *http.Request) {
+	post, err := getPostFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	app.updateReaction(w, r, func(ctx context.Context, userID int64, reaction string) error {
+		return app.store.Reactions.AddToComment(ctx, post.ID, commentID, userID, reaction)
+	})
+}
+
+// Remove Comment Reaction godoc
+//
+//	@Summary		Removes a reaction from a comment
+//	@Description	Removes a reaction from a comment. Removing a missing reaction is a no-op
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int		true	"Post ID"
+//	@Param			commentID	path		int		true	"Comment ID"
+//	@Param			reaction	path		string	true	"Reaction"
+//	@Success		204			{object}	nil
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/{commentID}/reactions/{reaction} [delete]
+func (app *application) removeCommentReactionHandler(w http.ResponseWriter, r *http.Request) {
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	app.updateReaction(w, r, func(ctx context.Context, userID int64, reaction string) error {
+		return app.store.Reactions.RemoveFromComment(ctx, commentID, userID, reaction)
+	})
+}
+
+// Get Comment Reactions godoc
+//
+//	@Summary		Lists who reacted to a comment
+//	@Description	Lists who reacted to a comment, most recent first
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int		true	"Post ID"
+//	@Param			commentID	path		int		true	"Comment ID"
+//	@Param			reaction	query		string	false	"Only this reaction"
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			offset		query		int		false	"Offset"
+//	@Success		200			{object}	[]store.Reactor
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/{commentID}/reactions [get]
+func (app *application) getCommentReactionsHandler(w http.ResponseWriter, r *http.Request) {
+	post, err := getPostFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	app.listReactors(w, r, func(ctx context.Context, viewerID int64, rq store.ReactionQuery) ([]store.Reactor, error) {
+		return app.store.Reactions.GetCommentReactors(ctx, post.ID, commentID, viewerID, rq)
+	})
+}
+
+// parseReaction unescapes a reaction taken from the URL, emoji arrive percent
+// encoded, and checks it is one of the configured reactions.
+func (app *application) parseReaction(raw string) (string, error) {
+	reaction, err := url.PathUnescape(raw)
+	if err != nil {
+		return "", err
+	}
+
+	if !slices.Contains(app.config.reactions.types, reaction) {
+		return "", fmt.Errorf("unknown reaction %q", reaction)
+	}
+
+	return reaction, nil
+}
+
+func (app *application) updateReaction(
+	w http.ResponseWriter,
+	r *http.Request,
+	update func(context.Context, int64, string) error,
+) {
+	reaction, err := app.parseReaction(chi.URLParam(r, "reaction"))
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := update(r.Context(), user.ID, reaction); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		case store.ErrBlocked:
+			app.forbidden(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) listReactors(
+	w http.ResponseWriter,
+	r *http.Request,
+	list func(context.Context, int64, store.ReactionQuery) ([]store.Reactor, error),
+) {
+	pq := store.PaginationQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	pq, err := pq.Parse(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(pq); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	rq := store.ReactionQuery{PaginationQuery: pq}
+	if reaction := r.URL.Query().Get("reaction"); reaction != "" {
+		if rq.Reaction, err = app.parseReaction(reaction); err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+	}
+
+	viewer := getUserFromContext(r)
+
+	reactors, err := list(r.Context(), viewer.ID, rq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, reactors); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
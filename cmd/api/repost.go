@@ -0,0 +1,169 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+type CreateQuotePayload struct {
+	Title   string `json:"title" validate:"max=100"`
+	Content string `json:"content" validate:"required,max=1000"`
+	// Tags are merged with the #hashtags of the content, up to 10 in all.
+	Tags []string `json:"tags"`
+}
+
+// Repost godoc
+//
+//	@Summary		Reposts a post
+//	@Description	Shares a post with your followers. Reposting a repost shares its original, and a post can be reposted once
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		201	{object}	store.Post
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/repost [post]
+func (app *application) repostHandler(w http.ResponseWriter, r *http.Request) {
+	original, err := getPostFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	post, err := app.store.Post.Repost(r.Context(), original.ID, user.ID)
+	if err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		case store.ErrConflict:
+			app.conflictResponse(w, r, err)
+			return
+		case store.ErrBlocked:
+			app.forbidden(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, post); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Undo Repost godoc
+//
+//	@Summary		Undoes a repost
+//	@Description	Removes your repost of a post
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		204	{object}	nil
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/repost [delete]
+func (app *application) undoRepostHandler(w http.ResponseWriter, r *http.Request) {
+	original, err := getPostFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if _, err := app.store.Post.DeleteRepost(r.Context(), original.ID, user.ID); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Quote Post godoc
+//
+//	@Summary		Quotes a post
+//	@Description	Creates a post embedding another one. The quote is kept when the original is deleted. The #hashtags of the content are added to the tags
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Post ID"
+//	@Param			payload	body		CreateQuotePayload	true	"Quote"
+//	@Success		201		{object}	store.Post
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/quote [post]
+func (app *application) quotePostHandler(w http.ResponseWriter, r *http.Request) {
+	original, err := getPostFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	var payload CreateQuotePayload
+	if err := ReadJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	tags, err := store.PostTags(payload.Tags, payload.Content)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	post := &store.Post{
+		Title:   payload.Title,
+		Content: payload.Content,
+		UserID:  user.ID,
+		Tags:    tags,
+	}
+
+	if err := app.store.Post.Quote(r.Context(), original.ID, post); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		case store.ErrBlocked:
+			app.forbidden(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	app.indexPost(r.Context(), post)
+
+	if err := app.jsonResponse(w, http.StatusCreated, post); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
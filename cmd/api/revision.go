@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tenteedee/gopher-social/internal/diff"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+type RevisionDiff struct {
+	From        int64       `json:"from"`
+	To          int64       `json:"to"`
+	Title       []diff.Line `json:"title"`
+	Content     []diff.Line `json:"content"`
+	TagsAdded   []string    `json:"tags_added"`
+	TagsRemoved []string    `json:"tags_removed"`
+}
+
+// Get Post Revisions godoc
+//
+//	@Summary		Lists the revisions of a post
+//	@Description	Lists the prior versions of a post, newest first. The current version is the post itself
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int	true	"Post ID"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.PostRevision
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/revisions [get]
+func (app *application) getPostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	post, err := getPostFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	page := store.PaginationQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	page, err = page.Parse(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(page); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	revisions, err := app.store.Post.GetRevisions(r.Context(), post.ID, page)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, revisions); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Diff Post Revisions godoc
+//
+//	@Summary		Diffs two versions of a post
+//	@Description	Compares two versions of a post line by line. to defaults to the current version
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int	true	"Post ID"
+//	@Param			from	query		int	true	"Older version"
+//	@Param			to		query		int	false	"Newer version"
+//	@Success		200		{object}	RevisionDiff
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/revisions/diff [get]
+func (app *application) diffPostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	post, err := getPostFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	from, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	to := post.Version
+	if s := r.URL.Query().Get("to"); s != "" {
+		to, err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+	}
+
+	older, err := app.postVersion(r.Context(), post, from)
+	if err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	newer, err := app.postVersion(r.Context(), post, to)
+	if err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	tagsAdded, tagsRemoved := diff.Sets(older.Tags, newer.Tags)
+	response := RevisionDiff{
+		From:        from,
+		To:          to,
+		Title:       diff.Lines(older.Title, newer.Title),
+		Content:     diff.Lines(older.Content, newer.Content),
+		TagsAdded:   tagsAdded,
+		TagsRemoved: tagsRemoved,
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Restore Post Revision godoc
+//
+//	@Summary		Restores a revision of a post
+//	@Description	Makes a prior version of a post the current one, the replaced version is kept as a revision. If-Match must carry the ETag of the current version, the ETag of the new version is returned. Requires the moderator role
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int		true	"Post ID"
+//	@Param			version		path		int		true	"Version"
+//	@Param			If-Match	header		string	true	"ETag of the current version"
+//	@Success		200			{object}	store.Post
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		412			{object}	error
+//	@Failure		428			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/revisions/{version}/restore [put]
+func (app *application) restorePostRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	post, err := getPostFromContext(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := checkIfMatch(r, post); err != nil {
+		app.ifMatchError(w, r, err)
+		return
+	}
+
+	version, err := strconv.ParseInt(chi.URLParam(r, "version"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if version >= post.Version {
+		app.badRequest(w, r, errors.New("only prior versions can be restored"))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.Post.RestoreRevision(r.Context(), post, version, user.ID); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		case store.ErrVersionMismatch:
+			app.preconditionFailed(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	app.indexPost(r.Context(), post)
+
+	if err := writePost(w, post); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// postVersion returns a version of the post, the current one or a revision.
+func (app *application) postVersion(ctx context.Context, post *store.Post, version int64) (*store.PostRevision, error) {
+	if version == post.Version {
+		return &store.PostRevision{
+			PostID:  post.ID,
+			Version: post.Version,
+			Title:   post.Title,
+			Content: post.Content,
+			Tags:    post.Tags,
+		}, nil
+	}
+
+	return app.store.Post.GetRevision(ctx, post.ID, version)
+}
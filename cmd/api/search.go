@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/tenteedee/gopher-social/internal/search"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+// Search godoc
+//
+//	@Summary		Full-text search
+//	@Description	Searches posts, comments and users. Supports "quoted phrases", -exclusions, #tag and @user filters. Only posts the user may see, and their comments, are returned, and nothing from users blocked either way. Results carry an HTML snippet, the text escaped and the matches in mark tags, not the media or poll of posts
+//	@Tags			search
+//	@Accept			json
+//	@Produce		json
+//	@Param			q		query		string	true	"Search query"
+//	@Param			type	query		string	false	"Result type (posts, comments, users)"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset, at most 1000"
+//	@Success		200		{object}	[]store.SearchResult
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/search [get]
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	sq := store.SearchQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	sq, err := sq.Parse(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(sq); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	sq.ViewerID = getUserFromContext(r).ID
+
+	results, err := app.searchIndexer.Search(r.Context(), sq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, results); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// publishSearchEvent keeps the search index in sync with writes. A failing index
+// must not fail the request, the reindex command repairs any drift.
+func (app *application) publishSearchEvent(ctx context.Context, event search.Event) {
+	if err := search.Apply(ctx, app.searchIndexer, event); err != nil {
+		app.logger.Errorw("failed to update search index",
+			"action", event.Action,
+			"document", event.Document.Key(),
+			"error", err,
+		)
+	}
+}
+
+// indexDocument upserts a document built by one of the search.*Document
+// constructors, it is skipped when it could not be built.
+func (app *application) indexDocument(ctx context.Context, doc search.Document, err error) {
+	if err != nil {
+		app.logger.Errorw("failed to build search document", "document", doc.Key(), "error", err)
+		return
+	}
+
+	app.publishSearchEvent(ctx, search.Event{
+		Action:   search.ActionUpsert,
+		Document: doc,
+	})
+}
+
+func (app *application) indexPost(ctx context.Context, post *store.Post) {
+	if post.Kind == store.PostKindRepost || post.Status != store.PostStatusPublished {
+		return
+	}
+
+	author, err := app.getUser(ctx, post.UserID)
+	if err != nil {
+		app.logger.Errorw("failed to load post author for search index", "post_id", post.ID, "error", err)
+		return
+	}
+
+	doc, err := search.PostDocument(post, author.Username)
+	app.indexDocument(ctx, doc, err)
+}
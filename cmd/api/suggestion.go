@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+// Get Suggestions godoc
+//
+//	@Summary		Suggests users to follow
+//	@Description	Suggests users to follow, ranked by mutual follows, shared tag interests and recent activity. Suggestions are refreshed by a background job
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.Suggestion
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/suggestions [get]
+func (app *application) getSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	pq := store.PaginationQuery{
+		Limit:  10,
+		Offset: 0,
+	}
+
+	pq, err := pq.Parse(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(pq); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	suggestions, err := app.getSuggestions(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	start := min(pq.Offset, int64(len(suggestions)))
+	end := min(start+pq.Limit, int64(len(suggestions)))
+
+	if err := app.jsonResponse(w, http.StatusOK, suggestions[start:end]); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getSuggestions(ctx context.Context, userID int64) ([]store.Suggestion, error) {
+	if !app.config.redisCfg.enabled {
+		return app.store.Suggestions.GetByUserID(ctx, userID)
+	}
+
+	suggestions, err := app.cacheStorage.Suggestions.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if suggestions != nil {
+		return suggestions, nil
+	}
+
+	suggestions, err = app.store.Suggestions.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := app.cacheStorage.Suggestions.Set(ctx, userID, suggestions); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+// invalidateSuggestions drops cached suggestions after the users' relations
+// changed, so a followed or blocked user is not suggested until the next run.
+func (app *application) invalidateSuggestions(ctx context.Context, userIDs ...int64) {
+	if !app.config.redisCfg.enabled {
+		return
+	}
+
+	if err := app.cacheStorage.Suggestions.Delete(ctx, userIDs...); err != nil {
+		app.logger.Errorw("failed to invalidate suggestions cache", "ids", userIDs, "error", err)
+	}
+}
+
+// computeSuggestionsJob refreshes the suggestions of a batch of stale users.
+func (app *application) computeSuggestionsJob(ctx context.Context) error {
+	now := time.Now()
+
+	userIDs, err := app.store.Suggestions.Stale(
+		ctx,
+		now,
+		app.config.jobs.suggestionsMaxAge,
+		app.config.jobs.suggestionsBatchSize,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := app.store.Suggestions.Compute(ctx, userIDs, now); err != nil {
+		return err
+	}
+
+	app.invalidateSuggestions(ctx, userIDs...)
+
+	return nil
+}
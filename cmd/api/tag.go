@@ -0,0 +1,154 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+type tagParam struct {
+	Tag string `validate:"required,max=100"`
+}
+
+// Follow Tag godoc
+//
+//	@Summary		Follow a tag
+//	@Description	Follow a tag, posts carrying it show up in the feed
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			tag	path		string	true	"Tag"
+//	@Success		204	{object}	nil
+//	@Failure		400	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/follow [put]
+func (app *application) followTagHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	param := tagParam{Tag: store.NormalizeTag(chi.URLParam(r, "tag"))}
+	if err := Validate.Struct(param); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := app.store.TagFollow.Follow(r.Context(), user.ID, param.Tag); err != nil {
+		switch err {
+		case store.ErrConflict:
+			app.conflictResponse(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Unfollow Tag godoc
+//
+//	@Summary		Unfollow a tag
+//	@Description	Unfollow a tag
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			tag	path		string	true	"Tag"
+//	@Success		204	{object}	nil
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/unfollow [put]
+func (app *application) unfollowTagHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	param := tagParam{Tag: store.NormalizeTag(chi.URLParam(r, "tag"))}
+	if err := Validate.Struct(param); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := app.store.TagFollow.Unfollow(r.Context(), user.ID, param.Tag); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get Followed Tags godoc
+//
+//	@Summary		Lists followed tags
+//	@Description	Lists the tags the authenticated user follows
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	[]store.TagFollow
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/following [get]
+func (app *application) getFollowedTagsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	tags, err := app.store.TagFollow.GetByUserID(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Autocomplete Tags godoc
+//
+//	@Summary		Autocompletes tags
+//	@Description	Lists the tags in use starting with a prefix, the most used first. The prefix is normalized like tags are
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			q		query		string	true	"Prefix"
+//	@Param			limit	query		int		false	"Limit"
+//	@Success		200		{object}	[]store.Tag
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/autocomplete [get]
+func (app *application) autocompleteTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tq := store.TagQuery{
+		Limit: 10,
+	}
+
+	tq, err := tq.Parse(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(tq); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	tags, err := app.store.Tags.Autocomplete(r.Context(), tq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
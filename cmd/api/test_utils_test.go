@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tenteedee/gopher-social/internal/auth"
+	"github.com/tenteedee/gopher-social/internal/search"
+	"github.com/tenteedee/gopher-social/internal/store"
+	"go.uber.org/zap"
+)
+
+func newTestApplication(t *testing.T, cfg config) *application {
+	t.Helper()
+
+	logger := zap.NewNop().Sugar()
+	mockStore := store.NewMockStore()
+
+	return &application{
+		config:        cfg,
+		store:         &mockStore,
+		logger:        logger,
+		authenticator: &auth.TestAuthenticator{},
+		searchIndexer: search.NewPostgresIndexer(nil, &mockStore),
+	}
+}
+
+func executeRequest(req *http.Request, mux http.Handler) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func checkResponseCode(t *testing.T, expected, actual int) {
+	t.Helper()
+
+	if expected != actual {
+		t.Errorf("expected the response code to be %d, got %d", expected, actual)
+	}
+}
+
+func testToken(t *testing.T, app *application, userID int64) string {
+	t.Helper()
+
+	token, err := app.authenticator.GenerateToken(auth.TestClaims(userID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+// authedRequest returns a request made by the user, carrying their token. body
+// may be nil.
+func authedRequest(t *testing.T, app *application, userID int64, method, path string, body io.Reader) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(method, path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken(t, app, userID))
+
+	return req
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tenteedee/gopher-social/internal/search"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+// trashPurgeBatchSize is how many posts, and how many comments, a purge batch removes at most.
+const trashPurgeBatchSize = 500
+
+// trashSince is the oldest deletion that can still be restored.
+func (app *application) trashSince() time.Time {
+	return time.Now().Add(-app.config.trash.retention)
+}
+
+// Get Trash godoc
+//
+//	@Summary		Lists the trash
+//	@Description	Lists the deleted posts and comments of the authenticated user that can still be restored, most recently deleted first
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.TrashItem
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/trash [get]
+func (app *application) getTrashHandler(w http.ResponseWriter, r *http.Request) {
+	page := store.PaginationQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	page, err := page.Parse(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(page); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	items, err := app.store.Trash.List(r.Context(), user.ID, app.trashSince(), page)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, items); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Restore Trash Item godoc
+//
+//	@Summary		Restores a deleted post or comment
+//	@Description	Takes a post or comment out of the trash. Authors restore what they deleted themselves, moderators restore what a moderator deleted. Items deleted before the deleting user was recorded count as deleted by their author
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			kind	path		string	true	"post or comment"
+//	@Param			itemID	path		int		true	"Post or comment ID"
+//	@Success		204		{object}	nil
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/trash/{kind}/{itemID}/restore [put]
+func (app *application) restoreTrashItemHandler(w http.ResponseWriter, r *http.Request) {
+	kind := chi.URLParam(r, "kind")
+	if kind != store.TrashKindPost && kind != store.TrashKindComment {
+		app.badRequest(w, r, errors.New("kind must be post or comment"))
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "itemID"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	since := app.trashSince()
+
+	item, err := app.store.Trash.Get(r.Context(), kind, id, since)
+	if err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	isModerator, err := app.checkRolePrecedence(r.Context(), user, "moderator")
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// items deleted before deleted_by was recorded count as deleted by their author
+	deletedByAuthor := item.DeletedBy == nil || *item.DeletedBy == item.UserID
+
+	switch {
+	case item.UserID == user.ID:
+		if !deletedByAuthor && !isModerator {
+			app.forbidden(w, r, errors.New("deleted by a moderator, only a moderator can restore it"))
+			return
+		}
+	case isModerator:
+		if deletedByAuthor {
+			app.forbidden(w, r, errors.New("deleted by its author, only they can restore it"))
+			return
+		}
+	default:
+		// the trash of other users is not there for this user
+		app.notFound(w, r, store.ErrorNotFound)
+		return
+	}
+
+	if err := app.store.Trash.Restore(r.Context(), kind, id, since); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		case store.ErrConflict:
+			app.conflictResponse(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	app.indexRestored(r.Context(), item, user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// indexRestored puts a restored post or comment back in the search index. A
+// post comes back with its live comments, the index dropped them along with it.
+func (app *application) indexRestored(ctx context.Context, item *store.TrashItem, viewerID int64) {
+	if item.Kind == store.TrashKindPost {
+		post, err := app.store.Post.GetByID(ctx, item.ID)
+		if err != nil {
+			app.logger.Errorw("failed to load restored post for search index", "post_id", item.ID, "error", err)
+			return
+		}
+		app.indexPost(ctx, post)
+
+		comments, err := app.store.Comment.GetLiveByPostID(ctx, post.ID)
+		if err != nil {
+			app.logger.Errorw("failed to load comments of restored post for search index", "post_id", item.ID, "error", err)
+			return
+		}
+		for _, comment := range comments {
+			doc, err := search.CommentDocument(comment)
+			app.indexDocument(ctx, doc, err)
+		}
+		return
+	}
+
+	comment, err := app.store.Comment.GetByID(ctx, item.PostID, item.ID, viewerID)
+	if err != nil {
+		app.logger.Errorw("failed to load restored comment for search index", "comment_id", item.ID, "error", err)
+		return
+	}
+	doc, err := search.CommentDocument(comment)
+	app.indexDocument(ctx, doc, err)
+}
+
+// purgeTrashJob hard-deletes what stayed in the trash past the retention window,
+// in batches of trashPurgeBatchSize so no single statement holds its locks for
+// the whole backlog.
+func (app *application) purgeTrashJob(ctx context.Context) error {
+	since := app.trashSince()
+
+	var purged int64
+	for {
+		batch, err := app.store.Trash.Purge(ctx, since, trashPurgeBatchSize)
+		if err != nil {
+			return err
+		}
+		if batch == 0 {
+			break
+		}
+		purged += batch
+	}
+
+	if purged > 0 {
+		app.logger.Infow("purged trash", "rows", purged)
+	}
+
+	return nil
+}
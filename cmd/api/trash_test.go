@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/tenteedee/gopher-social/internal/search"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+// recordingIndexer keeps the documents upserted into the search index.
+type recordingIndexer struct {
+	search.Indexer
+	indexed []search.Document
+}
+
+func (idx *recordingIndexer) Index(ctx context.Context, doc search.Document) error {
+	idx.indexed = append(idx.indexed, doc)
+	return nil
+}
+
+func TestRestoreTrashItem(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	indexer := &recordingIndexer{Indexer: app.searchIndexer}
+	app.searchIndexer = indexer
+
+	users := app.store.User.(*store.MockUserStore)
+	users.Users[1] = &store.User{ID: 1, Username: "author"}
+	users.Users[2] = &store.User{ID: 2, Username: "moderator", Role: store.Role{Name: "moderator", Level: 2}}
+	users.Users[3] = &store.User{ID: 3, Username: "other"}
+
+	createdAt := "2024-01-02T03:04:05Z"
+	posts := app.store.Post.(*store.MockPostStore)
+	posts.Posts[1] = &store.Post{ID: 1, UserID: 1, Title: "restored", Status: store.PostStatusPublished, CreatedAt: createdAt}
+
+	comments := app.store.Comment.(*store.MockCommentStore)
+	comments.Comments = []*store.Comment{
+		{ID: 1, PostID: 1, UserID: 3, Content: "live", CreatedAt: createdAt, User: store.User{ID: 3, Username: "other"}},
+		{ID: 2, PostID: 1, UserID: 3, Content: "deleted", CreatedAt: createdAt, Deleted: true},
+	}
+
+	author, moderator := int64(1), int64(2)
+	trash := app.store.Trash.(*store.MockTrashStore)
+	trash.Items = []*store.TrashItem{
+		{Kind: store.TrashKindPost, ID: 1, PostID: 1, UserID: 1, DeletedBy: &author},
+		{Kind: store.TrashKindComment, ID: 10, PostID: 1, UserID: 1, DeletedBy: &moderator},
+		{Kind: store.TrashKindComment, ID: 11, PostID: 1, UserID: 1},
+		{Kind: store.TrashKindComment, ID: 12, PostID: 1, UserID: 3, DeletedBy: &moderator},
+	}
+
+	newRequest := func(t *testing.T, path string, userID int64) *http.Request {
+		return authedRequest(t, app, userID, http.MethodPut, "/v1/users/me/trash/"+path+"/restore", nil)
+	}
+
+	t.Run("should not let a moderator restore what the author deleted", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "post/1", 2), mux)
+
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("should not let the author restore what a moderator deleted", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "comment/10", 1), mux)
+
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("should hide the trash of other users", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "post/1", 3), mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should let a moderator restore what a moderator deleted", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "comment/10", 2), mux)
+
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("should count an unknown deleter as the author", func(t *testing.T) {
+		rr := executeRequest(newRequest(t, "comment/11", 2), mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+
+		rr = executeRequest(newRequest(t, "comment/11", 1), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("should restore a post with its live comments in the index", func(t *testing.T) {
+		indexer.indexed = nil
+
+		rr := executeRequest(newRequest(t, "post/1", 1), mux)
+
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+		keys := []string{}
+		for _, doc := range indexer.indexed {
+			keys = append(keys, doc.Key())
+		}
+		if len(keys) != 2 || keys[0] != "posts:1" || keys[1] != "comments:1" {
+			t.Errorf("expected post 1 and comment 1 to be indexed, got %v", keys)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+// Get Trending Tags godoc
+//
+//	@Summary		Fetches trending tags
+//	@Description	Fetches the latest trending tags snapshot for a rolling window
+//	@Tags			trends
+//	@Accept			json
+//	@Produce		json
+//	@Param			window	query		string	false	"Window (1h, 24h, 7d)"
+//	@Param			limit	query		int		false	"Limit"
+//	@Success		200		{object}	[]store.TrendingTag
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/trends/tags [get]
+func (app *application) getTrendingTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tq, err := app.parseTrendQuery(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	tags, err := app.store.Trends.GetTags(r.Context(), tq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Get Trending Posts godoc
+//
+//	@Summary		Fetches trending posts
+//	@Description	Fetches the latest trending posts snapshot for a rolling window, applying the viewer's blocks, mutes and mute filters
+//	@Tags			trends
+//	@Accept			json
+//	@Produce		json
+//	@Param			window	query		string	false	"Window (1h, 24h, 7d)"
+//	@Param			limit	query		int		false	"Limit"
+//	@Success		200		{object}	[]store.TrendingPost
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/trends/posts [get]
+func (app *application) getTrendingPostsHandler(w http.ResponseWriter, r *http.Request) {
+	tq, err := app.parseTrendQuery(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	viewer := getUserFromContext(r)
+
+	posts, err := app.store.Trends.GetPosts(r.Context(), tq, viewer.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) parseTrendQuery(r *http.Request) (store.TrendQuery, error) {
+	tq := store.TrendQuery{
+		Window: "24h",
+		Limit:  10,
+	}
+
+	tq, err := tq.Parse(r)
+	if err != nil {
+		return tq, err
+	}
+
+	if err := Validate.Struct(tq); err != nil {
+		return tq, err
+	}
+
+	return tq, nil
+}
+
+func (app *application) computeTrendsJob(ctx context.Context) error {
+	now := time.Now()
+
+	if err := app.store.Trends.Compute(ctx, now); err != nil {
+		return err
+	}
+
+	return app.store.Trends.Prune(ctx, now.Add(-app.config.jobs.trendsRetention))
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/tenteedee/gopher-social/internal/search"
 	"github.com/tenteedee/gopher-social/internal/store"
 )
 
@@ -13,6 +15,14 @@ type FollowUserPayload struct {
 	FollowedUserID int64 `json:"followed_user_id"`
 }
 
+type FollowUserResponse struct {
+	Status string `json:"status"`
+}
+
+type UpdatePrivacyPayload struct {
+	IsPrivate *bool `json:"is_private" validate:"required"`
+}
+
 // Get User by ID godoc
 //
 //	@Summary		Fetch a user by ID
@@ -21,7 +31,7 @@ type FollowUserPayload struct {
 //	@Accept			json
 //	@Produce		json
 //	@Param			id	path		int	true	"User ID"
-//	@Success		200	{object}	store.User
+//	@Success		200	{object}	store.UserProfile
 //	@Failure		400	{object}	error
 //	@Failure		404	{object}	error
 //	@Failure		500	{object}	error
@@ -34,7 +44,9 @@ func (app *application) getUserByIdHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	user, err := app.getUser(r.Context(), userId)
+	viewer := getUserFromContext(r)
+
+	user, err := app.store.User.GetProfile(r.Context(), userId, viewer.ID)
 	if err != nil {
 		switch err {
 		case store.ErrorNotFound:
@@ -77,13 +89,15 @@ func (app *application) getUserProfileHandler(w http.ResponseWriter, r *http.Req
 // Follow User godoc
 //
 //	@Summary		Follow a user
-//	@Description	Follow a user
+//	@Description	Follow a user. Following a private account files a follow request instead and answers 202
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			id	path		int	true	"User ID"
-//	@Success		204 {object}	nil
+//	@Success		204	{object}	nil
+//	@Success		202	{object}	FollowUserResponse
 //	@Failure		400	{object}	error
+//	@Failure		403	{object}	error
 //	@Failure		404	{object}	error
 //	@Failure		500	{object}	error
 //	@Security		ApiKeyAuth
@@ -102,7 +116,7 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.store.Follow.Follow(r.Context(), followedUserID, userID)
+	status, err := app.store.Follow.Follow(r.Context(), followedUserID, userID)
 	if err != nil {
 		switch err {
 		case store.ErrorNotFound:
@@ -111,18 +125,31 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 		case store.ErrConflict:
 			app.conflictResponse(w, r, err)
 			return
+		case store.ErrBlocked:
+			app.forbidden(w, r, err)
+			return
 		default:
 			app.internalServerError(w, r, err)
 			return
 		}
 	}
+
+	app.invalidateSuggestions(r.Context(), userID)
+
+	if status == store.FollowStatusRequested {
+		if err := app.jsonResponse(w, http.StatusAccepted, FollowUserResponse{Status: status}); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // Unfollow User godoc
 //
 //	@Summary		Unfollow a user
-//	@Description	Unfollow a user
+//	@Description	Unfollow a user, or withdraw a pending follow request
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
@@ -157,6 +184,259 @@ func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Update Privacy godoc
+//
+//	@Summary		Updates the account privacy
+//	@Description	Makes the authenticated account private or public. Going public approves all pending follow requests
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdatePrivacyPayload	true	"Privacy setting"
+//	@Success		204		{object}	nil
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/privacy [put]
+func (app *application) updatePrivacyHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdatePrivacyPayload
+	if err := ReadJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.User.SetPrivacy(r.Context(), user.ID, *payload.IsPrivate); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	if app.config.redisCfg.enabled {
+		if err := app.cacheStorage.User.Delete(r.Context(), user.ID); err != nil {
+			app.logger.Errorw("failed to invalidate user cache", "id", user.ID, "error", err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get Follow Requests godoc
+//
+//	@Summary		Lists pending follow requests
+//	@Description	Lists the pending follow requests of the authenticated user, oldest first
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.FollowRequest
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests [get]
+func (app *application) getFollowRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	pq := store.PaginationQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	pq, err := pq.Parse(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(pq); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	requests, err := app.store.Follow.GetRequests(r.Context(), user.ID, pq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, requests); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// Approve Follow Request godoc
+//
+//	@Summary		Approves a follow request
+//	@Description	Approves a pending follow request, the requester becomes a follower
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			requesterID	path		int	true	"Requester ID"
+//	@Success		204			{object}	nil
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests/{requesterID}/approve [put]
+func (app *application) approveFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.resolveFollowRequest(w, r, app.store.Follow.ApproveRequest)
+}
+
+// Reject Follow Request godoc
+//
+//	@Summary		Rejects a follow request
+//	@Description	Rejects a pending follow request
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			requesterID	path		int	true	"Requester ID"
+//	@Success		204			{object}	nil
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests/{requesterID}/reject [put]
+func (app *application) rejectFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.resolveFollowRequest(w, r, app.store.Follow.RejectRequest)
+}
+
+func (app *application) resolveFollowRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+	resolve func(context.Context, int64, int64) error,
+) {
+	requesterID, err := strconv.ParseInt(chi.URLParam(r, "requesterID"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := resolve(r.Context(), user.ID, requesterID); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get Followers godoc
+//
+//	@Summary		Lists the followers of a user
+//	@Description	Lists the followers of a user, most recent first. Not found when the user and the viewer blocked one another, or for a private account the viewer does not follow. Blocked users are left out
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int	true	"User ID"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.FollowListEntry
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/followers [get]
+func (app *application) getFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	app.listFollows(w, r, app.store.Follow.GetFollowers)
+}
+
+// Get Following godoc
+//
+//	@Summary		Lists the users a user follows
+//	@Description	Lists the users a user follows, most recent first. Not found when the user and the viewer blocked one another, or for a private account the viewer does not follow. Blocked users are left out
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int	true	"User ID"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.FollowListEntry
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/following [get]
+func (app *application) getFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	app.listFollows(w, r, app.store.Follow.GetFollowing)
+}
+
+func (app *application) listFollows(
+	w http.ResponseWriter,
+	r *http.Request,
+	list func(context.Context, int64, int64, store.PaginationQuery) ([]store.FollowListEntry, error),
+) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	pq := store.PaginationQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	pq, err = pq.Parse(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(pq); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if _, err := app.getUser(r.Context(), userID); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	viewer := getUserFromContext(r)
+
+	entries, err := list(r.Context(), userID, viewer.ID, pq)
+	if err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFound(w, r, err)
+			return
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, entries); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
 // Activate User godoc
 //
 //	@Summary		Activates/Register a user
@@ -172,7 +452,7 @@ func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Reque
 func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Request) {
 	token := chi.URLParam(r, "token")
 
-	err := app.store.User.Activate(r.Context(), token)
+	user, err := app.store.User.Activate(r.Context(), token)
 	if err != nil {
 		switch err {
 		case store.ErrorNotFound:
@@ -184,6 +464,9 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
+	doc, err := search.UserDocument(user)
+	app.indexDocument(r.Context(), doc, err)
+
 	if err := app.jsonResponse(w, http.StatusNoContent, "User activated"); err != nil {
 		app.internalServerError(w, r, err)
 		return
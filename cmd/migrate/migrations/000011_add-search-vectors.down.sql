@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_users_search_vector;
+DROP INDEX IF EXISTS idx_comments_search_vector;
+DROP INDEX IF EXISTS idx_posts_search_vector;
+
+ALTER TABLE IF EXISTS users
+DROP COLUMN IF EXISTS search_vector;
+
+ALTER TABLE IF EXISTS comments
+DROP COLUMN IF EXISTS search_vector;
+
+ALTER TABLE IF EXISTS posts
+DROP COLUMN IF EXISTS search_vector;
+
+DROP FUNCTION IF EXISTS immutable_array_to_string(TEXT[], TEXT);
@@ -0,0 +1,28 @@
+-- array_to_string is only STABLE, generated columns need an IMMUTABLE expression
+CREATE OR REPLACE FUNCTION immutable_array_to_string(TEXT[], TEXT)
+RETURNS TEXT
+LANGUAGE sql IMMUTABLE
+AS $$ SELECT array_to_string($1, $2) $$;
+
+ALTER TABLE IF EXISTS posts
+ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+  setweight(to_tsvector('english', coalesce(content, '')), 'B') ||
+  setweight(to_tsvector('simple', coalesce(immutable_array_to_string(tags, ' '), '')), 'C')
+) STORED;
+
+ALTER TABLE IF EXISTS comments
+ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+  to_tsvector('english', coalesce(content, ''))
+) STORED;
+
+ALTER TABLE IF EXISTS users
+ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+  to_tsvector('simple', coalesce(username, ''))
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING gin (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_comments_search_vector ON comments USING gin (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING gin (search_vector);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_comments_created_at;
+DROP INDEX IF EXISTS idx_posts_created_at;
+
+DROP TABLE IF EXISTS trending_posts;
+DROP TABLE IF EXISTS trending_tags;
+
+ALTER TABLE IF EXISTS posts
+DROP COLUMN IF EXISTS moderated_by,
+DROP COLUMN IF EXISTS moderated_at;
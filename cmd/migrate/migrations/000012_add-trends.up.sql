@@ -0,0 +1,34 @@
+ALTER TABLE IF EXISTS posts
+ADD COLUMN IF NOT EXISTS moderated_at TIMESTAMP(0) WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS moderated_by BIGINT REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS trending_tags (
+  id BIGSERIAL PRIMARY KEY,
+  time_window VARCHAR(8) NOT NULL,
+  tag TEXT NOT NULL,
+  score DOUBLE PRECISION NOT NULL,
+  posts_count BIGINT NOT NULL DEFAULT 0,
+  comments_count BIGINT NOT NULL DEFAULT 0,
+  rank INT NOT NULL,
+  computed_at TIMESTAMP(0) WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS trending_posts (
+  id BIGSERIAL PRIMARY KEY,
+  time_window VARCHAR(8) NOT NULL,
+  post_id BIGINT NOT NULL,
+  score DOUBLE PRECISION NOT NULL,
+  comments_count BIGINT NOT NULL DEFAULT 0,
+  rank INT NOT NULL,
+  computed_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+
+  CONSTRAINT fk_post FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_trending_tags_window ON trending_tags (time_window, computed_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_trending_posts_window ON trending_posts (time_window, computed_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts (created_at);
+
+CREATE INDEX IF NOT EXISTS idx_comments_created_at ON comments (created_at);
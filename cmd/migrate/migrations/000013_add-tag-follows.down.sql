@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_followers_follower_id;
+DROP INDEX IF EXISTS idx_user_tag_follows_tag;
+
+DROP TABLE IF EXISTS user_tag_follows;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS user_tag_follows (
+  user_id BIGINT NOT NULL,
+  tag VARCHAR(100) NOT NULL,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT now(),
+
+  PRIMARY KEY (user_id, tag),
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_tag_follows_tag ON user_tag_follows (tag);
+
+CREATE INDEX IF NOT EXISTS idx_followers_follower_id ON followers (follower_id);
@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS trg_posts_counters ON posts;
+DROP TRIGGER IF EXISTS trg_followers_counters ON followers;
+
+DROP FUNCTION IF EXISTS update_post_counters();
+DROP FUNCTION IF EXISTS update_follow_counters();
+
+ALTER TABLE IF EXISTS users
+DROP COLUMN IF EXISTS posts_count,
+DROP COLUMN IF EXISTS following_count,
+DROP COLUMN IF EXISTS followers_count;
@@ -0,0 +1,46 @@
+ALTER TABLE IF EXISTS users
+ADD COLUMN IF NOT EXISTS followers_count BIGINT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS following_count BIGINT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS posts_count BIGINT NOT NULL DEFAULT 0;
+
+-- keep the counters in sync on every write, including cascading deletes
+CREATE OR REPLACE FUNCTION update_follow_counters() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    UPDATE users SET followers_count = followers_count + 1 WHERE id = NEW.user_id;
+    UPDATE users SET following_count = following_count + 1 WHERE id = NEW.follower_id;
+    RETURN NEW;
+  END IF;
+
+  UPDATE users SET followers_count = GREATEST(followers_count - 1, 0) WHERE id = OLD.user_id;
+  UPDATE users SET following_count = GREATEST(following_count - 1, 0) WHERE id = OLD.follower_id;
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION update_post_counters() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    UPDATE users SET posts_count = posts_count + 1 WHERE id = NEW.user_id;
+    RETURN NEW;
+  END IF;
+
+  UPDATE users SET posts_count = GREATEST(posts_count - 1, 0) WHERE id = OLD.user_id;
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_followers_counters ON followers;
+CREATE TRIGGER trg_followers_counters
+AFTER INSERT OR DELETE ON followers
+FOR EACH ROW EXECUTE FUNCTION update_follow_counters();
+
+DROP TRIGGER IF EXISTS trg_posts_counters ON posts;
+CREATE TRIGGER trg_posts_counters
+AFTER INSERT OR DELETE ON posts
+FOR EACH ROW EXECUTE FUNCTION update_post_counters();
+
+UPDATE users u SET
+  followers_count = (SELECT COUNT(*) FROM followers f WHERE f.user_id = u.id),
+  following_count = (SELECT COUNT(*) FROM followers f WHERE f.follower_id = u.id),
+  posts_count = (SELECT COUNT(*) FROM posts p WHERE p.user_id = u.id);
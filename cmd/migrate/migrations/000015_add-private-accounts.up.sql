@@ -0,0 +1,12 @@
+ALTER TABLE IF EXISTS users
+ADD COLUMN IF NOT EXISTS is_private BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS follow_requests (
+  user_id BIGINT NOT NULL,
+  requester_id BIGINT NOT NULL,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT now(),
+
+  PRIMARY KEY (user_id, requester_id),
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (requester_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS user_blocks (
+  blocker_id BIGINT NOT NULL,
+  blocked_id BIGINT NOT NULL,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT now(),
+
+  PRIMARY KEY (blocker_id, blocked_id),
+  FOREIGN KEY (blocker_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (blocked_id) REFERENCES users (id) ON DELETE CASCADE,
+  CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_blocks_blocked_id ON user_blocks (blocked_id);
+
+CREATE TABLE IF NOT EXISTS user_mutes (
+  muter_id BIGINT NOT NULL,
+  muted_id BIGINT NOT NULL,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT now(),
+
+  PRIMARY KEY (muter_id, muted_id),
+  FOREIGN KEY (muter_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (muted_id) REFERENCES users (id) ON DELETE CASCADE,
+  CHECK (muter_id <> muted_id)
+);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS mute_filters (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  kind VARCHAR(10) NOT NULL,
+  value VARCHAR(100) NOT NULL,
+  pattern TEXT NOT NULL,
+  whole_word BOOLEAN NOT NULL DEFAULT false,
+  action VARCHAR(10) NOT NULL DEFAULT 'hide',
+  expires_at TIMESTAMP(0) WITH TIME ZONE,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT now(),
+
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  UNIQUE (user_id, kind, value),
+  CHECK (kind IN ('word', 'phrase', 'tag')),
+  CHECK (action IN ('hide', 'warn'))
+);
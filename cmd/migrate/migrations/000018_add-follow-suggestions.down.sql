@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_user_tag_follows_created_at;
+
+DROP TABLE IF EXISTS follow_suggestion_runs;
+
+DROP INDEX IF EXISTS idx_follow_suggestions_user_score;
+DROP TABLE IF EXISTS follow_suggestions;
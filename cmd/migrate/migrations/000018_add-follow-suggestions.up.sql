@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS follow_suggestions (
+  user_id BIGINT NOT NULL,
+  suggested_id BIGINT NOT NULL,
+  score DOUBLE PRECISION NOT NULL,
+  mutual_count BIGINT NOT NULL DEFAULT 0,
+  shared_tags_count BIGINT NOT NULL DEFAULT 0,
+  computed_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+
+  PRIMARY KEY (user_id, suggested_id),
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (suggested_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_follow_suggestions_user_score ON follow_suggestions (user_id, score DESC);
+
+-- Tracks when suggestions were last computed for a user, so the job only
+-- refreshes users whose follows or tag interests changed since then.
+CREATE TABLE IF NOT EXISTS follow_suggestion_runs (
+  user_id BIGINT PRIMARY KEY,
+  computed_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_tag_follows_created_at ON user_tag_follows (user_id, created_at);
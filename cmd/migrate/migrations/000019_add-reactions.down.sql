@@ -0,0 +1,17 @@
+DROP TRIGGER IF EXISTS trg_comment_reactions_counts ON comment_reactions;
+DROP TRIGGER IF EXISTS trg_post_reactions_counts ON post_reactions;
+
+DROP FUNCTION IF EXISTS update_comment_reaction_counts();
+DROP FUNCTION IF EXISTS update_post_reaction_counts();
+
+ALTER TABLE IF EXISTS comments
+DROP COLUMN IF EXISTS reaction_counts;
+
+ALTER TABLE IF EXISTS posts
+DROP COLUMN IF EXISTS reaction_counts;
+
+DROP INDEX IF EXISTS idx_comment_reactions_comment_created_at;
+DROP TABLE IF EXISTS comment_reactions;
+
+DROP INDEX IF EXISTS idx_post_reactions_post_created_at;
+DROP TABLE IF EXISTS post_reactions;
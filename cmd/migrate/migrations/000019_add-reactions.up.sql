@@ -0,0 +1,88 @@
+CREATE TABLE IF NOT EXISTS post_reactions (
+  post_id BIGINT NOT NULL,
+  user_id BIGINT NOT NULL,
+  reaction VARCHAR(32) NOT NULL,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT now(),
+
+  PRIMARY KEY (post_id, user_id, reaction),
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_reactions_post_created_at ON post_reactions (post_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS comment_reactions (
+  comment_id BIGINT NOT NULL,
+  user_id BIGINT NOT NULL,
+  reaction VARCHAR(32) NOT NULL,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT now(),
+
+  PRIMARY KEY (comment_id, user_id, reaction),
+  FOREIGN KEY (comment_id) REFERENCES comments (id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_reactions_comment_created_at ON comment_reactions (comment_id, created_at DESC);
+
+ALTER TABLE IF EXISTS posts
+ADD COLUMN IF NOT EXISTS reaction_counts JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE IF EXISTS comments
+ADD COLUMN IF NOT EXISTS reaction_counts JSONB NOT NULL DEFAULT '{}';
+
+-- per reaction counts, e.g. {"like": 3, "🎉": 1}; a key is dropped once it reaches zero
+CREATE OR REPLACE FUNCTION update_post_reaction_counts() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    UPDATE posts
+    SET reaction_counts = jsonb_set(
+      reaction_counts,
+      ARRAY[NEW.reaction],
+      to_jsonb(COALESCE((reaction_counts ->> NEW.reaction)::BIGINT, 0) + 1)
+    )
+    WHERE id = NEW.post_id;
+    RETURN NEW;
+  END IF;
+
+  UPDATE posts
+  SET reaction_counts = CASE
+    WHEN COALESCE((reaction_counts ->> OLD.reaction)::BIGINT, 0) <= 1 THEN reaction_counts - OLD.reaction
+    ELSE jsonb_set(reaction_counts, ARRAY[OLD.reaction], to_jsonb((reaction_counts ->> OLD.reaction)::BIGINT - 1))
+  END
+  WHERE id = OLD.post_id;
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION update_comment_reaction_counts() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    UPDATE comments
+    SET reaction_counts = jsonb_set(
+      reaction_counts,
+      ARRAY[NEW.reaction],
+      to_jsonb(COALESCE((reaction_counts ->> NEW.reaction)::BIGINT, 0) + 1)
+    )
+    WHERE id = NEW.comment_id;
+    RETURN NEW;
+  END IF;
+
+  UPDATE comments
+  SET reaction_counts = CASE
+    WHEN COALESCE((reaction_counts ->> OLD.reaction)::BIGINT, 0) <= 1 THEN reaction_counts - OLD.reaction
+    ELSE jsonb_set(reaction_counts, ARRAY[OLD.reaction], to_jsonb((reaction_counts ->> OLD.reaction)::BIGINT - 1))
+  END
+  WHERE id = OLD.comment_id;
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_post_reactions_counts ON post_reactions;
+CREATE TRIGGER trg_post_reactions_counts
+AFTER INSERT OR DELETE ON post_reactions
+FOR EACH ROW EXECUTE FUNCTION update_post_reaction_counts();
+
+DROP TRIGGER IF EXISTS trg_comment_reactions_counts ON comment_reactions;
+CREATE TRIGGER trg_comment_reactions_counts
+AFTER INSERT OR DELETE ON comment_reactions
+FOR EACH ROW EXECUTE FUNCTION update_comment_reaction_counts();
@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS trg_posts_delete_reposts ON posts;
+DROP FUNCTION IF EXISTS delete_reposts();
+
+DROP INDEX IF EXISTS idx_posts_original_id;
+DROP INDEX IF EXISTS idx_posts_unique_repost;
+
+ALTER TABLE IF EXISTS posts
+DROP CONSTRAINT IF EXISTS posts_kind_check;
+
+ALTER TABLE IF EXISTS posts
+DROP COLUMN IF EXISTS original_id,
+DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,25 @@
+ALTER TABLE IF EXISTS posts
+ADD COLUMN IF NOT EXISTS kind VARCHAR(10) NOT NULL DEFAULT 'post',
+ADD COLUMN IF NOT EXISTS original_id BIGINT REFERENCES posts (id) ON DELETE SET NULL;
+
+ALTER TABLE IF EXISTS posts
+ADD CONSTRAINT posts_kind_check CHECK (kind IN ('post', 'repost', 'quote'));
+
+-- a user reposts a given post at most once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_unique_repost ON posts (user_id, original_id) WHERE kind = 'repost';
+
+CREATE INDEX IF NOT EXISTS idx_posts_original_id ON posts (original_id);
+
+-- a repost has no content of its own, so it goes away with the original;
+-- quote posts keep their content and lose the reference through ON DELETE SET NULL
+CREATE OR REPLACE FUNCTION delete_reposts() RETURNS TRIGGER AS $$
+BEGIN
+  DELETE FROM posts WHERE original_id = OLD.id AND kind = 'repost';
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_posts_delete_reposts ON posts;
+CREATE TRIGGER trg_posts_delete_reposts
+BEFORE DELETE ON posts
+FOR EACH ROW EXECUTE FUNCTION delete_reposts();
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS bookmark_collections (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT now(),
+
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS bookmarks (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  post_id BIGINT NOT NULL,
+  collection_id BIGINT,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT now(),
+
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+  -- deleting a collection keeps its bookmarks, unfiled
+  FOREIGN KEY (collection_id) REFERENCES bookmark_collections (id) ON DELETE SET NULL,
+  UNIQUE (user_id, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bookmarks_user_id_id ON bookmarks (user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_bookmarks_collection_id ON bookmarks (collection_id);
@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS trg_comments_reply_counts ON comments;
+DROP FUNCTION IF EXISTS update_reply_counts();
+
+DROP INDEX IF EXISTS idx_comments_post_id_created_at;
+DROP INDEX IF EXISTS idx_comments_parent_comment_id;
+
+ALTER TABLE IF EXISTS comments
+DROP COLUMN IF EXISTS deleted_at,
+DROP COLUMN IF EXISTS reply_count,
+DROP COLUMN IF EXISTS parent_comment_id;
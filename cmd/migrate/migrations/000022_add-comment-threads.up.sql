@@ -0,0 +1,29 @@
+ALTER TABLE IF EXISTS comments
+ADD COLUMN IF NOT EXISTS parent_comment_id BIGINT REFERENCES comments (id) ON DELETE CASCADE,
+ADD COLUMN IF NOT EXISTS reply_count BIGINT NOT NULL DEFAULT 0,
+-- a deleted comment with replies is kept as a tombstone so its subtree survives
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP(0) WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent_comment_id ON comments (parent_comment_id);
+CREATE INDEX IF NOT EXISTS idx_comments_post_id_created_at ON comments (post_id, created_at);
+
+CREATE OR REPLACE FUNCTION update_reply_counts() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    IF NEW.parent_comment_id IS NOT NULL THEN
+      UPDATE comments SET reply_count = reply_count + 1 WHERE id = NEW.parent_comment_id;
+    END IF;
+    RETURN NEW;
+  END IF;
+
+  IF OLD.parent_comment_id IS NOT NULL THEN
+    UPDATE comments SET reply_count = GREATEST(reply_count - 1, 0) WHERE id = OLD.parent_comment_id;
+  END IF;
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_comments_reply_counts ON comments;
+CREATE TRIGGER trg_comments_reply_counts
+AFTER INSERT OR DELETE ON comments
+FOR EACH ROW EXECUTE FUNCTION update_reply_counts();
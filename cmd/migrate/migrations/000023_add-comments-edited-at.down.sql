@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS comments
+DROP COLUMN IF EXISTS edited_at;
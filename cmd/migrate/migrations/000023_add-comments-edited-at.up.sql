@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS comments
+ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP(0) WITH TIME ZONE;
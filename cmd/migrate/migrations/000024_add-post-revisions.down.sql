@@ -0,0 +1,4 @@
+ALTER TABLE IF EXISTS posts
+DROP COLUMN IF EXISTS edited_at;
+
+DROP TABLE IF EXISTS post_revisions;
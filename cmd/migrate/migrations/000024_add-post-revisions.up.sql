@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS post_revisions (
+  post_id BIGINT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+  version BIGINT NOT NULL,
+  title TEXT NOT NULL,
+  content TEXT NOT NULL,
+  tags TEXT[] NOT NULL,
+  -- the edit that replaced this version
+  replaced_by BIGINT REFERENCES users (id) ON DELETE SET NULL,
+  replaced_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT now(),
+  PRIMARY KEY (post_id, version)
+);
+
+ALTER TABLE IF EXISTS posts
+ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP(0) WITH TIME ZONE;
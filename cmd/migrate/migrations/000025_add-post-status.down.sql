@@ -0,0 +1,27 @@
+CREATE OR REPLACE FUNCTION update_post_counters() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    UPDATE users SET posts_count = posts_count + 1 WHERE id = NEW.user_id;
+    RETURN NEW;
+  END IF;
+
+  UPDATE users SET posts_count = GREATEST(posts_count - 1, 0) WHERE id = OLD.user_id;
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_posts_counters ON posts;
+CREATE TRIGGER trg_posts_counters
+AFTER INSERT OR DELETE ON posts
+FOR EACH ROW EXECUTE FUNCTION update_post_counters();
+
+DROP INDEX IF EXISTS idx_posts_unpublished;
+DROP INDEX IF EXISTS idx_posts_publish_at;
+
+ALTER TABLE IF EXISTS posts
+DROP CONSTRAINT IF EXISTS posts_status_check,
+DROP COLUMN IF EXISTS published_at,
+DROP COLUMN IF EXISTS publish_at,
+DROP COLUMN IF EXISTS status;
+
+UPDATE users u SET posts_count = (SELECT COUNT(*) FROM posts p WHERE p.user_id = u.id);
@@ -0,0 +1,42 @@
+ALTER TABLE IF EXISTS posts
+ADD COLUMN IF NOT EXISTS status VARCHAR(10) NOT NULL DEFAULT 'published',
+ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP(0) WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS published_at TIMESTAMP(0) WITH TIME ZONE;
+
+ALTER TABLE IF EXISTS posts
+ADD CONSTRAINT posts_status_check CHECK (status IN ('draft', 'scheduled', 'published'));
+
+UPDATE posts SET published_at = created_at WHERE published_at IS NULL;
+
+-- the publisher picks the scheduled posts that are due
+CREATE INDEX IF NOT EXISTS idx_posts_publish_at ON posts (publish_at) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_posts_unpublished ON posts (user_id, created_at) WHERE status <> 'published';
+
+-- drafts and scheduled posts only count once published
+CREATE OR REPLACE FUNCTION update_post_counters() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    IF NEW.status = 'published' THEN
+      UPDATE users SET posts_count = posts_count + 1 WHERE id = NEW.user_id;
+    END IF;
+    RETURN NEW;
+  END IF;
+
+  IF TG_OP = 'UPDATE' THEN
+    IF OLD.status <> 'published' AND NEW.status = 'published' THEN
+      UPDATE users SET posts_count = posts_count + 1 WHERE id = NEW.user_id;
+    END IF;
+    RETURN NEW;
+  END IF;
+
+  IF OLD.status = 'published' THEN
+    UPDATE users SET posts_count = GREATEST(posts_count - 1, 0) WHERE id = OLD.user_id;
+  END IF;
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_posts_counters ON posts;
+CREATE TRIGGER trg_posts_counters
+AFTER INSERT OR DELETE OR UPDATE OF status ON posts
+FOR EACH ROW EXECUTE FUNCTION update_post_counters();
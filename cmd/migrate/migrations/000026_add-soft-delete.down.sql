@@ -0,0 +1,69 @@
+-- posts and comments still in the trash are gone for good
+DELETE FROM posts WHERE deleted_at IS NOT NULL;
+-- only tombstones with replies shown were kept before, their replies cascade
+DELETE FROM comments WHERE deleted_at IS NOT NULL AND reply_count = 0;
+UPDATE comments SET content = '' WHERE deleted_at IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION update_reply_counts() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    IF NEW.parent_comment_id IS NOT NULL THEN
+      UPDATE comments SET reply_count = reply_count + 1 WHERE id = NEW.parent_comment_id;
+    END IF;
+    RETURN NEW;
+  END IF;
+
+  IF OLD.parent_comment_id IS NOT NULL THEN
+    UPDATE comments SET reply_count = GREATEST(reply_count - 1, 0) WHERE id = OLD.parent_comment_id;
+  END IF;
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_comments_reply_counts ON comments;
+CREATE TRIGGER trg_comments_reply_counts
+AFTER INSERT OR DELETE ON comments
+FOR EACH ROW EXECUTE FUNCTION update_reply_counts();
+
+CREATE OR REPLACE FUNCTION update_post_counters() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    IF NEW.status = 'published' THEN
+      UPDATE users SET posts_count = posts_count + 1 WHERE id = NEW.user_id;
+    END IF;
+    RETURN NEW;
+  END IF;
+
+  IF TG_OP = 'UPDATE' THEN
+    IF OLD.status <> 'published' AND NEW.status = 'published' THEN
+      UPDATE users SET posts_count = posts_count + 1 WHERE id = NEW.user_id;
+    END IF;
+    RETURN NEW;
+  END IF;
+
+  IF OLD.status = 'published' THEN
+    UPDATE users SET posts_count = GREATEST(posts_count - 1, 0) WHERE id = OLD.user_id;
+  END IF;
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_posts_counters ON posts;
+CREATE TRIGGER trg_posts_counters
+AFTER INSERT OR DELETE OR UPDATE OF status ON posts
+FOR EACH ROW EXECUTE FUNCTION update_post_counters();
+
+DROP INDEX IF EXISTS idx_posts_unique_repost;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_unique_repost ON posts (user_id, original_id) WHERE kind = 'repost';
+
+DROP INDEX IF EXISTS idx_comments_deleted_at;
+DROP INDEX IF EXISTS idx_posts_deleted_at;
+
+ALTER TABLE IF EXISTS comments
+DROP COLUMN IF EXISTS deleted_by;
+
+ALTER TABLE IF EXISTS posts
+DROP COLUMN IF EXISTS deleted_by,
+DROP COLUMN IF EXISTS deleted_at;
+
+UPDATE users u SET posts_count = (SELECT COUNT(*) FROM posts p WHERE p.user_id = u.id AND p.status = 'published');
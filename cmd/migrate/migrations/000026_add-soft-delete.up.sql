@@ -0,0 +1,65 @@
+ALTER TABLE IF EXISTS posts
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP(0) WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS deleted_by BIGINT REFERENCES users (id) ON DELETE SET NULL;
+
+ALTER TABLE IF EXISTS comments
+ADD COLUMN IF NOT EXISTS deleted_by BIGINT REFERENCES users (id) ON DELETE SET NULL;
+
+-- the trash of a user and the purge job
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON comments (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- a repost in the trash no longer stands in the way of reposting again
+DROP INDEX IF EXISTS idx_posts_unique_repost;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_unique_repost ON posts (user_id, original_id) WHERE kind = 'repost' AND deleted_at IS NULL;
+
+-- posts in the trash do not count
+CREATE OR REPLACE FUNCTION update_post_counters() RETURNS TRIGGER AS $$
+DECLARE
+  was_live BOOLEAN := TG_OP <> 'INSERT' AND OLD.status = 'published' AND OLD.deleted_at IS NULL;
+  is_live BOOLEAN := TG_OP <> 'DELETE' AND NEW.status = 'published' AND NEW.deleted_at IS NULL;
+BEGIN
+  IF is_live AND NOT was_live THEN
+    UPDATE users SET posts_count = posts_count + 1 WHERE id = NEW.user_id;
+  ELSIF was_live AND NOT is_live THEN
+    UPDATE users SET posts_count = GREATEST(posts_count - 1, 0) WHERE id = OLD.user_id;
+  END IF;
+
+  IF TG_OP = 'DELETE' THEN
+    RETURN OLD;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_posts_counters ON posts;
+CREATE TRIGGER trg_posts_counters
+AFTER INSERT OR DELETE OR UPDATE OF status, deleted_at ON posts
+FOR EACH ROW EXECUTE FUNCTION update_post_counters();
+
+-- reply_count now counts the replies still shown: live ones, and deleted ones
+-- kept as tombstones because replies of their own are shown. Changes ripple up
+-- the thread as each parent's reply_count update fires the trigger again.
+CREATE OR REPLACE FUNCTION update_reply_counts() RETURNS TRIGGER AS $$
+DECLARE
+  was_shown BOOLEAN := TG_OP <> 'INSERT' AND (OLD.deleted_at IS NULL OR OLD.reply_count > 0);
+  is_shown BOOLEAN := TG_OP <> 'DELETE' AND (NEW.deleted_at IS NULL OR NEW.reply_count > 0);
+  parent_id BIGINT := CASE WHEN TG_OP = 'DELETE' THEN OLD.parent_comment_id ELSE NEW.parent_comment_id END;
+BEGIN
+  IF parent_id IS NOT NULL AND is_shown <> was_shown THEN
+    UPDATE comments
+    SET reply_count = GREATEST(reply_count + CASE WHEN is_shown THEN 1 ELSE -1 END, 0)
+    WHERE id = parent_id;
+  END IF;
+
+  IF TG_OP = 'DELETE' THEN
+    RETURN OLD;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_comments_reply_counts ON comments;
+CREATE TRIGGER trg_comments_reply_counts
+AFTER INSERT OR DELETE OR UPDATE OF deleted_at, reply_count ON comments
+FOR EACH ROW EXECUTE FUNCTION update_reply_counts();
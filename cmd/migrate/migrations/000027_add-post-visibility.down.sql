@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS post_mentions;
+
+ALTER TABLE IF EXISTS posts
+DROP CONSTRAINT IF EXISTS posts_visibility_check,
+DROP COLUMN IF EXISTS visibility;
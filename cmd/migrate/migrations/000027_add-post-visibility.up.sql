@@ -0,0 +1,14 @@
+ALTER TABLE IF EXISTS posts
+ADD COLUMN IF NOT EXISTS visibility VARCHAR(10) NOT NULL DEFAULT 'public';
+
+ALTER TABLE IF EXISTS posts
+ADD CONSTRAINT posts_visibility_check CHECK (visibility IN ('public', 'followers', 'mentioned', 'private'));
+
+-- the users a post mentions, who can see it when it is mentioned-only
+CREATE TABLE IF NOT EXISTS post_mentions (
+  post_id BIGINT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  PRIMARY KEY (post_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_mentions_user_id ON post_mentions (user_id);
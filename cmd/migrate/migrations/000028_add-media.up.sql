@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS media (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  -- unset until the upload is attached, and again once its post is purged
+  post_id BIGINT REFERENCES posts (id) ON DELETE SET NULL,
+  position INT NOT NULL DEFAULT 0,
+  kind VARCHAR(10) NOT NULL CHECK (kind IN ('image', 'video')),
+  content_type VARCHAR(50) NOT NULL,
+  storage_key TEXT NOT NULL UNIQUE,
+  url TEXT NOT NULL,
+  size BIGINT NOT NULL,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_post_id ON media (post_id, position);
+-- the cleanup job looks for uploads never attached
+CREATE INDEX IF NOT EXISTS idx_media_orphans ON media (created_at) WHERE post_id IS NULL;
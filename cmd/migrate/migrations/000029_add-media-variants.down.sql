@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_media_pending;
+
+ALTER TABLE media
+DROP COLUMN IF EXISTS processing_attempts,
+DROP COLUMN IF EXISTS variant_keys,
+DROP COLUMN IF EXISTS variants,
+DROP COLUMN IF EXISTS blurhash,
+DROP COLUMN IF EXISTS height,
+DROP COLUMN IF EXISTS width,
+DROP COLUMN IF EXISTS status;
@@ -0,0 +1,15 @@
+-- images are processed in the background into variants, videos are served as uploaded
+ALTER TABLE media
+ADD COLUMN IF NOT EXISTS status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+ADD COLUMN IF NOT EXISTS width INT,
+ADD COLUMN IF NOT EXISTS height INT,
+ADD COLUMN IF NOT EXISTS blurhash VARCHAR(100),
+ADD COLUMN IF NOT EXISTS variants JSONB NOT NULL DEFAULT '{}',
+-- storage keys of the variants, to delete them along with the upload
+ADD COLUMN IF NOT EXISTS variant_keys TEXT[] NOT NULL DEFAULT '{}',
+-- runs that failed to process the image, it is marked failed past a limit
+ADD COLUMN IF NOT EXISTS processing_attempts INT NOT NULL DEFAULT 0;
+
+UPDATE media SET status = 'ready' WHERE kind = 'video';
+
+CREATE INDEX IF NOT EXISTS idx_media_pending ON media (created_at) WHERE status = 'pending';
@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS polls (
+  id BIGSERIAL PRIMARY KEY,
+  post_id BIGINT NOT NULL UNIQUE REFERENCES posts (id) ON DELETE CASCADE,
+  multiple BOOLEAN NOT NULL DEFAULT FALSE,
+  -- tallies are only shown to voters and the author until the poll closes
+  hide_results BOOLEAN NOT NULL DEFAULT FALSE,
+  closes_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+  -- set by the closing job once closes_at has passed
+  closed_at TIMESTAMP(0) WITH TIME ZONE,
+  voters_count BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_polls_open ON polls (closes_at) WHERE closed_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS poll_options (
+  id BIGSERIAL PRIMARY KEY,
+  poll_id BIGINT NOT NULL REFERENCES polls (id) ON DELETE CASCADE,
+  position INT NOT NULL,
+  text VARCHAR(100) NOT NULL,
+  votes_count BIGINT NOT NULL DEFAULT 0,
+  UNIQUE (poll_id, position)
+);
+
+-- one row per user and poll: a user votes once, for one or several options
+CREATE TABLE IF NOT EXISTS poll_voters (
+  poll_id BIGINT NOT NULL REFERENCES polls (id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT now(),
+  PRIMARY KEY (poll_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+  poll_id BIGINT NOT NULL,
+  user_id BIGINT NOT NULL,
+  option_id BIGINT NOT NULL REFERENCES poll_options (id) ON DELETE CASCADE,
+  PRIMARY KEY (poll_id, user_id, option_id),
+  FOREIGN KEY (poll_id, user_id) REFERENCES poll_voters (poll_id, user_id) ON DELETE CASCADE
+);
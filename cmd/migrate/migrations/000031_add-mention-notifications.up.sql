@@ -0,0 +1,25 @@
+-- the users a comment mentions
+CREATE TABLE IF NOT EXISTS comment_mentions (
+  comment_id BIGINT NOT NULL REFERENCES comments (id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_mentions_user_id ON comment_mentions (user_id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+  id BIGSERIAL PRIMARY KEY,
+  -- who is notified, and who caused it
+  user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  actor_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  kind VARCHAR(20) NOT NULL CHECK (kind IN ('mention')),
+  post_id BIGINT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+  comment_id BIGINT REFERENCES comments (id) ON DELETE CASCADE,
+  read_at TIMESTAMP(0) WITH TIME ZONE,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id, id DESC);
+-- a user is notified once per post or comment mentioning them, however often it is edited
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_mention ON notifications (user_id, post_id, COALESCE(comment_id, 0))
+WHERE kind = 'mention';
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_posts_tag_counts ON posts;
+DROP FUNCTION IF EXISTS update_tag_counts();
+DROP TABLE IF EXISTS tags;
+DROP FUNCTION IF EXISTS normalize_tag(TEXT);
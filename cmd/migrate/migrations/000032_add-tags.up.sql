@@ -0,0 +1,95 @@
+-- the one definition of a tag, the same as store.NormalizeTag: compatibility
+-- forms and case are folded and a leading # is dropped. It only agrees with
+-- the Go side on a UTF-8 database whose LC_CTYPE is C.UTF-8 or an ICU locale,
+-- lower() folds by the ctype and leaves non-ASCII letters alone under C. It is
+-- IMMUTABLE on that assumption, changing the ctype means rebuilding the tags.
+CREATE OR REPLACE FUNCTION normalize_tag(tag TEXT) RETURNS TEXT AS $$
+  SELECT lower(normalize(regexp_replace(tag, '^\s*#?|\s+$', '', 'g'), NFKC))
+$$ LANGUAGE sql IMMUTABLE;
+
+-- tags were stored as written until now, bring them to the normalized form,
+-- without duplicates and in their order
+UPDATE posts p
+SET tags = n.tags
+FROM (
+  SELECT id, ARRAY(
+    SELECT normalize_tag(t)
+    FROM unnest(tags) WITH ORDINALITY AS u(t, position)
+    WHERE normalize_tag(t) <> ''
+    GROUP BY normalize_tag(t)
+    ORDER BY MIN(position)
+  ) AS tags
+  FROM posts
+) n
+WHERE p.id = n.id AND p.tags IS DISTINCT FROM n.tags;
+
+DELETE FROM user_tag_follows f
+USING user_tag_follows g
+WHERE f.user_id = g.user_id AND f.tag > g.tag AND normalize_tag(f.tag) = normalize_tag(g.tag);
+
+UPDATE user_tag_follows SET tag = normalize_tag(tag) WHERE tag <> normalize_tag(tag);
+
+DELETE FROM mute_filters f
+USING mute_filters g
+WHERE f.user_id = g.user_id AND f.kind = 'tag' AND g.kind = 'tag' AND f.id > g.id
+  AND normalize_tag(f.value) = normalize_tag(g.value);
+
+-- the pattern is rebuilt as store.muteFilterPattern does for tags
+UPDATE mute_filters
+SET value = normalize_tag(value),
+  pattern = '(^|[^[:alnum:]_])#' || regexp_replace(normalize_tag(value), '([.+*?()|\[\]{}^$\\])', '\\\1', 'g') || '($|[^[:alnum:]_])'
+WHERE kind = 'tag' AND value <> normalize_tag(value);
+
+-- the tags of live posts, counted for autocomplete
+CREATE TABLE IF NOT EXISTS tags (
+  tag TEXT PRIMARY KEY,
+  usage_count BIGINT NOT NULL DEFAULT 0,
+  last_used_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- prefix lookups
+CREATE INDEX IF NOT EXISTS idx_tags_prefix ON tags (tag text_pattern_ops) WHERE usage_count > 0;
+
+-- a tag counts once per live post carrying it, whatever its form. Tags are
+-- locked in order so concurrent posts sharing tags do not deadlock.
+CREATE OR REPLACE FUNCTION update_tag_counts() RETURNS TRIGGER AS $$
+DECLARE
+  old_tags TEXT[] := '{}';
+  new_tags TEXT[] := '{}';
+BEGIN
+  IF TG_OP <> 'INSERT' AND OLD.status = 'published' AND OLD.deleted_at IS NULL THEN
+    old_tags := ARRAY(SELECT DISTINCT normalize_tag(t) FROM unnest(OLD.tags) AS t ORDER BY 1);
+  END IF;
+  IF TG_OP <> 'DELETE' AND NEW.status = 'published' AND NEW.deleted_at IS NULL THEN
+    new_tags := ARRAY(SELECT DISTINCT normalize_tag(t) FROM unnest(NEW.tags) AS t ORDER BY 1);
+  END IF;
+
+  UPDATE tags
+  SET usage_count = GREATEST(usage_count - 1, 0)
+  WHERE tag = ANY(old_tags) AND NOT tag = ANY(new_tags);
+
+  INSERT INTO tags (tag, usage_count)
+  SELECT t, 1
+  FROM unnest(new_tags) AS t
+  WHERE NOT t = ANY(old_tags)
+  ORDER BY t
+  ON CONFLICT (tag) DO UPDATE SET usage_count = tags.usage_count + 1, last_used_at = now();
+
+  IF TG_OP = 'DELETE' THEN
+    RETURN OLD;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_posts_tag_counts ON posts;
+CREATE TRIGGER trg_posts_tag_counts
+AFTER INSERT OR DELETE OR UPDATE OF tags, status, deleted_at ON posts
+FOR EACH ROW EXECUTE FUNCTION update_tag_counts();
+
+INSERT INTO tags (tag, usage_count, last_used_at)
+SELECT t, COUNT(DISTINCT p.id), COALESCE(MAX(p.published_at), now())
+FROM posts p, unnest(p.tags) AS t
+WHERE p.status = 'published' AND p.deleted_at IS NULL
+GROUP BY t
+ON CONFLICT (tag) DO NOTHING;
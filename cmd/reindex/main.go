@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/tenteedee/gopher-social/internal/db"
+	"github.com/tenteedee/gopher-social/internal/env"
+	"github.com/tenteedee/gopher-social/internal/search"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+func main() {
+	checkOnly := flag.Bool("check", false, "only compare index counts with the database, do not reindex")
+	flag.Parse()
+
+	env.Init()
+
+	addr := fmt.Sprintf("postgres://%v:%v@%v:%v/%v?sslmode=disable", env.DbUser, env.DbPassword, env.DbHost, env.DbPort, env.DbName)
+	conn, err := db.New(addr, 3, 3, "15m")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	storage := store.NewStorage(conn)
+
+	indexer, err := search.New(env.SearchBackend, env.SearchIndexPath, conn, storage)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer indexer.Close()
+
+	if _, ok := indexer.(search.Mirror); !ok {
+		log.Printf("search backend %s searches generated columns that cannot drift from their rows, there is no index to rebuild or check", env.SearchBackend)
+		return
+	}
+
+	ctx := context.Background()
+
+	if !*checkOnly {
+		indexed, removed, err := search.Reindex(ctx, conn, indexer)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, docType := range search.DocumentTypes {
+			log.Printf("indexed %d %s, removed %d", indexed[docType], docType, removed[docType])
+		}
+	}
+
+	mismatches, err := search.CheckConsistency(ctx, conn, indexer)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(mismatches) > 0 {
+		for _, m := range mismatches {
+			log.Printf("inconsistent index: %s", m)
+		}
+		log.Fatalf("search index (%s) is out of sync, run without -check to rebuild", env.SearchBackend)
+	}
+
+	log.Printf("search index (%s) is consistent with the database", env.SearchBackend)
+}
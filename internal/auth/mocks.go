@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testSecret = "test"
+	testAud    = "test-aud"
+)
+
+// TestAuthenticator signs tokens with a fixed secret, for handler tests.
+type TestAuthenticator struct{}
+
+// TestClaims are valid claims for the given user.
+func TestClaims(userID int64) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": userID,
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"iat": time.Now().Unix(),
+		"nbf": time.Now().Unix(),
+		"iss": testAud,
+		"aud": testAud,
+	}
+}
+
+func (a *TestAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString([]byte(testSecret))
+}
+
+func (a *TestAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
+	return jwt.Parse(token, func(t *jwt.Token) (any, error) {
+		return []byte(testSecret), nil
+	},
+		jwt.WithExpirationRequired(),
+		jwt.WithAudience(testAud),
+		jwt.WithIssuer(testAud),
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
+	)
+}
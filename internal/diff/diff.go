@@ -0,0 +1,94 @@
+package diff
+
+import "strings"
+
+const (
+	OpEqual  = "equal"
+	OpInsert = "insert"
+	OpDelete = "delete"
+)
+
+// Line is a line of the diff, Op tells whether it is in both texts, only in
+// the new one or only in the old one.
+type Line struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Lines diffs two texts line by line, keeping the longest common subsequence
+// of lines and reporting deletions before insertions at each change.
+func Lines(from, to string) []Line {
+	a := split(from)
+	b := split(to)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := []Line{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{Op: OpEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Op: OpDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: OpInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, Line{Op: OpDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, Line{Op: OpInsert, Text: b[j]})
+	}
+
+	return lines
+}
+
+// Sets compares two lists as sets, returning what was added and removed.
+func Sets(from, to []string) (added, removed []string) {
+	added, removed = []string{}, []string{}
+
+	seen := make(map[string]bool, len(from))
+	for _, s := range from {
+		seen[s] = true
+	}
+	kept := make(map[string]bool, len(to))
+	for _, s := range to {
+		kept[s] = true
+		if !seen[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range from {
+		if !kept[s] {
+			removed = append(removed, s)
+		}
+	}
+
+	return added, removed
+}
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
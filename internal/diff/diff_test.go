@@ -0,0 +1,70 @@
+package diff
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := map[string]struct {
+		from string
+		to   string
+		want []Line
+	}{
+		"equal": {
+			from: "a\nb",
+			to:   "a\nb",
+			want: []Line{{OpEqual, "a"}, {OpEqual, "b"}},
+		},
+		"changed line": {
+			from: "a\nb\nc",
+			to:   "a\nB\nc",
+			want: []Line{{OpEqual, "a"}, {OpDelete, "b"}, {OpInsert, "B"}, {OpEqual, "c"}},
+		},
+		"appended": {
+			from: "a",
+			to:   "a\nb",
+			want: []Line{{OpEqual, "a"}, {OpInsert, "b"}},
+		},
+		"from empty": {
+			from: "",
+			to:   "a",
+			want: []Line{{OpInsert, "a"}},
+		},
+		"to empty": {
+			from: "a",
+			to:   "",
+			want: []Line{{OpDelete, "a"}},
+		},
+		"both empty": {
+			from: "",
+			to:   "",
+			want: []Line{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := Lines(tt.from, tt.to)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) || got == nil {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSets(t *testing.T) {
+	added, removed := Sets([]string{"go", "web"}, []string{"web", "api"})
+
+	if fmt.Sprint(added) != "[api]" {
+		t.Errorf("expected [api] to be added, got %v", added)
+	}
+	if fmt.Sprint(removed) != "[go]" {
+		t.Errorf("expected [go] to be removed, got %v", removed)
+	}
+
+	added, removed = Sets(nil, nil)
+	if added == nil || removed == nil {
+		t.Error("expected empty lists, not nil ones")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -41,3 +42,13 @@ func getEnvAsDuration(key string, defaultValue string) time.Duration {
 	}
 	return duration
 }
+
+func getEnvAsList(key string, defaultValue string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnvWithDefault(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -36,6 +36,32 @@ var (
 	RateLimiterRequestCount int
 	RateLimiterTimeFrame    time.Duration
 	RateLimiterEnabled      bool
+	SearchBackend           string
+	SearchIndexPath         string
+	JobsEnabled             bool
+	TrendsInterval          time.Duration
+	TrendsRetention         time.Duration
+	SuggestionsInterval     time.Duration
+	SuggestionsMaxAge       time.Duration
+	SuggestionsBatchSize    int
+	PublisherInterval       time.Duration
+	PurgeInterval           time.Duration
+	MediaCleanupInterval    time.Duration
+	MediaProcessingInterval time.Duration
+	PollsInterval           time.Duration
+	TrashRetention          time.Duration
+	ReactionTypes           []string
+	MediaBackend            string
+	MediaLocalDir           string
+	MediaPublicURL          string
+	MediaMaxImageSize       int
+	MediaMaxVideoSize       int
+	MediaOrphanTTL          time.Duration
+	S3Endpoint              string
+	S3Region                string
+	S3Bucket                string
+	S3AccessKey             string
+	S3SecretKey             string
 )
 
 func Init() {
@@ -79,4 +105,36 @@ func Init() {
 	RateLimiterRequestCount = getEnvAsInt("RATE_LIMITER_REQUEST_COUNT", 100)
 	RateLimiterTimeFrame = getEnvAsDuration("RATE_LIMITER_WINDOW", "5s")
 	RateLimiterEnabled = getEnvAsBool("RATE_LIMITER_ENABLED", false)
+
+	SearchBackend = getEnvWithDefault("SEARCH_BACKEND", "postgres")
+	SearchIndexPath = getEnvWithDefault("SEARCH_INDEX_PATH", "data/search.bleve")
+
+	JobsEnabled = getEnvAsBool("JOBS_ENABLED", true)
+	TrendsInterval = getEnvAsDuration("TRENDS_INTERVAL", "10m")
+	TrendsRetention = getEnvAsDuration("TRENDS_RETENTION", "168h")
+	SuggestionsInterval = getEnvAsDuration("SUGGESTIONS_INTERVAL", "5m")
+	SuggestionsMaxAge = getEnvAsDuration("SUGGESTIONS_MAX_AGE", "24h")
+	SuggestionsBatchSize = getEnvAsInt("SUGGESTIONS_BATCH_SIZE", 500)
+	PublisherInterval = getEnvAsDuration("PUBLISHER_INTERVAL", "1m")
+	PurgeInterval = getEnvAsDuration("PURGE_INTERVAL", "1h")
+	MediaCleanupInterval = getEnvAsDuration("MEDIA_CLEANUP_INTERVAL", "1h")
+	MediaProcessingInterval = getEnvAsDuration("MEDIA_PROCESSING_INTERVAL", "1m")
+	PollsInterval = getEnvAsDuration("POLLS_INTERVAL", "1m")
+
+	TrashRetention = getEnvAsDuration("TRASH_RETENTION", "720h")
+
+	ReactionTypes = getEnvAsList("REACTION_TYPES", "❤️,😂,😮,😢,🎉")
+
+	MediaBackend = getEnvWithDefault("MEDIA_BACKEND", "local")
+	MediaLocalDir = getEnvWithDefault("MEDIA_LOCAL_DIR", "data/media")
+	MediaPublicURL = getEnvWithDefault("MEDIA_PUBLIC_URL", "")
+	MediaMaxImageSize = getEnvAsInt("MEDIA_MAX_IMAGE_SIZE", 10<<20)
+	MediaMaxVideoSize = getEnvAsInt("MEDIA_MAX_VIDEO_SIZE", 50<<20)
+	MediaOrphanTTL = getEnvAsDuration("MEDIA_ORPHAN_TTL", "24h")
+
+	S3Endpoint = getEnvWithDefault("S3_ENDPOINT", "")
+	S3Region = getEnvWithDefault("S3_REGION", "us-east-1")
+	S3Bucket = getEnvWithDefault("S3_BUCKET", "")
+	S3AccessKey = getEnvWithDefault("S3_ACCESS_KEY", "")
+	S3SecretKey = getEnvWithDefault("S3_SECRET_KEY", "")
 }
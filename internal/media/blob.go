@@ -0,0 +1,48 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrNotFound       = errors.New("blob not found")
+	ErrUnknownBackend = errors.New("unknown blob backend")
+)
+
+// BlobStore keeps uploaded files under a key. URL is where clients fetch the
+// file from, it is recorded with the media so it must not change over time.
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// Config selects and configures a BlobStore. Only the fields of the chosen
+// backend are used.
+type Config struct {
+	Backend string
+	// LocalDir is where the local backend writes, served under PublicURL.
+	LocalDir string
+	// PublicURL is the base URL of the files, it defaults to the bucket URL for S3.
+	PublicURL   string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+func NewBlobStore(cfg Config) (BlobStore, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return NewLocalStore(cfg.LocalDir, cfg.PublicURL)
+	case "s3":
+		return NewS3Store(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, cfg.PublicURL), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, cfg.Backend)
+	}
+}
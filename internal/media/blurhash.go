@@ -0,0 +1,115 @@
+package media
+
+import (
+	"image"
+	"math"
+	"strings"
+)
+
+// blurHashSize is the size the image is shrunk to before hashing, the hash
+// only keeps a handful of low frequencies so more pixels would not change it.
+const blurHashSize = 64
+
+const base83 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// srgbToLinear maps the 8 bit sRGB values to linear light.
+var srgbToLinear = func() (table [256]float64) {
+	for i := range table {
+		v := float64(i) / 255
+		if v <= 0.04045 {
+			table[i] = v / 12.92
+		} else {
+			table[i] = math.Pow((v+0.055)/1.055, 2.4)
+		}
+	}
+	return table
+}()
+
+func linearToSRGB(v float64) int {
+	v = math.Max(0, math.Min(1, v))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+// blurHash encodes the image as a BlurHash (https://blurha.sh), a short string
+// clients decode into a blurred placeholder while the image loads. The image
+// is described by xComponents x yComponents cosine components, 1 to 9 each.
+func blurHash(img *image.RGBA, xComponents, yComponents int) string {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				basisY := math.Cos(math.Pi * float64(j) * float64(y) / float64(height))
+				offset := img.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+				for x := 0; x < width; x++ {
+					basis := normalisation * basisY * math.Cos(math.Pi*float64(i)*float64(x)/float64(width))
+					for c := range factor {
+						factor[c] += basis * srgbToLinear[img.Pix[offset+c]]
+					}
+					offset += 4
+				}
+			}
+
+			scale := 1 / float64(width*height)
+			for c := range factor {
+				factor[c] *= scale
+			}
+			factors = append(factors, factor)
+		}
+	}
+
+	var hash strings.Builder
+	encode83(&hash, (xComponents-1)+(yComponents-1)*9, 1)
+
+	dc, ac := factors[0], factors[1:]
+	maximum := 1.0
+	if len(ac) > 0 {
+		actual := 0.0
+		for _, factor := range ac {
+			for _, v := range factor {
+				actual = math.Max(actual, math.Abs(v))
+			}
+		}
+		quantised := int(math.Max(0, math.Min(82, math.Floor(actual*166-0.5))))
+		maximum = float64(quantised+1) / 166
+		encode83(&hash, quantised, 1)
+	} else {
+		encode83(&hash, 0, 1)
+	}
+
+	encode83(&hash, linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4)
+
+	for _, factor := range ac {
+		var value int
+		for _, v := range factor {
+			quantised := math.Floor(signPow(v/maximum, 0.5)*9 + 9.5)
+			value = value*19 + int(math.Max(0, math.Min(18, quantised)))
+		}
+		encode83(&hash, value, 2)
+	}
+
+	return hash.String()
+}
+
+func signPow(v, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(v), exp), v)
+}
+
+// encode83 writes value as length base 83 digits.
+func encode83(hash *strings.Builder, value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		digit := value / int(math.Pow(83, float64(i))) % 83
+		hash.WriteByte(base83[digit])
+	}
+}
@@ -0,0 +1,80 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps blobs as files under a directory, for development and
+// single node deployments. The API serves the directory itself.
+type LocalStore struct {
+	dir       string
+	publicURL string
+}
+
+func NewLocalStore(dir, publicURL string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &LocalStore{dir: dir, publicURL: strings.TrimSuffix(publicURL, "/")}, nil
+}
+
+// Dir is the directory the blobs are written to.
+func (s *LocalStore) Dir() string {
+	return s.dir
+}
+
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+// Put writes to a temporary file first so a failed upload never leaves a
+// partial file under the key.
+func (s *LocalStore) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := os.Open(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalStore) URL(key string) string {
+	return s.publicURL + "/" + key
+}
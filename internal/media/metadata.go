@@ -0,0 +1,142 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var ErrMalformedImage = errors.New("malformed image")
+
+// StripMetadata removes the EXIF and XMP metadata of an image, which may carry
+// the location it was taken at, without re-encoding it. Formats without such
+// metadata are returned as they are.
+func StripMetadata(contentType string, data []byte) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/webp":
+		return stripWebP(data)
+	default:
+		return data, nil
+	}
+}
+
+// stripJPEG drops the APP1 segments, which hold EXIF and XMP, up to the start
+// of the scan. The compressed image data after it is copied as is.
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, ErrMalformedImage
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return nil, ErrMalformedImage
+		}
+		marker := data[i+1]
+		// fill bytes before a marker
+		if marker == 0xFF {
+			i++
+			continue
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, ErrMalformedImage
+		}
+
+		// start of scan: the rest is image data
+		if marker == 0xDA {
+			out.Write(data[i:])
+			return out.Bytes(), nil
+		}
+		if marker != 0xE1 {
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+
+	return nil, ErrMalformedImage
+}
+
+// stripPNG drops the eXIf chunk and the text chunks, where XMP lives.
+func stripPNG(data []byte) ([]byte, error) {
+	signature := []byte("\x89PNG\r\n\x1a\n")
+	if !bytes.HasPrefix(data, signature) {
+		return nil, ErrMalformedImage
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(signature)
+
+	i := len(signature)
+	for i+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		chunkType := string(data[i+4 : i+8])
+		// length, type, data and CRC
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, ErrMalformedImage
+		}
+
+		switch chunkType {
+		case "eXIf", "tEXt", "zTXt", "iTXt":
+		default:
+			out.Write(data[i:end])
+		}
+		i = end
+
+		if chunkType == "IEND" {
+			return out.Bytes(), nil
+		}
+	}
+
+	return nil, ErrMalformedImage
+}
+
+// stripWebP drops the EXIF and XMP chunks of an extended WebP and clears
+// their flags in the VP8X header. Simple WebP files carry no metadata.
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrMalformedImage
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+
+	i := 12
+	for i+8 <= len(data) {
+		chunkType := string(data[i : i+4])
+		length := int(binary.LittleEndian.Uint32(data[i+4:]))
+		// chunks are padded to an even size
+		end := i + 8 + length + length%2
+		if end > len(data) {
+			return nil, ErrMalformedImage
+		}
+
+		switch chunkType {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[i:end]...)
+			if len(chunk) > 8 {
+				// bit 3 is EXIF, bit 2 is XMP
+				chunk[8] &^= 0x08 | 0x04
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+
+	stripped := out.Bytes()
+	binary.LittleEndian.PutUint32(stripped[4:], uint32(len(stripped)-8))
+	return stripped, nil
+}
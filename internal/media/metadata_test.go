@@ -0,0 +1,42 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+func TestStripMetadata(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 4, 4)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// an APP1 segment right after SOI, as cameras write it
+	payload := []byte("Exif\x00\x00GPS 48.8584 N 2.2945 E")
+	segment := append([]byte{0xFF, 0xE1, 0x00, byte(len(payload) + 2)}, payload...)
+	withExif := append(append(append([]byte{}, encoded.Bytes()[:2]...), segment...), encoded.Bytes()[2:]...)
+
+	t.Run("should drop the EXIF segment of a JPEG", func(t *testing.T) {
+		stripped, err := StripMetadata("image/jpeg", withExif)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(stripped, []byte("Exif")) {
+			t.Error("expected the EXIF data to be gone")
+		}
+		if !bytes.Equal(stripped, encoded.Bytes()) {
+			t.Error("expected the rest of the image to be kept as is")
+		}
+		if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+			t.Errorf("expected a valid JPEG, got %v", err)
+		}
+	})
+
+	t.Run("should reject a truncated JPEG", func(t *testing.T) {
+		if _, err := StripMetadata("image/jpeg", withExif[:10]); err != ErrMalformedImage {
+			t.Errorf("expected ErrMalformedImage, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	// decoders for image.Decode
+	_ "image/gif"
+)
+
+// MaxPixels bounds the images the pipeline decodes, a small file can
+// declare huge dimensions and exhaust memory once decoded.
+const MaxPixels = 50_000_000
+
+// jpegQuality is a good trade-off between size and visible artifacts for photos.
+const jpegQuality = 82
+
+var ErrImageTooLarge = errors.New("image dimensions too large")
+
+// VariantSpec is a size derived from every image.
+type VariantSpec struct {
+	Name string
+	// Size is the largest width or height of the variant.
+	Size int
+	// Square crops the middle of the image before scaling.
+	Square bool
+}
+
+// Variants are the sizes generated for every uploaded image.
+var Variants = []VariantSpec{
+	{Name: "thumbnail", Size: 320, Square: true},
+	{Name: "feed", Size: 1080},
+	{Name: "full", Size: 2048},
+}
+
+// Variant is an encoded image variant.
+type Variant struct {
+	Name        string
+	Width       int
+	Height      int
+	ContentType string
+	Extension   string
+	Data        []byte
+}
+
+// ProcessedImage holds what the pipeline derives from an uploaded image.
+type ProcessedImage struct {
+	Width    int
+	Height   int
+	BlurHash string
+	Variants []Variant
+}
+
+// ProcessImage decodes an image and derives its Variants, its dimensions and
+// a BlurHash placeholder. Opaque images are re-encoded as JPEG, which is far
+// smaller than PNG or GIF for photos, and images with transparency as PNG.
+// Animated GIFs are reduced to their first frame. Formats the standard
+// library cannot decode, such as WebP, fail with ErrUnsupportedType.
+func ProcessImage(data []byte) (*ProcessedImage, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, ErrUnsupportedType
+		}
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	src := toRGBA(decoded)
+
+	processed := &ProcessedImage{
+		Width:  src.Rect.Dx(),
+		Height: src.Rect.Dy(),
+	}
+
+	width, height := fit(processed.Width, processed.Height, blurHashSize)
+	processed.BlurHash = blurHash(resize(src, width, height), 4, 3)
+
+	for _, spec := range Variants {
+		variant, err := encodeVariant(src, spec)
+		if err != nil {
+			return nil, err
+		}
+		processed.Variants = append(processed.Variants, variant)
+	}
+
+	return processed, nil
+}
+
+func encodeVariant(src *image.RGBA, spec VariantSpec) (Variant, error) {
+	if spec.Square {
+		src = centerSquare(src)
+	}
+	width, height := fit(src.Rect.Dx(), src.Rect.Dy(), spec.Size)
+	scaled := resize(src, width, height)
+
+	variant := Variant{Name: spec.Name, Width: width, Height: height}
+
+	var encoded bytes.Buffer
+	if scaled.Opaque() {
+		variant.ContentType, variant.Extension = "image/jpeg", ".jpg"
+		if err := jpeg.Encode(&encoded, scaled, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return Variant{}, err
+		}
+	} else {
+		variant.ContentType, variant.Extension = "image/png", ".png"
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := encoder.Encode(&encoded, scaled); err != nil {
+			return Variant{}, err
+		}
+	}
+	variant.Data = encoded.Bytes()
+
+	return variant, nil
+}
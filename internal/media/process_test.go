@@ -0,0 +1,117 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestProcessImage(t *testing.T) {
+	encode := func(t *testing.T, img image.Image) []byte {
+		var encoded bytes.Buffer
+		if err := png.Encode(&encoded, img); err != nil {
+			t.Fatal(err)
+		}
+		return encoded.Bytes()
+	}
+
+	t.Run("should scale the variants down to their size", func(t *testing.T) {
+		src := image.NewNRGBA(image.Rect(0, 0, 2400, 1200))
+		for i := 3; i < len(src.Pix); i += 4 {
+			src.Pix[i] = 0xFF
+		}
+
+		processed, err := ProcessImage(encode(t, src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if processed.Width != 2400 || processed.Height != 1200 {
+			t.Errorf("expected 2400x1200, got %dx%d", processed.Width, processed.Height)
+		}
+
+		expected := map[string]image.Point{
+			"thumbnail": {320, 320},
+			"feed":      {1080, 540},
+			"full":      {2048, 1024},
+		}
+		for _, variant := range processed.Variants {
+			if size := (image.Point{variant.Width, variant.Height}); size != expected[variant.Name] {
+				t.Errorf("expected %s to be %v, got %v", variant.Name, expected[variant.Name], size)
+			}
+			if variant.ContentType != "image/jpeg" {
+				t.Errorf("expected %s of an opaque image to be a JPEG, got %s", variant.Name, variant.ContentType)
+			}
+			cfg, err := jpeg.DecodeConfig(bytes.NewReader(variant.Data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Width != variant.Width || cfg.Height != variant.Height {
+				t.Errorf("expected %s to be encoded at %dx%d, got %dx%d", variant.Name, variant.Width, variant.Height, cfg.Width, cfg.Height)
+			}
+		}
+	})
+
+	t.Run("should not scale up and keep transparency", func(t *testing.T) {
+		processed, err := ProcessImage(encode(t, image.NewNRGBA(image.Rect(0, 0, 200, 100))))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, variant := range processed.Variants {
+			if variant.ContentType != "image/png" {
+				t.Errorf("expected %s of a transparent image to be a PNG, got %s", variant.Name, variant.ContentType)
+			}
+		}
+		if thumbnail := processed.Variants[0]; thumbnail.Width != 100 || thumbnail.Height != 100 {
+			t.Errorf("expected a 100x100 thumbnail, got %dx%d", thumbnail.Width, thumbnail.Height)
+		}
+		if full := processed.Variants[2]; full.Width != 200 || full.Height != 100 {
+			t.Errorf("expected a 200x100 full variant, got %dx%d", full.Width, full.Height)
+		}
+	})
+
+	t.Run("should reject formats it cannot decode", func(t *testing.T) {
+		if _, err := ProcessImage([]byte("RIFF\x00\x00\x00\x00WEBPVP8 ")); err != ErrUnsupportedType {
+			t.Errorf("expected ErrUnsupportedType, got %v", err)
+		}
+	})
+}
+
+// TestBlurHash checks the encoding against hashes computed independently
+// with the reference algorithm.
+func TestBlurHash(t *testing.T) {
+	tests := []struct {
+		name     string
+		pixel    func(x, y int) color.RGBA
+		expected string
+	}{
+		{
+			name:     "plain red",
+			pixel:    func(x, y int) color.RGBA { return color.RGBA{0xFF, 0, 0, 0xFF} },
+			expected: "L9TI:j|cfQ|c|co1fQo1fQfQfQfQ",
+		},
+		{
+			name:     "horizontal gradient",
+			pixel:    func(x, y int) color.RGBA { return color.RGBA{uint8(x * 8), 0, 0, 0xFF} },
+			expected: "LxG{_V2EwxWpo1WpjtfQfQfQfQfQ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+			for x := 0; x < 32; x++ {
+				for y := 0; y < 32; y++ {
+					img.SetRGBA(x, y, tt.pixel(x, y))
+				}
+			}
+
+			if hash := blurHash(img, 4, 3); hash != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, hash)
+			}
+		})
+	}
+}
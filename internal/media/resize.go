@@ -0,0 +1,85 @@
+package media
+
+import (
+	"image"
+	"image/draw"
+)
+
+// toRGBA copies any decoded image into premultiplied RGBA, the layout resize
+// and blurHash work on.
+func toRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Rect, src, bounds.Min, draw.Src)
+	return dst
+}
+
+// fit returns the size of a width x height image scaled down to fit within a
+// box x box square, keeping its aspect ratio. Images already small enough are
+// left as they are, they are never scaled up.
+func fit(width, height, box int) (int, int) {
+	if width <= box && height <= box {
+		return width, height
+	}
+
+	if width >= height {
+		return box, max(1, (height*box+width/2)/width)
+	}
+	return max(1, (width*box+height/2)/height), box
+}
+
+// centerSquare is the largest square in the middle of the image.
+func centerSquare(src *image.RGBA) *image.RGBA {
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+
+	return src.SubImage(image.Rect(x, y, x+side, y+side)).(*image.RGBA)
+}
+
+// resize scales src down to width x height with a box filter: every pixel of
+// the result is the average of the source pixels it covers. It is cheap and
+// does not alias when shrinking, but is not meant for scaling up.
+func resize(src *image.RGBA, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if bounds.Dx() == width && bounds.Dy() == height {
+		draw.Draw(dst, dst.Rect, src, bounds.Min, draw.Src)
+		return dst
+	}
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var sum [4]uint64
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					sum[0] += uint64(src.Pix[i])
+					sum[1] += uint64(src.Pix[i+1])
+					sum[2] += uint64(src.Pix[i+2])
+					sum[3] += uint64(src.Pix[i+3])
+					i += 4
+				}
+			}
+
+			count := uint64((y1 - y0) * (x1 - x0))
+			i := dst.PixOffset(x, y)
+			for c := range sum {
+				dst.Pix[i+c] = uint8((sum[c] + count/2) / count)
+			}
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,192 @@
+package media
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// unsignedPayload tells S3 the body is not part of the signature, so uploads
+// can be streamed without hashing them first.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Store keeps blobs in a bucket of an S3-compatible service (AWS S3, MinIO,
+// R2...), addressed path-style as endpoint/bucket/key. Requests are signed
+// with AWS Signature Version 4.
+type S3Store struct {
+	endpoint  string
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	publicURL string
+	client    *http.Client
+	// now is replaceable so signatures can be checked against fixed dates.
+	now func() time.Time
+}
+
+func NewS3Store(endpoint, region, bucket, accessKey, secretKey, publicURL string) *S3Store {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if publicURL == "" {
+		publicURL = endpoint + "/" + bucket
+	}
+
+	return &S3Store{
+		endpoint:  endpoint,
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+		client:    &http.Client{Timeout: 5 * time.Minute},
+		now:       time.Now,
+	}
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// Delete succeeds for missing keys too, as S3 does.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+func (s *S3Store) URL(key string) string {
+	return s.publicURL + "/" + escapePath(key)
+}
+
+func (s *S3Store) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, method, s.endpoint+"/"+s.bucket+"/"+escapePath(key), body)
+}
+
+// do signs and sends the request, a response other than 2xx is an error and
+// its body is closed.
+func (s *S3Store) do(req *http.Request) (*http.Response, error) {
+	s.sign(req, s.now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, message)
+}
+
+// sign adds the Signature Version 4 headers for the request at the given time.
+func (s *S3Store) sign(req *http.Request, at time.Time) {
+	amzDate := at.Format("20060102T150405Z")
+	day := at.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + unsignedPayload,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), day)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// escapePath escapes every segment of a key the way S3 expects, keeping the slashes.
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,39 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+)
+
+const (
+	KindImage = "image"
+	KindVideo = "video"
+)
+
+var ErrUnsupportedType = errors.New("unsupported media type")
+
+// Type is an accepted upload format.
+type Type struct {
+	ContentType string
+	Kind        string
+	Extension   string
+}
+
+var types = map[string]Type{
+	"image/jpeg": {ContentType: "image/jpeg", Kind: KindImage, Extension: ".jpg"},
+	"image/png":  {ContentType: "image/png", Kind: KindImage, Extension: ".png"},
+	"image/gif":  {ContentType: "image/gif", Kind: KindImage, Extension: ".gif"},
+	"image/webp": {ContentType: "image/webp", Kind: KindImage, Extension: ".webp"},
+	"video/mp4":  {ContentType: "video/mp4", Kind: KindVideo, Extension: ".mp4"},
+	"video/webm": {ContentType: "video/webm", Kind: KindVideo, Extension: ".webm"},
+}
+
+// Sniff tells the type of an upload from its first bytes, whatever the client
+// claimed it to be. Up to 512 bytes are looked at.
+func Sniff(head []byte) (Type, error) {
+	t, ok := types[http.DetectContentType(head)]
+	if !ok {
+		return Type{}, ErrUnsupportedType
+	}
+	return t, nil
+}
@@ -0,0 +1,362 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+// bleveBatchSize bounds how many documents are touched per batch when a post
+// deletion has to cascade to its comments.
+const bleveBatchSize = 500
+
+// bleveMaxScan bounds how many hits a search looks at to fill a page with
+// results the viewer may see, a page can come back short past it. Offsets are
+// bounded well below it by store.SearchQuery.
+const bleveMaxScan = 5000
+
+type bleveDocument struct {
+	Type      string    `json:"type"`
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BleveIndexer keeps an embedded Bleve index on local disk. The index holds
+// no visibility rules, the store filters the hits for the viewer.
+type BleveIndexer struct {
+	index bleve.Index
+	store *store.Storage
+}
+
+func NewBleveIndexer(path string, storage *store.Storage) (*BleveIndexer, error) {
+	index, err := bleve.Open(path)
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		index, err = bleve.New(path, newBleveMapping())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &BleveIndexer{index: index, store: storage}, nil
+}
+
+func newBleveMapping() mapping.IndexMapping {
+	keyword := bleve.NewKeywordFieldMapping()
+
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = en.AnalyzerName
+
+	numeric := bleve.NewNumericFieldMapping()
+	datetime := bleve.NewDateTimeFieldMapping()
+
+	doc := bleve.NewDocumentStaticMapping()
+	doc.AddFieldMappingsAt("type", keyword)
+	doc.AddFieldMappingsAt("id", numeric)
+	doc.AddFieldMappingsAt("post_id", numeric)
+	doc.AddFieldMappingsAt("user_id", numeric)
+	doc.AddFieldMappingsAt("username", keyword)
+	doc.AddFieldMappingsAt("title", text)
+	doc.AddFieldMappingsAt("content", text)
+	doc.AddFieldMappingsAt("tags", keyword)
+	doc.AddFieldMappingsAt("created_at", datetime)
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = doc
+	indexMapping.DefaultAnalyzer = en.AnalyzerName
+
+	return indexMapping
+}
+
+func (idx *BleveIndexer) Index(ctx context.Context, doc Document) error {
+	return idx.index.Index(doc.Key(), bleveDocument{
+		Type:      doc.Type,
+		ID:        doc.ID,
+		PostID:    doc.PostID,
+		UserID:    doc.UserID,
+		Username:  doc.Username,
+		Title:     doc.Title,
+		Content:   doc.Content,
+		Tags:      doc.Tags,
+		CreatedAt: doc.CreatedAt,
+	})
+}
+
+func (idx *BleveIndexer) Delete(ctx context.Context, docType string, id int64) error {
+	if err := idx.index.Delete(Document{Type: docType, ID: id}.Key()); err != nil {
+		return err
+	}
+
+	// comments are removed together with their post by ON DELETE CASCADE,
+	// mirror that here
+	if docType != store.SearchTypePosts {
+		return nil
+	}
+
+	postID := float64(id)
+	inclusive := true
+	byPost := bleve.NewNumericRangeInclusiveQuery(&postID, &postID, &inclusive, &inclusive)
+	byPost.SetField("post_id")
+
+	q := bleve.NewConjunctionQuery(termQuery("type", store.SearchTypeComments), byPost)
+
+	for {
+		res, err := idx.index.SearchInContext(ctx, bleve.NewSearchRequestOptions(q, bleveBatchSize, 0, false))
+		if err != nil {
+			return err
+		}
+		if len(res.Hits) == 0 {
+			return nil
+		}
+
+		batch := idx.index.NewBatch()
+		for _, hit := range res.Hits {
+			batch.Delete(hit.ID)
+		}
+		if err := idx.index.Batch(batch); err != nil {
+			return err
+		}
+	}
+}
+
+// Search pages through the visible hits: the index does not know who can see
+// what, so hits are fetched in batches and filtered until the offset is
+// skipped and the page is full.
+func (idx *BleveIndexer) Search(ctx context.Context, sq store.SearchQuery) ([]store.SearchResult, error) {
+	q := buildBleveQuery(store.ParseSearch(sq.Query), sq.Type)
+	want := int(sq.Offset + sq.Limit)
+
+	visible := []store.SearchResult{}
+	for from := 0; len(visible) < want && from < bleveMaxScan; {
+		size := min(max(want-len(visible), bleveBatchSize), bleveMaxScan-from)
+		hits, total, err := idx.search(ctx, q, size, from)
+		if err != nil {
+			return nil, err
+		}
+
+		kept, err := idx.store.Search.Visible(ctx, hits, sq.ViewerID)
+		if err != nil {
+			return nil, err
+		}
+		visible = append(visible, kept...)
+
+		from += len(hits)
+		if len(hits) == 0 || from >= total {
+			break
+		}
+	}
+
+	if int(sq.Offset) >= len(visible) {
+		return []store.SearchResult{}, nil
+	}
+	visible = visible[sq.Offset:]
+	if len(visible) > int(sq.Limit) {
+		visible = visible[:sq.Limit]
+	}
+
+	return visible, nil
+}
+
+// search returns a batch of hits, whoever may see them, and the total number
+// of hits.
+func (idx *BleveIndexer) search(ctx context.Context, q query.Query, size, from int) ([]store.SearchResult, int, error) {
+	req := bleve.NewSearchRequestOptions(q, size, from, false)
+	req.Fields = []string{"*"}
+	req.Highlight = bleve.NewHighlightWithStyle("html")
+	req.Highlight.AddField("content")
+	req.Highlight.AddField("title")
+
+	res, err := idx.index.SearchInContext(ctx, req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]store.SearchResult, 0, len(res.Hits))
+	for _, hit := range res.Hits {
+		result := store.SearchResult{
+			Type:      stringField(hit.Fields, "type"),
+			ID:        intField(hit.Fields, "id"),
+			PostID:    intField(hit.Fields, "post_id"),
+			UserID:    intField(hit.Fields, "user_id"),
+			Username:  stringField(hit.Fields, "username"),
+			Title:     stringField(hit.Fields, "title"),
+			Tags:      stringsField(hit.Fields, "tags"),
+			Rank:      hit.Score,
+			CreatedAt: stringField(hit.Fields, "created_at"),
+		}
+
+		switch {
+		case len(hit.Fragments["content"]) > 0:
+			result.Snippet = strings.Join(hit.Fragments["content"], " … ")
+		case len(hit.Fragments["title"]) > 0:
+			result.Snippet = hit.Fragments["title"][0]
+		default:
+			result.Snippet = html.EscapeString(stringField(hit.Fields, "content"))
+		}
+
+		if result.Type == store.SearchTypeUsers {
+			result.Title = ""
+		}
+
+		results = append(results, result)
+	}
+
+	return results, int(res.Total), nil
+}
+
+func (idx *BleveIndexer) Count(ctx context.Context, docType string) (int64, error) {
+	req := bleve.NewSearchRequestOptions(termQuery("type", docType), 0, 0, false)
+
+	res, err := idx.index.SearchInContext(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(res.Total), nil
+}
+
+func (idx *BleveIndexer) IDs(ctx context.Context, docType string, fn func(int64) error) error {
+	var after []string
+	for {
+		req := bleve.NewSearchRequestOptions(termQuery("type", docType), bleveBatchSize, 0, false)
+		req.Fields = []string{"id"}
+		req.SortBy([]string{"_id"})
+		req.SearchAfter = after
+
+		res, err := idx.index.SearchInContext(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		for _, hit := range res.Hits {
+			if err := fn(intField(hit.Fields, "id")); err != nil {
+				return err
+			}
+		}
+
+		if len(res.Hits) < bleveBatchSize {
+			return nil
+		}
+		after = []string{res.Hits[len(res.Hits)-1].ID}
+	}
+}
+
+func (idx *BleveIndexer) Close() error {
+	return idx.index.Close()
+}
+
+// buildBleveQuery mirrors the websearch_to_tsquery semantics used by Postgres:
+// every term and phrase must match, exclusions must not, and #tag / @user act
+// as exact filters.
+func buildBleveQuery(parsed store.ParsedSearch, docType string) query.Query {
+	q := bleve.NewBooleanQuery()
+
+	for _, term := range parsed.Terms {
+		q.AddMust(textQuery(term, false))
+	}
+	for _, phrase := range parsed.Phrases {
+		q.AddMust(textQuery(phrase, true))
+	}
+	for _, excluded := range parsed.Exclude {
+		q.AddMustNot(textQuery(excluded, true))
+	}
+	for _, tag := range parsed.Tags {
+		q.AddMust(termQuery("tags", tag))
+	}
+	if len(parsed.Users) > 0 {
+		users := bleve.NewDisjunctionQuery()
+		for _, username := range parsed.Users {
+			users.AddQuery(termQuery("username", username))
+		}
+		q.AddMust(users)
+	}
+	if docType != "" {
+		q.AddMust(termQuery("type", docType))
+	}
+
+	if len(parsed.Terms) == 0 && len(parsed.Phrases) == 0 && len(parsed.Tags) == 0 && len(parsed.Users) == 0 {
+		q.AddMust(bleve.NewMatchAllQuery())
+	}
+
+	return q
+}
+
+// textQuery matches title (weighted highest), content and tags, the same
+// priority the Postgres search_vector weights use.
+func textQuery(text string, phrase bool) query.Query {
+	fields := []struct {
+		name  string
+		boost float64
+	}{
+		{"title", 3},
+		{"content", 2},
+	}
+
+	disjunction := bleve.NewDisjunctionQuery()
+	for _, field := range fields {
+		if phrase {
+			mq := bleve.NewMatchPhraseQuery(text)
+			mq.SetField(field.name)
+			mq.SetBoost(field.boost)
+			disjunction.AddQuery(mq)
+			continue
+		}
+
+		mq := bleve.NewMatchQuery(text)
+		mq.SetField(field.name)
+		mq.SetBoost(field.boost)
+		disjunction.AddQuery(mq)
+	}
+
+	tq := termQuery("tags", store.NormalizeTag(text))
+	tq.SetBoost(1)
+	disjunction.AddQuery(tq)
+
+	return disjunction
+}
+
+func termQuery(field, term string) *query.TermQuery {
+	tq := bleve.NewTermQuery(term)
+	tq.SetField(field)
+	return tq
+}
+
+func stringField(fields map[string]any, name string) string {
+	value, _ := fields[name].(string)
+	return value
+}
+
+func intField(fields map[string]any, name string) int64 {
+	value, _ := fields[name].(float64)
+	return int64(value)
+}
+
+// stringsField handles Bleve returning a single value array as a plain string.
+func stringsField(fields map[string]any, name string) []string {
+	switch value := fields[name].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			values = append(values, fmt.Sprint(v))
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,169 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+func TestBleveSearchPages(t *testing.T) {
+	storage := store.NewMockStore()
+	idx, err := NewBleveIndexer(filepath.Join(t.TempDir(), "index"), &storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	// more hits than fit in a batch, every other one hidden from the viewer
+	hidden := make(map[string]bool)
+	batch := idx.index.NewBatch()
+	for id := int64(1); id <= 3*bleveBatchSize; id++ {
+		doc := bleveDocument{Type: store.SearchTypePosts, ID: id, PostID: id, Content: "gophers", CreatedAt: time.Now()}
+		key := Document{Type: doc.Type, ID: id}.Key()
+		if err := batch.Index(key, doc); err != nil {
+			t.Fatal(err)
+		}
+		hidden[key] = id%2 == 0
+	}
+	if err := idx.index.Batch(batch); err != nil {
+		t.Fatal(err)
+	}
+	storage.Search.(*store.MockSearchStore).Hidden = hidden
+
+	search := func(t *testing.T, limit, offset int64) []store.SearchResult {
+		t.Helper()
+
+		results, err := idx.Search(context.Background(), store.SearchQuery{Query: "gophers", Limit: limit, Offset: offset})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, result := range results {
+			if hidden[fmt.Sprintf("%s:%d", result.Type, result.ID)] {
+				t.Errorf("expected post %d to be hidden", result.ID)
+			}
+		}
+		return results
+	}
+
+	t.Run("should fill pages past hidden hits", func(t *testing.T) {
+		seen := make(map[int64]bool)
+		for offset := int64(0); offset < bleveBatchSize; offset += 50 {
+			results := search(t, 50, offset)
+			if len(results) != 50 {
+				t.Fatalf("expected a full page at offset %d, got %d results", offset, len(results))
+			}
+			for _, result := range results {
+				if seen[result.ID] {
+					t.Errorf("expected post %d on a single page", result.ID)
+				}
+				seen[result.ID] = true
+			}
+		}
+	})
+
+	t.Run("should return the last page short", func(t *testing.T) {
+		results := search(t, 50, 3*bleveBatchSize/2-20)
+
+		if len(results) != 20 {
+			t.Errorf("expected 20 results, got %d", len(results))
+		}
+	})
+
+	t.Run("should return nothing past the last page", func(t *testing.T) {
+		if results := search(t, 50, 3*bleveBatchSize); len(results) != 0 {
+			t.Errorf("expected no results, got %d", len(results))
+		}
+		if results := search(t, 50, 1_000_000); len(results) != 0 {
+			t.Errorf("expected no results past the scan limit, got %d", len(results))
+		}
+	})
+}
+
+func TestBleveSearchSnippet(t *testing.T) {
+	storage := store.NewMockStore()
+	idx, err := NewBleveIndexer(filepath.Join(t.TempDir(), "index"), &storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	doc := bleveDocument{Type: store.SearchTypePosts, ID: 1, PostID: 1, Content: `<img src=x onerror=alert(1)> gophers`, CreatedAt: time.Now()}
+	if err := idx.index.Index(Document{Type: doc.Type, ID: doc.ID}.Key(), doc); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := idx.Search(context.Background(), store.SearchQuery{Query: "gophers", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected a result, got %d", len(results))
+	}
+
+	snippet := results[0].Snippet
+	if !strings.Contains(snippet, "&lt;img") || strings.Contains(snippet, "<img") {
+		t.Errorf("expected the content to be escaped, got %s", snippet)
+	}
+	if !strings.Contains(snippet, "<mark>gophers</mark>") {
+		t.Errorf("expected the match to be marked, got %s", snippet)
+	}
+}
+
+func TestBlevePrune(t *testing.T) {
+	storage := store.NewMockStore()
+	idx, err := NewBleveIndexer(filepath.Join(t.TempDir(), "index"), &storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	ctx := context.Background()
+
+	// more documents than IDs reads at once, every other one gone from the
+	// database, and a comment that must be left alone
+	posts := int64(2*bleveBatchSize + 3)
+	seen := make(map[int64]bool)
+	for id := int64(1); id <= posts; id++ {
+		if err := idx.Index(ctx, Document{Type: store.SearchTypePosts, ID: id, PostID: id, CreatedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		seen[id] = id%2 == 1
+	}
+	if err := idx.Index(ctx, Document{Type: store.SearchTypeComments, ID: 2, PostID: 1, CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := prune(ctx, idx, idx, store.SearchTypePosts, seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != posts/2 {
+		t.Errorf("expected %d documents removed, got %d", posts/2, removed)
+	}
+
+	count, err := idx.Count(ctx, store.SearchTypePosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != posts-posts/2 {
+		t.Errorf("expected %d posts left, got %d", posts-posts/2, count)
+	}
+
+	err = idx.IDs(ctx, store.SearchTypePosts, func(id int64) error {
+		if !seen[id] {
+			t.Errorf("expected post %d to be removed", id)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count, _ := idx.Count(ctx, store.SearchTypeComments); count != 1 {
+		t.Errorf("expected the comment to be kept, got %d comments", count)
+	}
+}
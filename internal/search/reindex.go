@@ -0,0 +1,186 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+var DocumentTypes = []string{
+	store.SearchTypePosts,
+	store.SearchTypeComments,
+	store.SearchTypeUsers,
+}
+
+var sourceQueries = map[string]string{
+	store.SearchTypePosts: `
+		SELECT p.id, p.id, p.user_id, u.username, p.title, p.content, p.tags, p.created_at
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.kind <> 'repost' AND p.status = 'published' AND p.deleted_at IS NULL
+		`,
+	store.SearchTypeComments: `
+		SELECT c.id, c.post_id, c.user_id, u.username, '', c.content, '{}'::TEXT[], c.created_at
+		FROM comments c
+		JOIN users u ON c.user_id = u.id
+		JOIN posts p ON c.post_id = p.id
+		WHERE c.deleted_at IS NULL AND p.status = 'published' AND p.deleted_at IS NULL
+		`,
+	store.SearchTypeUsers: `
+		SELECT u.id, 0, u.id, u.username, u.username, '', '{}'::TEXT[], u.created_at
+		FROM users u
+		WHERE u.is_activated = true
+		`,
+}
+
+var countQueries = map[string]string{
+	store.SearchTypePosts:    `SELECT COUNT(*) FROM posts WHERE kind <> 'repost' AND status = 'published' AND deleted_at IS NULL`,
+	store.SearchTypeComments: `SELECT COUNT(*) FROM comments c JOIN posts p ON c.post_id = p.id WHERE c.deleted_at IS NULL AND p.status = 'published' AND p.deleted_at IS NULL`,
+	store.SearchTypeUsers:    `SELECT COUNT(*) FROM users WHERE is_activated = true`,
+}
+
+// Reindex walks every searchable table and feeds each row to the indexer, then
+// removes the documents whose rows are gone or no longer searchable, e.g. a
+// delete that was missed. It returns the number of documents indexed and
+// removed per type. Indexers that are no Mirror have nothing to rebuild.
+func Reindex(ctx context.Context, db *sql.DB, idx Indexer) (map[string]int64, map[string]int64, error) {
+	indexed := make(map[string]int64, len(DocumentTypes))
+	removed := make(map[string]int64, len(DocumentTypes))
+
+	mirror, ok := idx.(Mirror)
+	if !ok {
+		return indexed, removed, nil
+	}
+
+	for _, docType := range DocumentTypes {
+		rows, err := db.QueryContext(ctx, sourceQueries[docType])
+		if err != nil {
+			return indexed, removed, err
+		}
+
+		seen := make(map[int64]bool)
+
+		for rows.Next() {
+			doc := Document{Type: docType}
+			if err := rows.Scan(
+				&doc.ID,
+				&doc.PostID,
+				&doc.UserID,
+				&doc.Username,
+				&doc.Title,
+				&doc.Content,
+				pq.Array(&doc.Tags),
+				&doc.CreatedAt,
+			); err != nil {
+				rows.Close()
+				return indexed, removed, err
+			}
+
+			if err := idx.Index(ctx, doc); err != nil {
+				rows.Close()
+				return indexed, removed, err
+			}
+			seen[doc.ID] = true
+			indexed[docType]++
+		}
+
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return indexed, removed, err
+		}
+		rows.Close()
+
+		removed[docType], err = prune(ctx, idx, mirror, docType, seen)
+		if err != nil {
+			return indexed, removed, err
+		}
+	}
+
+	return indexed, removed, nil
+}
+
+// prune deletes the documents of docType that are not in seen.
+func prune(ctx context.Context, idx Indexer, mirror Mirror, docType string, seen map[int64]bool) (int64, error) {
+	var stale []int64
+	err := mirror.IDs(ctx, docType, func(id int64) error {
+		if !seen[id] {
+			stale = append(stale, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range stale {
+		if err := idx.Delete(ctx, docType, id); err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(stale)), nil
+}
+
+type Mismatch struct {
+	Type       string
+	Database   int64
+	IndexCount int64
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s: database has %d, index has %d", m.Type, m.Database, m.IndexCount)
+}
+
+// CheckConsistency compares the number of indexed documents per type with the
+// number of rows in the source tables. Indexers that are no Mirror search the
+// tables themselves and are always consistent.
+func CheckConsistency(ctx context.Context, db *sql.DB, idx Indexer) ([]Mismatch, error) {
+	var mismatches []Mismatch
+
+	mirror, ok := idx.(Mirror)
+	if !ok {
+		return mismatches, nil
+	}
+
+	for _, docType := range DocumentTypes {
+		expected, err := countSource(ctx, db, docType)
+		if err != nil {
+			return nil, err
+		}
+
+		actual, err := mirror.Count(ctx, docType)
+		if err != nil {
+			return nil, err
+		}
+
+		if expected != actual {
+			mismatches = append(mismatches, Mismatch{
+				Type:       docType,
+				Database:   expected,
+				IndexCount: actual,
+			})
+		}
+	}
+
+	return mismatches, nil
+}
+
+func countSource(ctx context.Context, db *sql.DB, docType string) (int64, error) {
+	query, ok := countQueries[docType]
+	if !ok {
+		return 0, fmt.Errorf("unknown document type %q", docType)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, store.QueryTimeoutDuration)
+	defer cancel()
+
+	var count int64
+	if err := db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,138 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+var ErrUnknownBackend = errors.New("unknown search backend")
+
+type Action string
+
+const (
+	ActionUpsert Action = "upsert"
+	ActionDelete Action = "delete"
+)
+
+// Document is the backend agnostic representation of anything searchable.
+// Type is one of the store.SearchType* constants.
+type Document struct {
+	Type      string
+	ID        int64
+	PostID    int64
+	UserID    int64
+	Username  string
+	Title     string
+	Content   string
+	Tags      []string
+	CreatedAt time.Time
+}
+
+func (d Document) Key() string {
+	return fmt.Sprintf("%s:%d", d.Type, d.ID)
+}
+
+// Event is emitted whenever a post, comment or user is created, updated or deleted.
+type Event struct {
+	Action   Action
+	Document Document
+}
+
+type Indexer interface {
+	Index(context.Context, Document) error
+	Delete(context.Context, string, int64) error
+	Search(context.Context, store.SearchQuery) ([]store.SearchResult, error)
+	Close() error
+}
+
+// Mirror is implemented by indexers keeping their own copy of the documents,
+// which can drift from the database. The Postgres indexer searches the rows
+// themselves and is not one.
+type Mirror interface {
+	Count(context.Context, string) (int64, error)
+	// IDs calls fn with the id of every document of a type.
+	IDs(context.Context, string, func(int64) error) error
+}
+
+func Apply(ctx context.Context, idx Indexer, event Event) error {
+	switch event.Action {
+	case ActionUpsert:
+		return idx.Index(ctx, event.Document)
+	case ActionDelete:
+		return idx.Delete(ctx, event.Document.Type, event.Document.ID)
+	default:
+		return fmt.Errorf("unknown search action %q", event.Action)
+	}
+}
+
+func PostDocument(post *store.Post, username string) (Document, error) {
+	doc := Document{
+		Type:     store.SearchTypePosts,
+		ID:       post.ID,
+		PostID:   post.ID,
+		UserID:   post.UserID,
+		Username: username,
+		Title:    post.Title,
+		Content:  post.Content,
+		Tags:     post.Tags,
+	}
+
+	var err error
+	doc.CreatedAt, err = parseTimestamp(post.CreatedAt)
+	return doc, err
+}
+
+func CommentDocument(comment *store.Comment) (Document, error) {
+	doc := Document{
+		Type:     store.SearchTypeComments,
+		ID:       comment.ID,
+		PostID:   comment.PostID,
+		UserID:   comment.UserID,
+		Username: comment.User.Username,
+		Content:  comment.Content,
+	}
+
+	var err error
+	doc.CreatedAt, err = parseTimestamp(comment.CreatedAt)
+	return doc, err
+}
+
+func UserDocument(user *store.User) (Document, error) {
+	doc := Document{
+		Type:     store.SearchTypeUsers,
+		ID:       user.ID,
+		UserID:   user.ID,
+		Username: user.Username,
+		Title:    user.Username,
+	}
+
+	var err error
+	doc.CreatedAt, err = parseTimestamp(user.CreatedAt)
+	return doc, err
+}
+
+// parseTimestamp reads a timestamp as the store returns it. Documents are not
+// indexed with a made up date, the reindex command fixes them from the database.
+func parseTimestamp(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid created_at %q: %w", s, err)
+	}
+	return t, nil
+}
+
+func New(backend, indexPath string, db *sql.DB, storage *store.Storage) (Indexer, error) {
+	switch backend {
+	case "", "postgres":
+		return NewPostgresIndexer(db, storage), nil
+	case "bleve":
+		return NewBleveIndexer(indexPath, storage)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, backend)
+	}
+}
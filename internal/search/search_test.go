@@ -0,0 +1,34 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+func TestPostDocument(t *testing.T) {
+	t.Run("should keep the creation time", func(t *testing.T) {
+		doc, err := PostDocument(&store.Post{ID: 1, CreatedAt: "2024-03-01T10:00:00.5Z"}, "author")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := time.Date(2024, 3, 1, 10, 0, 0, 5e8, time.UTC); !doc.CreatedAt.Equal(want) {
+			t.Errorf("expected %s, got %s", want, doc.CreatedAt)
+		}
+	})
+
+	t.Run("should reject an invalid creation time", func(t *testing.T) {
+		if _, err := PostDocument(&store.Post{ID: 1, CreatedAt: "yesterday"}, "author"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestPostgresIndexer(t *testing.T) {
+	var idx Indexer = NewPostgresIndexer(nil, nil)
+
+	if _, ok := idx.(Mirror); ok {
+		t.Error("expected the Postgres indexer to have no copy to rebuild or check")
+	}
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+// BlockedUser is an entry of the authenticated user's block or mute list.
+type BlockedUser struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	CreatedAt string `json:"created_at"`
+}
+
+type BlockStore struct {
+	db *sql.DB
+}
+
+// Block stops all interaction between the two users. Existing follow edges and
+// pending follow requests are removed in both directions.
+func (store *BlockStore) Block(ctx context.Context, blockerID, blockedID int64) error {
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)`,
+			blockerID,
+			blockedID,
+		)
+		if err := constraintError(err); err != nil {
+			return err
+		}
+
+		queries := []string{
+			`DELETE FROM followers
+			WHERE (user_id = $1 AND follower_id = $2) OR (user_id = $2 AND follower_id = $1)`,
+			`DELETE FROM follow_requests
+			WHERE (user_id = $1 AND requester_id = $2) OR (user_id = $2 AND requester_id = $1)`,
+		}
+
+		for _, query := range queries {
+			if _, err := tx.ExecContext(ctx, query, blockerID, blockedID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (store *BlockStore) Unblock(ctx context.Context, blockerID, blockedID int64) error {
+	query := `
+		DELETE FROM user_blocks
+		WHERE blocker_id = $1 AND blocked_id = $2
+		`
+
+	return store.remove(ctx, query, blockerID, blockedID)
+}
+
+// Mute silently hides the muted user's posts and comments from the muter.
+func (store *BlockStore) Mute(ctx context.Context, muterID, mutedID int64) error {
+	query := `
+		INSERT INTO user_mutes (muter_id, muted_id)
+		VALUES ($1, $2)
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := store.db.ExecContext(ctx, query, muterID, mutedID)
+	return constraintError(err)
+}
+
+func (store *BlockStore) Unmute(ctx context.Context, muterID, mutedID int64) error {
+	query := `
+		DELETE FROM user_mutes
+		WHERE muter_id = $1 AND muted_id = $2
+		`
+
+	return store.remove(ctx, query, muterID, mutedID)
+}
+
+func (store *BlockStore) GetBlocked(ctx context.Context, userID int64, page PaginationQuery) ([]BlockedUser, error) {
+	query := `
+		SELECT u.id, u.username, b.created_at
+		FROM user_blocks b
+		JOIN users u ON b.blocked_id = u.id
+		WHERE b.blocker_id = $1
+		ORDER BY b.created_at DESC, u.id DESC
+		LIMIT $2 OFFSET $3
+		`
+
+	return store.list(ctx, query, userID, page)
+}
+
+func (store *BlockStore) GetMuted(ctx context.Context, userID int64, page PaginationQuery) ([]BlockedUser, error) {
+	query := `
+		SELECT u.id, u.username, m.created_at
+		FROM user_mutes m
+		JOIN users u ON m.muted_id = u.id
+		WHERE m.muter_id = $1
+		ORDER BY m.created_at DESC, u.id DESC
+		LIMIT $2 OFFSET $3
+		`
+
+	return store.list(ctx, query, userID, page)
+}
+
+func (store *BlockStore) remove(ctx context.Context, query string, userID, targetID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := store.db.ExecContext(ctx, query, userID, targetID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func (store *BlockStore) list(ctx context.Context, query string, userID int64, page PaginationQuery) ([]BlockedUser, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, userID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []BlockedUser{}
+	for rows.Next() {
+		var user BlockedUser
+		if err := rows.Scan(&user.ID, &user.Username, &user.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
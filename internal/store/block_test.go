@@ -0,0 +1,189 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// commentIDs returns the ids of a comment tree, parents before their replies.
+func commentIDs(comments []*Comment) string {
+	ids := []int64{}
+	var walk func([]*Comment)
+	walk = func(comments []*Comment) {
+		for _, comment := range comments {
+			ids = append(ids, comment.ID)
+			walk(comment.Replies)
+		}
+	}
+	walk(comments)
+	return fmt.Sprint(ids)
+}
+
+// feedIDs returns the ids of the posts of a feed, in order.
+func feedIDs(posts []*PostWithMetadata) string {
+	ids := []int64{}
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestBlocks(t *testing.T) {
+	db := newTestDB(t)
+	blocks := &BlockStore{db: db}
+	follows := &FollowStore{db: db}
+	posts := &PostStore{db: db}
+	comments := &CommentStore{db: db}
+	users := &UserStore{db: db}
+	ctx := context.Background()
+
+	blocker := insertUser(t, db, "blocker")
+	blocked := insertUser(t, db, "blocked")
+	host := insertUser(t, db, "host")
+
+	mustExec(t, db, `INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)`, blocker, blocked)
+	mustExec(t, db, `INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)`, blocked, blocker)
+	post := insertPost(t, db, blocker, "public")
+	hosted := insertPost(t, db, host, "public")
+	blockerComment := insertComment(t, db, hosted, blocker)
+	blockedComment := insertComment(t, db, hosted, blocked)
+
+	if err := blocks.Block(ctx, blocker, blocked); err != nil {
+		t.Fatal(err)
+	}
+	if err := blocks.Block(ctx, blocker, blocked); err != ErrConflict {
+		t.Errorf("expected blocking twice to be a conflict, got %v", err)
+	}
+
+	t.Run("should remove the follows both ways", func(t *testing.T) {
+		var follows int64
+		err := db.QueryRow(`
+			SELECT COUNT(*) FROM followers
+			WHERE (user_id = $1 AND follower_id = $2) OR (user_id = $2 AND follower_id = $1)
+			`, blocker, blocked).Scan(&follows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if follows != 0 {
+			t.Errorf("expected no follow left, got %d", follows)
+		}
+	})
+
+	t.Run("should refuse follows both ways", func(t *testing.T) {
+		if _, err := follows.Follow(ctx, blocker, blocked); err != ErrBlocked {
+			t.Errorf("expected ErrBlocked, got %v", err)
+		}
+		if _, err := follows.Follow(ctx, blocked, blocker); err != ErrBlocked {
+			t.Errorf("expected ErrBlocked, got %v", err)
+		}
+	})
+
+	t.Run("should hide the profile and the posts both ways", func(t *testing.T) {
+		if _, err := users.GetProfile(ctx, blocker, blocked); err != ErrorNotFound {
+			t.Errorf("expected ErrorNotFound, got %v", err)
+		}
+		if _, err := users.GetProfile(ctx, blocked, blocker); err != ErrorNotFound {
+			t.Errorf("expected ErrorNotFound, got %v", err)
+		}
+		if _, err := posts.GetVisibleByID(ctx, post, blocked); err != ErrorNotFound {
+			t.Errorf("expected ErrorNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should hide the comments both ways", func(t *testing.T) {
+		for viewer, want := range map[int64]int64{blocker: blockerComment, blocked: blockedComment} {
+			thread, err := comments.GetCommentByPostId(ctx, hosted, viewer, CursorQuery{Limit: 20})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := commentIDs(thread); got != fmt.Sprint([]int64{want}) {
+				t.Errorf("expected only the comment %d, got %s", want, got)
+			}
+		}
+
+		if _, err := comments.GetByID(ctx, hosted, blockedComment, blocker); err != ErrorNotFound {
+			t.Errorf("expected ErrorNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should refuse comments and replies", func(t *testing.T) {
+		err := comments.Create(ctx, &Comment{PostID: post, UserID: blocked, Content: "comment"})
+		if err != ErrBlocked {
+			t.Errorf("expected commenting on the blocker's post to be ErrBlocked, got %v", err)
+		}
+
+		err = comments.Create(ctx, &Comment{PostID: hosted, UserID: blocked, Content: "reply", ParentCommentID: &blockerComment})
+		if err != ErrBlocked {
+			t.Errorf("expected replying to the blocker to be ErrBlocked, got %v", err)
+		}
+	})
+
+	t.Run("should restore visibility once unblocked", func(t *testing.T) {
+		if err := blocks.Unblock(ctx, blocker, blocked); err != nil {
+			t.Fatal(err)
+		}
+		if err := blocks.Unblock(ctx, blocker, blocked); err != ErrorNotFound {
+			t.Errorf("expected ErrorNotFound, got %v", err)
+		}
+		if _, err := posts.GetVisibleByID(ctx, post, blocked); err != nil {
+			t.Errorf("expected the post to be visible, got %v", err)
+		}
+	})
+}
+
+func TestMutes(t *testing.T) {
+	db := newTestDB(t)
+	blocks := &BlockStore{db: db}
+	posts := &PostStore{db: db}
+	comments := &CommentStore{db: db}
+	ctx := context.Background()
+	feed := PaginationFeedQuery{Limit: 20, Sort: "desc"}
+
+	muter := insertUser(t, db, "muter")
+	muted := insertUser(t, db, "muted")
+	mustExec(t, db, `INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)`, muted, muter)
+
+	post := insertPost(t, db, muted, "public")
+	own := insertPost(t, db, muter, "public")
+	comment := insertComment(t, db, own, muted)
+
+	if err := blocks.Mute(ctx, muter, muted); err != nil {
+		t.Fatal(err)
+	}
+	if err := blocks.Mute(ctx, muter, muted); err != ErrConflict {
+		t.Errorf("expected muting twice to be a conflict, got %v", err)
+	}
+
+	home, err := posts.GetByUserId(ctx, muter, feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := feedIDs(home), fmt.Sprint([]int64{own}); got != want {
+		t.Errorf("expected the feed %s, got %s", want, got)
+	}
+
+	thread, err := comments.GetCommentByPostId(ctx, own, muter, CursorQuery{Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := commentIDs(thread); got != "[]" {
+		t.Errorf("expected the muted comment to be left out, got %s", got)
+	}
+
+	if _, err := posts.GetVisibleByID(ctx, post, muter); err != nil {
+		t.Errorf("expected a muted user's post to stay reachable, got %v", err)
+	}
+
+	if err := blocks.Unmute(ctx, muter, muted); err != nil {
+		t.Fatal(err)
+	}
+
+	thread, err = comments.GetCommentByPostId(ctx, own, muter, CursorQuery{Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := commentIDs(thread), fmt.Sprint([]int64{comment}); got != want {
+		t.Errorf("expected the comments %s once unmuted, got %s", want, got)
+	}
+}
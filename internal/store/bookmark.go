@@ -0,0 +1,267 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type BookmarkCollection struct {
+	ID             int64  `json:"id"`
+	UserID         int64  `json:"user_id"`
+	Name           string `json:"name"`
+	BookmarksCount int64  `json:"bookmarks_count"`
+	CreatedAt      string `json:"created_at"`
+}
+
+type Bookmark struct {
+	ID           int64  `json:"id"`
+	CollectionID *int64 `json:"collection_id"`
+	CreatedAt    string `json:"created_at"`
+	Post         Post   `json:"post"`
+}
+
+// BookmarkQuery lists bookmarks, newest first, optionally of a single collection.
+type BookmarkQuery struct {
+	CursorQuery
+	CollectionID *int64
+}
+
+type BookmarkStore struct {
+	db *sql.DB
+}
+
+// Save bookmarks a post the user can see, or moves an existing bookmark to
+// another collection. A nil collection leaves the bookmark unfiled.
+func (store *BookmarkStore) Save(ctx context.Context, userID, postID int64, collectionID *int64) (*Bookmark, error) {
+	query := `
+		INSERT INTO bookmarks (user_id, post_id, collection_id)
+		SELECT $1, p.id, $3::BIGINT
+		FROM posts p
+		WHERE p.id = $2
+			AND ` + canViewPostSQL("p", "$1") + `
+			AND ` + livePostSQL("p") + `
+			AND ($3::BIGINT IS NULL OR EXISTS (
+				SELECT 1 FROM bookmark_collections bc WHERE bc.id = $3 AND bc.user_id = $1
+			))
+		ON CONFLICT (user_id, post_id) DO UPDATE SET collection_id = EXCLUDED.collection_id
+		RETURNING id, collection_id, created_at
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	bookmark := &Bookmark{}
+	err := store.db.QueryRowContext(ctx, query, userID, postID, collectionID).Scan(
+		&bookmark.ID,
+		&bookmark.CollectionID,
+		&bookmark.CreatedAt,
+	)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return bookmark, nil
+}
+
+func (store *BookmarkStore) Remove(ctx context.Context, userID, postID int64) error {
+	query := `
+		DELETE FROM bookmarks
+		WHERE user_id = $1 AND post_id = $2
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := store.db.ExecContext(ctx, query, userID, postID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// List returns the bookmarks of a user, newest first, with the media, poll,
+// mentions, reactions and original of the posts. Bookmarks of posts the user can no longer see are
+// skipped but kept, they come back if the post becomes visible again.
+func (store *BookmarkStore) List(ctx context.Context, userID int64, bq BookmarkQuery) ([]Bookmark, error) {
+	query := `
+		SELECT
+			b.id, b.collection_id, b.created_at,
+			p.id, p.title, p.content, p.user_id, p.tags, p.created_at, p.updated_at, p.version,
+			p.kind, p.original_id, p.reaction_counts,
+			ARRAY(SELECT r.reaction FROM post_reactions r WHERE r.post_id = p.id AND r.user_id = $1 ORDER BY r.reaction),
+			u.id, u.username,
+			` + postMediaSQL("p") + `,
+			` + postPollSQL("p", "$1") + `,
+			` + mentionedUsersSQL("post_mentions", "post_id", "p.id") + `,
+			` + originalColumnsSQL("$1") + `
+		FROM bookmarks b
+		JOIN posts p ON b.post_id = p.id
+		JOIN users u ON p.user_id = u.id
+		` + originalJoinSQL("p", "$1") + `
+		WHERE b.user_id = $1
+			AND (b.id < $2 OR $2 = 0)
+			AND (b.collection_id = $4 OR $4::BIGINT IS NULL)
+			AND ` + canViewPostSQL("p", "$1") + `
+			AND ` + livePostSQL("p") + `
+		ORDER BY b.id DESC
+		LIMIT $3
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, userID, bq.Cursor, bq.Limit, bq.CollectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookmarks := []Bookmark{}
+	for rows.Next() {
+		var bookmark Bookmark
+		var poll []byte
+		var original originalColumns
+		var mentions mentionedUsers
+		bookmark.Post.User = &User{}
+		if err := rows.Scan(append([]any{
+			&bookmark.ID,
+			&bookmark.CollectionID,
+			&bookmark.CreatedAt,
+			&bookmark.Post.ID,
+			&bookmark.Post.Title,
+			&bookmark.Post.Content,
+			&bookmark.Post.UserID,
+			pq.Array(&bookmark.Post.Tags),
+			&bookmark.Post.CreatedAt,
+			&bookmark.Post.UpdatedAt,
+			&bookmark.Post.Version,
+			&bookmark.Post.Kind,
+			&bookmark.Post.OriginalID,
+			&bookmark.Post.ReactionCounts,
+			pq.Array(&bookmark.Post.MyReactions),
+			&bookmark.Post.User.ID,
+			&bookmark.Post.User.Username,
+			&bookmark.Post.Media,
+			&poll,
+			&mentions,
+		}, original.dest()...)...); err != nil {
+			return nil, err
+		}
+		if err := original.apply(&bookmark.Post); err != nil {
+			return nil, err
+		}
+		if bookmark.Post.Poll, err = scanPoll(poll); err != nil {
+			return nil, err
+		}
+		bookmark.Post.Mentions = mentions.entities(bookmark.Post.Content)
+		bookmark.Post.Bookmarked = true
+		bookmarks = append(bookmarks, bookmark)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return bookmarks, nil
+}
+
+func (store *BookmarkStore) CreateCollection(ctx context.Context, collection *BookmarkCollection) error {
+	query := `
+		INSERT INTO bookmark_collections (user_id, name)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := store.db.QueryRowContext(ctx, query, collection.UserID, collection.Name).Scan(
+		&collection.ID,
+		&collection.CreatedAt,
+	)
+	if err := constraintError(err); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (store *BookmarkStore) GetCollections(ctx context.Context, userID int64) ([]BookmarkCollection, error) {
+	query := `
+		SELECT bc.id, bc.user_id, bc.name, bc.created_at,
+			(SELECT COUNT(*) FROM bookmarks b WHERE b.collection_id = bc.id)
+		FROM bookmark_collections bc
+		WHERE bc.user_id = $1
+		ORDER BY bc.name
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []BookmarkCollection{}
+	for rows.Next() {
+		var collection BookmarkCollection
+		if err := rows.Scan(
+			&collection.ID,
+			&collection.UserID,
+			&collection.Name,
+			&collection.CreatedAt,
+			&collection.BookmarksCount,
+		); err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+// DeleteCollection removes a collection, its bookmarks are kept unfiled.
+func (store *BookmarkStore) DeleteCollection(ctx context.Context, userID, collectionID int64) error {
+	query := `
+		DELETE FROM bookmark_collections
+		WHERE id = $1 AND user_id = $2
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := store.db.ExecContext(ctx, query, collectionID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// bookmarkedPostIDs returns the ids of the bookmarked posts, in order.
+func bookmarkedPostIDs(t *testing.T, store *BookmarkStore, userID int64, collectionID *int64) string {
+	t.Helper()
+
+	bookmarks, err := store.List(context.Background(), userID, BookmarkQuery{
+		CursorQuery:  CursorQuery{Limit: 20},
+		CollectionID: collectionID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int64{}
+	for _, bookmark := range bookmarks {
+		ids = append(ids, bookmark.Post.ID)
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestBookmarkCollections(t *testing.T) {
+	db := newTestDB(t)
+	store := &BookmarkStore{db: db}
+	ctx := context.Background()
+
+	reader := insertUser(t, db, "reader")
+	author := insertUser(t, db, "author")
+	other := insertUser(t, db, "other")
+
+	first := insertPost(t, db, author, "public")
+	second := insertPost(t, db, author, "public")
+	private := insertPost(t, db, author, "private")
+
+	collection := &BookmarkCollection{UserID: reader, Name: "later"}
+	if err := store.CreateCollection(ctx, collection); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateCollection(ctx, &BookmarkCollection{UserID: reader, Name: "later"}); err != ErrConflict {
+		t.Errorf("expected a duplicate name to be a conflict, got %v", err)
+	}
+	foreign := &BookmarkCollection{UserID: other, Name: "later"}
+	if err := store.CreateCollection(ctx, foreign); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Save(ctx, reader, first, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Save(ctx, reader, second, &collection.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should refuse posts and collections of others", func(t *testing.T) {
+		if _, err := store.Save(ctx, reader, private, nil); err != ErrorNotFound {
+			t.Errorf("expected a hidden post to be ErrorNotFound, got %v", err)
+		}
+		if _, err := store.Save(ctx, reader, first, &foreign.ID); err != ErrorNotFound {
+			t.Errorf("expected another user's collection to be ErrorNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should list by collection", func(t *testing.T) {
+		if got, want := bookmarkedPostIDs(t, store, reader, nil), fmt.Sprint([]int64{second, first}); got != want {
+			t.Errorf("expected all the bookmarks %s, got %s", want, got)
+		}
+		if got, want := bookmarkedPostIDs(t, store, reader, &collection.ID), fmt.Sprint([]int64{second}); got != want {
+			t.Errorf("expected the collection %s, got %s", want, got)
+		}
+	})
+
+	t.Run("should move a bookmark", func(t *testing.T) {
+		bookmark, err := store.Save(ctx, reader, first, &collection.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bookmark.CollectionID == nil || *bookmark.CollectionID != collection.ID {
+			t.Errorf("expected the bookmark to move to %d, got %v", collection.ID, bookmark.CollectionID)
+		}
+
+		collections, err := store.GetCollections(ctx, reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(collections) != 1 || collections[0].BookmarksCount != 2 {
+			t.Errorf("expected one collection of 2 bookmarks, got %+v", collections)
+		}
+	})
+
+	t.Run("should skip posts no longer visible", func(t *testing.T) {
+		mustExec(t, db, `UPDATE posts SET visibility = 'private' WHERE id = $1`, second)
+		if got, want := bookmarkedPostIDs(t, store, reader, nil), fmt.Sprint([]int64{first}); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+
+		mustExec(t, db, `UPDATE posts SET visibility = 'public' WHERE id = $1`, second)
+		if got, want := bookmarkedPostIDs(t, store, reader, nil), fmt.Sprint([]int64{second, first}); got != want {
+			t.Errorf("expected the bookmark to come back, got %s", got)
+		}
+	})
+
+	t.Run("should unfile the bookmarks of a deleted collection", func(t *testing.T) {
+		if err := store.DeleteCollection(ctx, other, collection.ID); err != ErrorNotFound {
+			t.Errorf("expected deleting another user's collection to be ErrorNotFound, got %v", err)
+		}
+		if err := store.DeleteCollection(ctx, reader, collection.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		bookmarks, err := store.List(ctx, reader, BookmarkQuery{CursorQuery: CursorQuery{Limit: 20}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, bookmark := range bookmarks {
+			if bookmark.CollectionID != nil {
+				t.Errorf("expected the bookmark of %d to be unfiled, got %d", bookmark.Post.ID, *bookmark.CollectionID)
+			}
+		}
+		if len(bookmarks) != 2 {
+			t.Errorf("expected the 2 bookmarks to be kept, got %d", len(bookmarks))
+		}
+	})
+
+	t.Run("should remove a bookmark", func(t *testing.T) {
+		if err := store.Remove(ctx, reader, first); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Remove(ctx, reader, first); err != ErrorNotFound {
+			t.Errorf("expected ErrorNotFound, got %v", err)
+		}
+	})
+}
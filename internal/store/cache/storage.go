@@ -13,10 +13,16 @@ type Storage struct {
 		Set(context.Context, *store.User) error
 		Delete(context.Context, int64) error
 	}
+	Suggestions interface {
+		Get(context.Context, int64) ([]store.Suggestion, error)
+		Set(context.Context, int64, []store.Suggestion) error
+		Delete(context.Context, ...int64) error
+	}
 }
 
 func NewRedisStorage(rdb *redis.Client) Storage {
 	return Storage{
-		User: &UserStore{rdb},
+		User:        &UserStore{rdb},
+		Suggestions: &SuggestionStore{rdb},
 	}
 }
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/tenteedee/gopher-social/internal/store"
+)
+
+const SuggestionsExpTime = time.Hour
+
+type SuggestionStore struct {
+	rdb *redis.Client
+}
+
+func suggestionsKey(userID int64) string {
+	return fmt.Sprintf("suggestions-%d", userID)
+}
+
+// Get returns nil without error on a cache miss.
+func (s *SuggestionStore) Get(ctx context.Context, userID int64) ([]store.Suggestion, error) {
+	data, err := s.rdb.Get(ctx, suggestionsKey(userID)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	suggestions := []store.Suggestion{}
+	if err := json.Unmarshal([]byte(data), &suggestions); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+func (s *SuggestionStore) Set(ctx context.Context, userID int64, suggestions []store.Suggestion) error {
+	json, err := json.Marshal(suggestions)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.SetEX(ctx, suggestionsKey(userID), json, SuggestionsExpTime).Err()
+}
+
+func (s *SuggestionStore) Delete(ctx context.Context, userIDs ...int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = suggestionsKey(id)
+	}
+
+	return s.rdb.Del(ctx, keys...).Err()
+}
@@ -50,6 +50,6 @@ func (s *UserStore) Set(ctx context.Context, user *store.User) error {
 }
 
 func (s *UserStore) Delete(ctx context.Context, id int64) error {
-
-	return nil
+	cacheKey := fmt.Sprintf("user-%d", id)
+	return s.rdb.Del(ctx, cacheKey).Err()
 }
@@ -3,15 +3,47 @@ package store
 import (
 	"context"
 	"database/sql"
+
+	"github.com/lib/pq"
 )
 
+// CommentTreeDepth is how many levels of replies are returned at once. Deeper
+// replies are fetched through the replies of the last comment returned.
+const CommentTreeDepth = 3
+
+// CommentReplyLimit is how many replies of each comment in a tree are
+// returned. The rest are fetched through the replies of that comment.
+const CommentReplyLimit = 5
+
+// CommentPreviewSize is how many comments a post carries inline, the rest are
+// paginated through the comments of the post.
+const CommentPreviewSize = 3
+
 type Comment struct {
-	ID        int64  `json:"id"`
-	PostID    int64  `json:"post_id"`
-	UserID    int64  `json:"user_id"`
-	Content   string `json:"content"`
-	CreatedAt string `json:"created_at"`
-	User      User   `json:"user"`
+	ID              int64  `json:"id"`
+	PostID          int64  `json:"post_id"`
+	UserID          int64  `json:"user_id"`
+	ParentCommentID *int64 `json:"parent_comment_id"`
+	Content         string `json:"content"`
+	CreatedAt       string `json:"created_at"`
+	// EditedAt is set once the content has been edited.
+	EditedAt *string `json:"edited_at"`
+	User     User    `json:"user"`
+	// Deleted marks a tombstone: a deleted comment kept, without its content,
+	// because it has replies.
+	Deleted    bool  `json:"deleted,omitempty"`
+	ReplyCount int64 `json:"reply_count"`
+	// HasMoreReplies is set on comments whose replies were not all returned,
+	// because they are at the depth limit or have more than CommentReplyLimit.
+	HasMoreReplies bool       `json:"has_more_replies,omitempty"`
+	Replies        []*Comment `json:"replies,omitempty"`
+	// ReactionCounts is maintained by a trigger on comment_reactions.
+	ReactionCounts ReactionCounts `json:"reaction_counts"`
+	MyReactions    []string       `json:"my_reactions"`
+	// HiddenBy is set when one of the viewer's mute filters asks to warn about the comment.
+	HiddenBy *MuteFilterMatch `json:"hidden_by,omitempty"`
+	// Mentions locate the mentions of existing users in the content.
+	Mentions []MentionEntity `json:"mentions"`
 }
 
 type CommentStore struct {
@@ -22,29 +54,372 @@ func NewCommentStore(db *sql.DB) *CommentStore {
 	return &CommentStore{db: db}
 }
 
-func (store *CommentStore) GetCommentByPostId(ctx context.Context, postID int64) ([]Comment, error) {
+// GetByID returns a live comment of a post. Comments by users the viewer
+// blocked or was blocked by are not found.
+func (store *CommentStore) GetByID(ctx context.Context, postID, commentID, viewerID int64) (*Comment, error) {
+	return store.getOne(ctx, "c.deleted_at IS NULL", postID, commentID, viewerID)
+}
+
+// GetShownByID returns a comment of a post as shown in its thread: a live
+// comment, or a tombstone whose replies are still shown, see commentShownSQL.
+func (store *CommentStore) GetShownByID(ctx context.Context, postID, commentID, viewerID int64) (*Comment, error) {
+	return store.getOne(ctx, commentShownSQL, postID, commentID, viewerID)
+}
+
+func (store *CommentStore) getOne(ctx context.Context, filter string, postID, commentID, viewerID int64) (*Comment, error) {
 	query := `
-		SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, u.id, u.username
+		SELECT
+			c.id, c.post_id, c.user_id, c.parent_comment_id, ` + commentContentSQL + `, c.created_at, c.edited_at,
+			c.deleted_at IS NOT NULL, c.reply_count, c.reaction_counts,
+			u.id, u.username,
+			` + mentionedUsersSQL("comment_mentions", "comment_id", "c.id") + `
 		FROM comments c
 		JOIN users u ON c.user_id = u.id
-		WHERE post_id = $1
-    ORDER BY c.created_at DESC
+		WHERE c.id = $1 AND c.post_id = $2 AND ` + filter + `
+			AND ` + notBlockedSQL("c.user_id", "$3") + `
 		`
 
-	rows, err := store.db.QueryContext(ctx, query, postID)
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var comment Comment
+	var mentions mentionedUsers
+	err := store.db.QueryRowContext(ctx, query, commentID, postID, viewerID).Scan(
+		&comment.ID,
+		&comment.PostID,
+		&comment.UserID,
+		&comment.ParentCommentID,
+		&comment.Content,
+		&comment.CreatedAt,
+		&comment.EditedAt,
+		&comment.Deleted,
+		&comment.ReplyCount,
+		&comment.ReactionCounts,
+		&comment.User.ID,
+		&comment.User.Username,
+		&mentions,
+	)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+	comment.Mentions = mentions.entities(comment.Content)
+	if comment.Deleted {
+		comment.UserID = 0
+		comment.User = User{}
+	}
+
+	return &comment, nil
+}
+
+// GetCommentByPostId returns a page of the top-level comments of a post,
+// oldest first, each with its first CommentReplyLimit replies down to
+// CommentTreeDepth levels, or
+// nothing when the viewer is not allowed to see the post. Comments by blocked
+// or muted users, or caught by a hiding mute filter of the viewer, are left out
+// along with their replies.
+func (store *CommentStore) GetCommentByPostId(ctx context.Context, postID, viewerID int64, cq CursorQuery) ([]*Comment, error) {
+	return store.getTree(ctx, postID, viewerID, nil, cq)
+}
+
+// GetReplies returns a page of the reply tree of a comment, like GetCommentByPostId.
+func (store *CommentStore) GetReplies(ctx context.Context, postID, commentID, viewerID int64, cq CursorQuery) ([]*Comment, error) {
+	return store.getTree(ctx, postID, viewerID, &commentID, cq)
+}
+
+// commentShownSQL is true for the comments c shown in a thread: live ones,
+// and deleted ones as tombstones while replies of theirs are shown. The
+// reply_count trigger only counts shown replies.
+const commentShownSQL = `(c.deleted_at IS NULL OR c.reply_count > 0)`
+
+// commentContentSQL is the content of comment c, empty for tombstones.
+const commentContentSQL = `CASE WHEN c.deleted_at IS NULL THEN c.content ELSE '' END`
+
+// commentVisibleSQL filters comments c of posts p the viewer may see, it
+// expects the mute filter join of the comment.
+func commentVisibleSQL(viewer string) string {
+	return commentShownSQL + `
+		AND ` + canViewPostSQL("p", viewer) + `
+		AND ` + livePostSQL("p") + `
+		AND ` + notBlockedSQL("c.user_id", viewer) + `
+		AND ` + notMutedSQL("c.user_id", viewer) + `
+		AND ` + notHiddenByFilterSQL
+}
+
+func (store *CommentStore) getTree(ctx context.Context, postID, viewerID int64, rootID *int64, cq CursorQuery) ([]*Comment, error) {
+	// The page is cut on the first level, and the replies of each comment on
+	// the levels below, all already filtered so that a full page means there
+	// may be more. One reply past the limit is fetched to tell so, and the walk
+	// does not go below it.
+	query := `
+		WITH RECURSIVE thread AS (
+			SELECT page.id, 1 AS depth, 1::BIGINT AS n
+			FROM (
+				SELECT c.id
+				FROM comments c
+				JOIN posts p ON c.post_id = p.id
+				` + muteFilterJoinSQL("$2", commentContentSQL, "'{}'::TEXT[]") + `
+				WHERE c.post_id = $1
+					AND (c.parent_comment_id = $3 OR ($3::BIGINT IS NULL AND c.parent_comment_id IS NULL))
+					AND (c.id > $5 OR $5 = 0)
+					AND ` + commentVisibleSQL("$2") + `
+				ORDER BY c.id
+				LIMIT $6
+			) page
+			UNION ALL
+			SELECT r.id, t.depth + 1, r.n
+			FROM thread t
+			CROSS JOIN LATERAL (
+				SELECT c.id, ROW_NUMBER() OVER (ORDER BY c.id) AS n
+				FROM comments c
+				JOIN posts p ON c.post_id = p.id
+				` + muteFilterJoinSQL("$2", commentContentSQL, "'{}'::TEXT[]") + `
+				WHERE c.parent_comment_id = t.id
+					AND ` + commentVisibleSQL("$2") + `
+				ORDER BY c.id
+				LIMIT $7 + 1
+			) r
+			WHERE t.depth < $4 AND t.n <= $7
+		)
+		SELECT
+			c.id, c.post_id, c.user_id, c.parent_comment_id, ` + commentContentSQL + `, c.created_at, c.edited_at,
+			c.deleted_at IS NOT NULL, c.reply_count, t.depth, t.n,
+			u.id, u.username,
+			c.reaction_counts,
+			ARRAY(SELECT r.reaction FROM comment_reactions r WHERE r.comment_id = c.id AND r.user_id = $2 ORDER BY r.reaction),
+			` + mentionedUsersSQL("comment_mentions", "comment_id", "c.id") + `,
+			mf.id, mf.kind, mf.value
+		FROM thread t
+		JOIN comments c ON c.id = t.id
+		JOIN users u ON c.user_id = u.id
+		JOIN posts p ON c.post_id = p.id
+		` + muteFilterJoinSQL("$2", commentContentSQL, "'{}'::TEXT[]") + `
+		WHERE ` + commentVisibleSQL("$2") + `
+		ORDER BY t.depth, c.id
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, postID, viewerID, rootID, CommentTreeDepth, cq.Cursor, cq.Limit, CommentReplyLimit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var comments []Comment
+	// Rows come level by level, so a parent is always seen before its replies.
+	// Replies whose parent was filtered out are dropped with it, and a reply
+	// past CommentReplyLimit only tells that its parent has more.
+	roots := []*Comment{}
+	byID := make(map[int64]*Comment)
 	for rows.Next() {
 		var comment Comment
+		var filter muteFilterColumns
+		var depth int
+		var n int64
+		var mentions mentionedUsers
 		comment.User = User{}
+		if err := rows.Scan(append([]any{
+			&comment.ID,
+			&comment.PostID,
+			&comment.UserID,
+			&comment.ParentCommentID,
+			&comment.Content,
+			&comment.CreatedAt,
+			&comment.EditedAt,
+			&comment.Deleted,
+			&comment.ReplyCount,
+			&depth,
+			&n,
+			&comment.User.ID,
+			&comment.User.Username,
+			&comment.ReactionCounts,
+			pq.Array(&comment.MyReactions),
+			&mentions,
+		}, filter.dest()...)...); err != nil {
+			return nil, err
+		}
+		comment.HiddenBy = filter.match()
+		comment.Mentions = mentions.entities(comment.Content)
+		comment.HasMoreReplies = depth == CommentTreeDepth && comment.ReplyCount > 0
+		if comment.Deleted {
+			comment.UserID = 0
+			comment.User = User{}
+		}
+
+		if depth == 1 {
+			roots = append(roots, &comment)
+		} else {
+			parent, ok := byID[*comment.ParentCommentID]
+			if !ok {
+				continue
+			}
+			if n > CommentReplyLimit {
+				parent.HasMoreReplies = true
+				continue
+			}
+			parent.Replies = append(parent.Replies, &comment)
+		}
+		byID[comment.ID] = &comment
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roots, nil
+}
+
+// Create stores a comment, or a reply when ParentCommentID is set. The parent
+// must be a live comment of the same post. ErrorNotFound is returned when the
+// commenter cannot see the post, and ErrBlocked when the commenter and the
+// author of the post or of the parent blocked each other. The users mentioned
+// in the content are notified.
+func (store *CommentStore) Create(ctx context.Context, comment *Comment) error {
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var isBlocked, isVisible bool
+		err := tx.QueryRowContext(
+			ctx,
+			`SELECT NOT `+notBlockedSQL("p.user_id", "$2")+`, `+canViewPostSQL("p", "$2")+` AND `+livePostSQL("p")+`
+			FROM posts p
+			WHERE p.id = $1`,
+			comment.PostID,
+			comment.UserID,
+		).Scan(&isBlocked, &isVisible)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrorNotFound
+			default:
+				return err
+			}
+		}
+		if isBlocked {
+			return ErrBlocked
+		}
+		if !isVisible {
+			return ErrorNotFound
+		}
+
+		if comment.ParentCommentID != nil {
+			err := tx.QueryRowContext(
+				ctx,
+				`SELECT NOT `+notBlockedSQL("pc.user_id", "$2")+`
+				FROM comments pc
+				WHERE pc.id = $1 AND pc.post_id = $3 AND pc.deleted_at IS NULL`,
+				*comment.ParentCommentID,
+				comment.UserID,
+				comment.PostID,
+			).Scan(&isBlocked)
+			if err != nil {
+				switch err {
+				case sql.ErrNoRows:
+					return ErrorNotFound
+				default:
+					return err
+				}
+			}
+			if isBlocked {
+				return ErrBlocked
+			}
+		}
+
+		query := `
+			INSERT INTO comments (post_id, user_id, content, parent_comment_id)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at
+			`
+
+		err = tx.QueryRowContext(
+			ctx,
+			query,
+			comment.PostID,
+			comment.UserID,
+			comment.Content,
+			comment.ParentCommentID,
+		).Scan(
+			&comment.ID,
+			&comment.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := setCommentMentions(ctx, tx, comment.ID, comment.Content); err != nil {
+			return err
+		}
+
+		return notifyCommentMentions(ctx, tx, comment.ID)
+	})
+}
+
+// Update edits the content of a live comment and marks it as edited. Users
+// newly mentioned are notified.
+func (store *CommentStore) Update(ctx context.Context, comment *Comment) error {
+	query := `
+		UPDATE comments
+		SET content = $1, edited_at = now()
+		WHERE id = $2 AND deleted_at IS NULL
+		RETURNING edited_at
+		`
+
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(ctx, query, comment.Content, comment.ID).Scan(&comment.EditedAt)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrorNotFound
+			default:
+				return err
+			}
+		}
+
+		if err := setCommentMentions(ctx, tx, comment.ID, comment.Content); err != nil {
+			return err
+		}
+
+		return notifyCommentMentions(ctx, tx, comment.ID)
+	})
+}
+
+// GetLiveByPostID returns all the live comments of a post, oldest first and
+// flat, with their authors. It is meant to reindex a post, not to show it.
+func (store *CommentStore) GetLiveByPostID(ctx context.Context, postID int64) ([]*Comment, error) {
+	query := `
+		SELECT c.id, c.post_id, c.user_id, c.parent_comment_id, c.content, c.created_at, u.id, u.username
+		FROM comments c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.post_id = $1 AND c.deleted_at IS NULL
+		ORDER BY c.id
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*Comment{}
+	for rows.Next() {
+		var comment Comment
 		if err := rows.Scan(
 			&comment.ID,
 			&comment.PostID,
 			&comment.UserID,
+			&comment.ParentCommentID,
 			&comment.Content,
 			&comment.CreatedAt,
 			&comment.User.ID,
@@ -52,7 +427,7 @@ func (store *CommentStore) GetCommentByPostId(ctx context.Context, postID int64)
 		); err != nil {
 			return nil, err
 		}
-		comments = append(comments, comment)
+		comments = append(comments, &comment)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -62,29 +437,28 @@ func (store *CommentStore) GetCommentByPostId(ctx context.Context, postID int64)
 	return comments, nil
 }
 
-func (store *CommentStore) Create(ctx context.Context, comment *Comment) error {
+// Delete moves a comment to the trash. While replies of it are shown it stays
+// in the thread as a tombstone, see commentShownSQL.
+func (store *CommentStore) Delete(ctx context.Context, commentID, deletedBy int64) error {
 	query := `
-		INSERT INTO comments (post_id, user_id, content)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		UPDATE comments
+		SET deleted_at = now(), deleted_by = $2
+		WHERE id = $1 AND deleted_at IS NULL
 		`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := store.db.QueryRowContext(
-		ctx,
-		query,
-		comment.PostID,
-		comment.UserID,
-		comment.Content,
-	).Scan(
-		&comment.ID,
-		&comment.CreatedAt,
-	)
+	result, err := store.db.ExecContext(ctx, query, commentID, deletedBy)
 	if err != nil {
 		return err
 	}
-
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrorNotFound
+	}
 	return nil
 }
@@ -0,0 +1,200 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestCommentThreads(t *testing.T) {
+	db := newTestDB(t)
+	store := &CommentStore{db: db}
+	ctx := context.Background()
+
+	author := insertUser(t, db, "author")
+	replier := insertUser(t, db, "replier")
+	post := insertPost(t, db, author, "public")
+	other := insertPost(t, db, author, "public")
+
+	reply := func(t *testing.T, parentID *int64, content string) int64 {
+		t.Helper()
+
+		comment := &Comment{PostID: post, UserID: replier, Content: content, ParentCommentID: parentID}
+		if err := store.Create(ctx, comment); err != nil {
+			t.Fatal(err)
+		}
+		return comment.ID
+	}
+
+	// root <- first <- second <- third <- fourth, and root <- sibling
+	root := reply(t, nil, "root")
+	first := reply(t, &root, "first")
+	second := reply(t, &first, "second")
+	third := reply(t, &second, "third")
+	fourth := reply(t, &third, "fourth")
+	sibling := reply(t, &root, "sibling")
+
+	t.Run("should return the tree down to the depth limit", func(t *testing.T) {
+		thread, err := store.GetCommentByPostId(ctx, post, author, CursorQuery{Limit: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := commentIDs(thread), fmt.Sprint([]int64{root, first, second, sibling}); got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+		if got := thread[0].ReplyCount; got != 2 {
+			t.Errorf("expected the root to have 2 replies, got %d", got)
+		}
+		if deepest := thread[0].Replies[0].Replies[0]; !deepest.HasMoreReplies {
+			t.Errorf("expected the comment at the depth limit to have more replies, got %+v", deepest)
+		}
+	})
+
+	t.Run("should continue from the replies of a comment", func(t *testing.T) {
+		replies, err := store.GetReplies(ctx, post, second, author, CursorQuery{Limit: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := commentIDs(replies), fmt.Sprint([]int64{third, fourth}); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	})
+
+	t.Run("should refuse a parent of another post", func(t *testing.T) {
+		comment := &Comment{PostID: other, UserID: replier, Content: "lost", ParentCommentID: &root}
+		if err := store.Create(ctx, comment); err != ErrorNotFound {
+			t.Errorf("expected ErrorNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should keep a deleted comment with replies as a tombstone", func(t *testing.T) {
+		if err := store.Delete(ctx, first, replier); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Delete(ctx, sibling, replier); err != nil {
+			t.Fatal(err)
+		}
+
+		thread, err := store.GetCommentByPostId(ctx, post, author, CursorQuery{Limit: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := commentIDs(thread), fmt.Sprint([]int64{root, first, second}); got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+
+		tombstone := thread[0].Replies[0]
+		if !tombstone.Deleted || tombstone.Content != "" || tombstone.UserID != 0 {
+			t.Errorf("expected a tombstone without content nor author, got %+v", tombstone)
+		}
+		if got := thread[0].ReplyCount; got != 1 {
+			t.Errorf("expected the root to count 1 shown reply, got %d", got)
+		}
+
+		comment := &Comment{PostID: post, UserID: replier, Content: "late", ParentCommentID: &first}
+		if err := store.Create(ctx, comment); err != ErrorNotFound {
+			t.Errorf("expected replying to a deleted comment to be ErrorNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should page the top level", func(t *testing.T) {
+		later := reply(t, nil, "later")
+
+		page, err := store.GetCommentByPostId(ctx, post, author, CursorQuery{Limit: 1, Cursor: root})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := commentIDs(page), fmt.Sprint([]int64{later}); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	})
+}
+
+func TestCommentReplyLimit(t *testing.T) {
+	db := newTestDB(t)
+	store := &CommentStore{db: db}
+	ctx := context.Background()
+
+	author := insertUser(t, db, "author")
+	post := insertPost(t, db, author, "public")
+	root := insertComment(t, db, post, author)
+
+	// root <- CommentReplyLimit+1 replies, the first with as many of its own
+	replies := make([]int64, CommentReplyLimit+1)
+	for i := range replies {
+		replies[i] = insertID(t, db, `INSERT INTO comments (post_id, user_id, content, parent_comment_id) VALUES ($1, $2, 'reply', $3) RETURNING id`, post, author, root)
+	}
+	for range CommentReplyLimit + 1 {
+		mustExec(t, db, `INSERT INTO comments (post_id, user_id, content, parent_comment_id) VALUES ($1, $2, 'nested', $3)`, post, author, replies[0])
+	}
+
+	thread, err := store.GetCommentByPostId(ctx, post, author, CursorQuery{Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(thread) != 1 {
+		t.Fatalf("expected the root only, got %s", commentIDs(thread))
+	}
+
+	if got := len(thread[0].Replies); got != CommentReplyLimit {
+		t.Errorf("expected %d replies of the root, got %d", CommentReplyLimit, got)
+	}
+	if !thread[0].HasMoreReplies {
+		t.Error("expected the root to have more replies")
+	}
+
+	first := thread[0].Replies[0]
+	if got := len(first.Replies); got != CommentReplyLimit || !first.HasMoreReplies {
+		t.Errorf("expected %d replies of the first reply and more, got %d", CommentReplyLimit, got)
+	}
+	if last := thread[0].Replies[CommentReplyLimit-1]; last.HasMoreReplies {
+		t.Errorf("expected a reply without replies to have no more, got %+v", last)
+	}
+
+	rest, err := store.GetReplies(ctx, post, root, author, CursorQuery{Limit: 20, Cursor: replies[CommentReplyLimit-1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := commentIDs(rest), fmt.Sprint([]int64{replies[CommentReplyLimit]}); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestCommentEdits(t *testing.T) {
+	db := newTestDB(t)
+	store := &CommentStore{db: db}
+	ctx := context.Background()
+
+	author := insertUser(t, db, "author")
+	post := insertPost(t, db, author, "public")
+	id := insertComment(t, db, post, author)
+
+	comment := &Comment{ID: id, Content: "edited"}
+	if err := store.Update(ctx, comment); err != nil {
+		t.Fatal(err)
+	}
+	if comment.EditedAt == nil {
+		t.Error("expected the comment to be marked as edited")
+	}
+
+	got, err := store.GetByID(ctx, post, id, author)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Content != "edited" || got.EditedAt == nil {
+		t.Errorf("expected the edited content, got %+v", got)
+	}
+
+	if err := store.Delete(ctx, id, author); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, id, author); err != ErrorNotFound {
+		t.Errorf("expected deleting twice to be ErrorNotFound, got %v", err)
+	}
+	if err := store.Update(ctx, &Comment{ID: id, Content: "again"}); err != ErrorNotFound {
+		t.Errorf("expected editing a deleted comment to be ErrorNotFound, got %v", err)
+	}
+	if _, err := store.GetByID(ctx, post, id, author); err != ErrorNotFound {
+		t.Errorf("expected a deleted comment to be ErrorNotFound, got %v", err)
+	}
+}
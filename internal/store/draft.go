@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GetDrafts lists the drafts and scheduled posts of a user with their
+// mentions, the ones due first, then the most recently updated drafts.
+func (store *PostStore) GetDrafts(ctx context.Context, userID int64, page PaginationQuery) ([]Post, error) {
+	query := `
+		SELECT p.id, p.title, p.content, p.tags, p.user_id, p.version, p.created_at, p.updated_at, p.status, p.publish_at, p.visibility,
+			` + postMediaSQL("p") + `,
+			` + postPollSQL("p", "$1") + `,
+			` + mentionedUsersSQL("post_mentions", "post_id", "p.id") + `
+		FROM posts p
+		WHERE p.user_id = $1 AND p.status <> 'published' AND p.deleted_at IS NULL
+		ORDER BY p.publish_at ASC NULLS LAST, p.updated_at DESC
+		LIMIT $2 OFFSET $3
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, userID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		post := Post{Kind: "post"}
+		var poll []byte
+		var mentions mentionedUsers
+		if err := rows.Scan(
+			&post.ID,
+			&post.Title,
+			&post.Content,
+			pq.Array(&post.Tags),
+			&post.UserID,
+			&post.Version,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&post.Status,
+			&post.PublishAt,
+			&post.Visibility,
+			&post.Media,
+			&poll,
+			&mentions,
+		); err != nil {
+			return nil, err
+		}
+		post.Mentions = mentions.entities(post.Content)
+		if post.Poll, err = scanPoll(poll); err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// PublishScheduled makes live up to limit scheduled posts whose publish_at is
+// due by now, the longest due first, and returns them, notifying the users
+// they mention. Callers publish batch after batch until nothing is returned.
+// Posts are claimed with SKIP LOCKED, so overlapping runs never publish a post
+// twice nor wait on each other.
+func (store *PostStore) PublishScheduled(ctx context.Context, now time.Time, limit int) ([]*Post, error) {
+	query := `
+		UPDATE posts
+		SET status = 'published', published_at = publish_at, version = version + 1, updated_at = now()
+		WHERE id IN (
+			SELECT id FROM posts
+			WHERE status = 'scheduled' AND publish_at <= $1 AND deleted_at IS NULL
+			ORDER BY publish_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, title, content, tags, user_id, version, created_at, updated_at, status, publish_at, published_at, visibility, kind
+		`
+
+	posts := []*Post{}
+
+	err := withTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		rows, err := tx.QueryContext(ctx, query, now, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		ids := []int64{}
+		for rows.Next() {
+			post := &Post{}
+			if err := rows.Scan(
+				&post.ID,
+				&post.Title,
+				&post.Content,
+				pq.Array(&post.Tags),
+				&post.UserID,
+				&post.Version,
+				&post.CreatedAt,
+				&post.UpdatedAt,
+				&post.Status,
+				&post.PublishAt,
+				&post.PublishedAt,
+				&post.Visibility,
+				&post.Kind,
+			); err != nil {
+				return err
+			}
+			posts = append(posts, post)
+			ids = append(ids, post.ID)
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		return notifyPostMentions(ctx, tx, ids...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// insertUnpublished inserts a draft, or a scheduled post when publishIn is
+// set, publish_at being that far from now.
+func insertUnpublished(t *testing.T, db *sql.DB, userID int64, publishIn string) int64 {
+	t.Helper()
+
+	if publishIn == "" {
+		return insertID(t, db, `
+			INSERT INTO posts (user_id, title, content, tags, status)
+			VALUES ($1, 'title', 'content', '{}', 'draft')
+			RETURNING id
+			`, userID)
+	}
+
+	return insertID(t, db, `
+		INSERT INTO posts (user_id, title, content, tags, status, publish_at)
+		VALUES ($1, 'title', 'content', '{}', 'scheduled', now() + $2::INTERVAL)
+		RETURNING id
+		`, userID, publishIn)
+}
+
+func TestGetDrafts(t *testing.T) {
+	db := newTestDB(t)
+	posts := &PostStore{db: db}
+	ctx := context.Background()
+
+	author := insertUser(t, db, "author")
+	other := insertUser(t, db, "other")
+
+	older := insertUnpublished(t, db, author, "")
+	newer := insertUnpublished(t, db, author, "")
+	mustExec(t, db, `UPDATE posts SET updated_at = updated_at - interval '1 hour' WHERE id = $1`, older)
+	later := insertUnpublished(t, db, author, "2 days")
+	sooner := insertUnpublished(t, db, author, "1 day")
+	deleted := insertUnpublished(t, db, author, "")
+	mustExec(t, db, `UPDATE posts SET deleted_at = now() WHERE id = $1`, deleted)
+	insertPost(t, db, author, "public")
+	insertUnpublished(t, db, other, "")
+
+	drafts, err := posts.GetDrafts(ctx, author, PaginationQuery{Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []int64{}
+	for _, draft := range drafts {
+		ids = append(ids, draft.ID)
+	}
+	if got, want := fmt.Sprint(ids), fmt.Sprint([]int64{sooner, later, newer, older}); got != want {
+		t.Errorf("expected the scheduled posts by due time, then the drafts most recently updated first: %s, got %s", want, got)
+	}
+}
+
+func TestPublishScheduled(t *testing.T) {
+	db := newTestDB(t)
+	posts := &PostStore{db: db}
+	ctx := context.Background()
+
+	author := insertUser(t, db, "author")
+	mentioned := insertUser(t, db, "mentioned")
+
+	publishedIDs := func(t *testing.T, limit int) []int64 {
+		t.Helper()
+
+		published, err := posts.PublishScheduled(ctx, time.Now(), limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := []int64{}
+		for _, post := range published {
+			if post.Status != PostStatusPublished || post.PublishedAt == nil {
+				t.Errorf("expected post %d to be published, got %+v", post.ID, post)
+			}
+			ids = append(ids, post.ID)
+		}
+		return ids
+	}
+
+	t.Run("should publish the due posts only, longest due first", func(t *testing.T) {
+		due := insertUnpublished(t, db, author, "-2 hours")
+		dueLater := insertUnpublished(t, db, author, "-1 hour")
+		future := insertUnpublished(t, db, author, "1 hour")
+		deleted := insertUnpublished(t, db, author, "-1 hour")
+		mustExec(t, db, `UPDATE posts SET deleted_at = now() WHERE id = $1`, deleted)
+
+		if got, want := fmt.Sprint(publishedIDs(t, 1)), fmt.Sprint([]int64{due}); got != want {
+			t.Errorf("expected the first batch to be %s, got %s", want, got)
+		}
+		if got, want := fmt.Sprint(publishedIDs(t, 10)), fmt.Sprint([]int64{dueLater}); got != want {
+			t.Errorf("expected the next batch to be %s, got %s", want, got)
+		}
+		if got := publishedIDs(t, 10); len(got) != 0 {
+			t.Errorf("expected a run with nothing due to publish nothing, got %v", got)
+		}
+
+		var status string
+		if err := db.QueryRow(`SELECT status FROM posts WHERE id = $1`, future).Scan(&status); err != nil {
+			t.Fatal(err)
+		}
+		if status != PostStatusScheduled {
+			t.Errorf("expected the future post to stay scheduled, got %s", status)
+		}
+		if err := db.QueryRow(`SELECT status FROM posts WHERE id = $1`, deleted).Scan(&status); err != nil {
+			t.Fatal(err)
+		}
+		if status != PostStatusScheduled {
+			t.Errorf("expected the deleted post to stay scheduled, got %s", status)
+		}
+	})
+
+	t.Run("should return the kind of the post", func(t *testing.T) {
+		original := insertPost(t, db, author, "public")
+		quote := insertID(t, db, `
+			INSERT INTO posts (user_id, title, content, tags, status, publish_at, kind, original_id)
+			VALUES ($1, 'title', 'content', '{}', 'scheduled', now() - interval '1 minute', 'quote', $2)
+			RETURNING id
+			`, author, original)
+
+		published, err := posts.PublishScheduled(ctx, time.Now(), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(published) != 1 || published[0].ID != quote || published[0].Kind != PostKindQuote {
+			t.Errorf("expected the quote %d, got %+v", quote, published)
+		}
+	})
+
+	t.Run("should notify the mentioned users once", func(t *testing.T) {
+		publishAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		post, err := posts.Create(ctx, &Post{
+			UserID:    author,
+			Title:     "title",
+			Content:   "hi @mentioned",
+			Tags:      []string{},
+			Status:    PostStatusScheduled,
+			PublishAt: &publishAt,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for range 2 {
+			if _, err := posts.PublishScheduled(ctx, time.Now().Add(2*time.Hour), 10); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var count int
+		if err := db.QueryRow(
+			`SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND post_id = $2 AND kind = 'mention'`,
+			mentioned,
+			post.ID,
+		).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Errorf("expected a single notification, got %d", count)
+		}
+	})
+}
@@ -3,8 +3,6 @@ package store
 import (
 	"context"
 	"database/sql"
-
-	"github.com/lib/pq"
 )
 
 type FollowStore struct {
@@ -18,38 +16,302 @@ type Follow struct {
 	UpdatedAt  string `json:"updated_at"`
 }
 
-func (store *FollowStore) Follow(ctx context.Context, followedUserID int64, followerID int64) error {
+const (
+	FollowStatusFollowing = "following"
+	FollowStatusRequested = "requested"
+)
+
+type FollowRequest struct {
+	RequesterID int64  `json:"requester_id"`
+	Username    string `json:"username"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// FollowListEntry is a user in a followers or following list. IsFollowing is
+// relative to the viewer, not to the owner of the list.
+type FollowListEntry struct {
+	ID          int64  `json:"id"`
+	Username    string `json:"username"`
+	FollowedAt  string `json:"followed_at"`
+	IsFollowing bool   `json:"is_following"`
+}
+
+// Follow makes followerID follow followedUserID. Following a private account
+// only files a follow request, the returned status tells which one happened.
+func (store *FollowStore) Follow(ctx context.Context, followedUserID int64, followerID int64) (string, error) {
+	status := FollowStatusFollowing
+
+	err := withTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		// the follow counters trigger updates both users, their rows are
+		// locked up front and in id order so follows of the same user, or of
+		// each other, queue up instead of deadlocking
+		_, err := tx.ExecContext(
+			ctx,
+			`SELECT 1 FROM users WHERE id IN ($1, $2) ORDER BY id FOR NO KEY UPDATE`,
+			followedUserID,
+			followerID,
+		)
+		if err != nil {
+			return err
+		}
+
+		var isPrivate, isBlocked bool
+		err = tx.QueryRowContext(
+			ctx,
+			`SELECT u.is_private, NOT `+notBlockedSQL("u.id", "$2")+`
+			FROM users u
+			WHERE u.id = $1 AND u.is_activated = true`,
+			followedUserID,
+			followerID,
+		).Scan(&isPrivate, &isBlocked)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrorNotFound
+			default:
+				return err
+			}
+		}
+		if isBlocked {
+			return ErrBlocked
+		}
+
+		query := `
+			INSERT INTO followers (user_id, follower_id)
+			VALUES ($1, $2)
+			`
+
+		if isPrivate {
+			var alreadyFollowing bool
+			err := tx.QueryRowContext(
+				ctx,
+				`SELECT EXISTS (SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2)`,
+				followedUserID,
+				followerID,
+			).Scan(&alreadyFollowing)
+			if err != nil {
+				return err
+			}
+			if alreadyFollowing {
+				return ErrConflict
+			}
+
+			status = FollowStatusRequested
+			query = `
+				INSERT INTO follow_requests (user_id, requester_id)
+				VALUES ($1, $2)
+				`
+		}
+
+		_, err = tx.ExecContext(ctx, query, followedUserID, followerID)
+		return constraintError(err)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return status, nil
+}
+
+// Unfollow removes the follow edge, or withdraws a pending follow request.
+func (store *FollowStore) Unfollow(ctx context.Context, followedUserID, followerID int64) error {
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		_, err := tx.ExecContext(ctx, `DELETE FROM followers WHERE user_id = $1 AND follower_id = $2`, followedUserID, followerID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM follow_requests WHERE user_id = $1 AND requester_id = $2`, followedUserID, followerID)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// GetRequests lists the pending follow requests sent to userID, oldest first.
+func (store *FollowStore) GetRequests(ctx context.Context, userID int64, page PaginationQuery) ([]FollowRequest, error) {
 	query := `
-		INSERT INTO followers (user_id, follower_id)
-		VALUES ($1, $2)
+		SELECT fr.requester_id, u.username, fr.created_at
+		FROM follow_requests fr
+		JOIN users u ON fr.requester_id = u.id
+		WHERE fr.user_id = $1
+		ORDER BY fr.created_at, fr.requester_id
+		LIMIT $2 OFFSET $3
 		`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := store.db.ExecContext(ctx, query, followedUserID, followerID)
+	rows, err := store.db.QueryContext(ctx, query, userID, page.Limit, page.Offset)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-			return ErrConflict
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []FollowRequest{}
+	for rows.Next() {
+		var request FollowRequest
+		if err := rows.Scan(
+			&request.RequesterID,
+			&request.Username,
+			&request.CreatedAt,
+		); err != nil {
+			return nil, err
 		}
+		requests = append(requests, request)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// ApproveRequest turns a pending follow request into a follow edge.
+func (store *FollowStore) ApproveRequest(ctx context.Context, userID, requesterID int64) error {
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		if err := store.deleteRequest(ctx, tx, userID, requesterID); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO followers (user_id, follower_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			userID,
+			requesterID,
+		)
+		return err
+	})
+}
+
+func (store *FollowStore) RejectRequest(ctx context.Context, userID, requesterID int64) error {
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		return store.deleteRequest(ctx, tx, userID, requesterID)
+	})
+}
+
+func (store *FollowStore) deleteRequest(ctx context.Context, tx *sql.Tx, userID, requesterID int64) error {
+	query := `
+		DELETE FROM follow_requests
+		WHERE user_id = $1 AND requester_id = $2
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, query, userID, requesterID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrorNotFound
 	}
 
 	return nil
 }
 
-func (store *FollowStore) Unfollow(ctx context.Context, followedUserID, followerID int64) error {
+// GetFollowers lists the users following userID, most recent first, leaving
+// out users blocked by or blocking the viewer. ErrorNotFound is returned when
+// the viewer may not see the lists of userID: they blocked one another, or
+// userID is a private account the viewer does not follow.
+func (store *FollowStore) GetFollowers(ctx context.Context, userID, viewerID int64, page PaginationQuery) ([]FollowListEntry, error) {
 	query := `
-		DELETE FROM followers
-		WHERE user_id = $1 AND follower_id = $2
+		SELECT u.id, u.username, f.created_at,
+			EXISTS (SELECT 1 FROM followers vf WHERE vf.user_id = u.id AND vf.follower_id = $2)
+		FROM followers f
+		JOIN users u ON f.follower_id = u.id
+		WHERE f.user_id = $1
+			AND ` + notBlockedSQL("u.id", "$2") + `
+		ORDER BY f.created_at DESC, u.id DESC
+		LIMIT $3 OFFSET $4
 		`
 
+	return store.list(ctx, query, userID, viewerID, page)
+}
+
+// GetFollowing lists the users userID follows, most recent first, like
+// GetFollowers.
+func (store *FollowStore) GetFollowing(ctx context.Context, userID, viewerID int64, page PaginationQuery) ([]FollowListEntry, error) {
+	query := `
+		SELECT u.id, u.username, f.created_at,
+			EXISTS (SELECT 1 FROM followers vf WHERE vf.user_id = u.id AND vf.follower_id = $2)
+		FROM followers f
+		JOIN users u ON f.user_id = u.id
+		WHERE f.follower_id = $1
+			AND ` + notBlockedSQL("u.id", "$2") + `
+		ORDER BY f.created_at DESC, u.id DESC
+		LIMIT $3 OFFSET $4
+		`
+
+	return store.list(ctx, query, userID, viewerID, page)
+}
+
+func (store *FollowStore) list(ctx context.Context, query string, userID, viewerID int64, page PaginationQuery) ([]FollowListEntry, error) {
+	if err := store.checkListsVisible(ctx, userID, viewerID); err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := store.db.ExecContext(ctx, query, followedUserID, followerID)
+	rows, err := store.db.QueryContext(ctx, query, userID, viewerID, page.Limit, page.Offset)
 	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []FollowListEntry{}
+	for rows.Next() {
+		var entry FollowListEntry
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Username,
+			&entry.FollowedAt,
+			&entry.IsFollowing,
+		); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// checkListsVisible returns ErrorNotFound unless the viewer may see the
+// followers and following of userID, on the terms of canViewAuthorSQL.
+func (store *FollowStore) checkListsVisible(ctx context.Context, userID, viewerID int64) error {
+	query := `SELECT ` + canViewAuthorSQL("$1::BIGINT", "$2::BIGINT")
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var visible bool
+	if err := store.db.QueryRowContext(ctx, query, userID, viewerID).Scan(&visible); err != nil {
 		return err
 	}
+	if !visible {
+		return ErrorNotFound
+	}
 
 	return nil
 }
@@ -0,0 +1,314 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// followListIDs returns the sorted ids of a follow list.
+func followListIDs(entries []FollowListEntry) string {
+	ids := []int64{}
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return fmt.Sprint(ids)
+}
+
+func TestFollowListsBlocks(t *testing.T) {
+	db := newTestDB(t)
+	store := &FollowStore{db: db}
+	ctx := context.Background()
+	page := PaginationQuery{Limit: 20}
+
+	owner := insertUser(t, db, "owner")
+	fan := insertUser(t, db, "fan")
+	noisy := insertUser(t, db, "noisy")
+	stranger := insertUser(t, db, "stranger")
+	blocked := insertUser(t, db, "blocked")
+
+	for _, follow := range [][2]int64{{owner, fan}, {owner, noisy}, {fan, owner}, {noisy, owner}} {
+		mustExec(t, db, `INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)`, follow[0], follow[1])
+	}
+	mustExec(t, db, `INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)`, owner, blocked)
+	mustExec(t, db, `INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)`, noisy, stranger)
+
+	lists := map[string]func(context.Context, int64, int64, PaginationQuery) ([]FollowListEntry, error){
+		"followers": store.GetFollowers,
+		"following": store.GetFollowing,
+	}
+
+	for name, list := range lists {
+		t.Run(name+" should not be found by a blocked viewer", func(t *testing.T) {
+			if _, err := list(ctx, owner, blocked, page); err != ErrorNotFound {
+				t.Errorf("expected ErrorNotFound, got %v", err)
+			}
+		})
+
+		t.Run(name+" should leave out the users blocked either way", func(t *testing.T) {
+			entries, err := list(ctx, owner, stranger, page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := followListIDs(entries), fmt.Sprint([]int64{fan}); got != want {
+				t.Errorf("expected %s, got %s", want, got)
+			}
+		})
+
+		t.Run(name+" should list everyone to others", func(t *testing.T) {
+			entries, err := list(ctx, owner, fan, page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := followListIDs(entries), fmt.Sprint([]int64{fan, noisy}); got != want {
+				t.Errorf("expected %s, got %s", want, got)
+			}
+		})
+	}
+}
+
+func TestFollowListsPrivate(t *testing.T) {
+	db := newTestDB(t)
+	store := &FollowStore{db: db}
+	ctx := context.Background()
+	page := PaginationQuery{Limit: 20}
+
+	owner := insertUser(t, db, "owner")
+	follower := insertUser(t, db, "follower")
+	requester := insertUser(t, db, "requester")
+	stranger := insertUser(t, db, "stranger")
+
+	mustExec(t, db, `UPDATE users SET is_private = true WHERE id = $1`, owner)
+	mustExec(t, db, `INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)`, owner, follower)
+	mustExec(t, db, `INSERT INTO follow_requests (user_id, requester_id) VALUES ($1, $2)`, owner, requester)
+
+	tests := map[string]struct {
+		viewer  int64
+		visible bool
+	}{
+		"owner":     {owner, true},
+		"follower":  {follower, true},
+		"requester": {requester, false},
+		"stranger":  {stranger, false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			for _, list := range []func(context.Context, int64, int64, PaginationQuery) ([]FollowListEntry, error){store.GetFollowers, store.GetFollowing} {
+				_, err := list(ctx, owner, tt.viewer, page)
+				switch {
+				case tt.visible && err != nil:
+					t.Errorf("expected the lists to be visible, got %v", err)
+				case !tt.visible && err != ErrorNotFound:
+					t.Errorf("expected ErrorNotFound, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestFollowListsCounts(t *testing.T) {
+	db := newTestDB(t)
+	follows := &FollowStore{db: db}
+	users := &UserStore{db: db}
+	ctx := context.Background()
+
+	owner := insertUser(t, db, "owner")
+	first := insertUser(t, db, "first")
+	second := insertUser(t, db, "second")
+	viewer := insertUser(t, db, "viewer")
+
+	for _, follower := range []int64{first, second} {
+		if _, err := follows.Follow(ctx, owner, follower); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := follows.Follow(ctx, first, owner); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := follows.Follow(ctx, second, viewer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := follows.Follow(ctx, owner, first); err != ErrConflict {
+		t.Errorf("expected following twice to be a conflict, got %v", err)
+	}
+
+	t.Run("should page the most recent followers first", func(t *testing.T) {
+		entries, err := follows.GetFollowers(ctx, owner, viewer, PaginationQuery{Limit: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].ID != second || !entries[0].IsFollowing {
+			t.Errorf("expected the viewer to follow the second follower, got %+v", entries)
+		}
+
+		entries, err = follows.GetFollowers(ctx, owner, viewer, PaginationQuery{Limit: 1, Offset: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].ID != first || entries[0].IsFollowing {
+			t.Errorf("expected the viewer not to follow the first follower, got %+v", entries)
+		}
+	})
+
+	t.Run("should count the follows", func(t *testing.T) {
+		profile, err := users.GetProfile(ctx, owner, viewer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if profile.FollowersCount != 2 || profile.FollowingCount != 1 {
+			t.Errorf("expected 2 followers and 1 following, got %d and %d", profile.FollowersCount, profile.FollowingCount)
+		}
+
+		if err := follows.Unfollow(ctx, owner, second); err != nil {
+			t.Fatal(err)
+		}
+
+		profile, err = users.GetProfile(ctx, owner, viewer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if profile.FollowersCount != 1 {
+			t.Errorf("expected 1 follower after unfollowing, got %d", profile.FollowersCount)
+		}
+	})
+}
+
+func TestFollowParallel(t *testing.T) {
+	db := newTestDB(t)
+	follows := &FollowStore{db: db}
+	users := &UserStore{db: db}
+	ctx := context.Background()
+
+	owner := insertUser(t, db, "owner")
+	followers := make([]int64, 10)
+	for i := range followers {
+		followers[i] = insertUser(t, db, fmt.Sprintf("parallel_%d", i))
+	}
+
+	// every follower follows the owner, and the owner follows them back, all
+	// at the same time
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*len(followers))
+	for _, id := range followers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := follows.Follow(ctx, owner, id)
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := follows.Follow(ctx, id, owner)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("expected every follow to go through, got %v", err)
+		}
+	}
+
+	profile, err := users.GetProfile(ctx, owner, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.FollowersCount != int64(len(followers)) || profile.FollowingCount != int64(len(followers)) {
+		t.Errorf("expected %d followers and following, got %d and %d", len(followers), profile.FollowersCount, profile.FollowingCount)
+	}
+}
+
+// requesterIDs returns the ids of the pending follow requests, oldest first.
+func requesterIDs(t *testing.T, store *FollowStore, userID int64) string {
+	t.Helper()
+
+	requests, err := store.GetRequests(context.Background(), userID, PaginationQuery{Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int64{}
+	for _, request := range requests {
+		ids = append(ids, request.RequesterID)
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestFollowRequests(t *testing.T) {
+	db := newTestDB(t)
+	follows := &FollowStore{db: db}
+	users := &UserStore{db: db}
+	posts := &PostStore{db: db}
+	ctx := context.Background()
+
+	owner := insertUser(t, db, "owner")
+	approved := insertUser(t, db, "approved")
+	rejected := insertUser(t, db, "rejected")
+	pending := insertUser(t, db, "pending")
+
+	if err := users.SetPrivacy(ctx, owner, true); err != nil {
+		t.Fatal(err)
+	}
+	post := insertPost(t, db, owner, "public")
+
+	for _, requester := range []int64{approved, rejected, pending} {
+		status, err := follows.Follow(ctx, owner, requester)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != FollowStatusRequested {
+			t.Errorf("expected following a private account to file a request, got %s", status)
+		}
+	}
+	if _, err := follows.Follow(ctx, owner, pending); err != ErrConflict {
+		t.Errorf("expected requesting twice to be a conflict, got %v", err)
+	}
+
+	if got, want := requesterIDs(t, follows, owner), fmt.Sprint([]int64{approved, rejected, pending}); got != want {
+		t.Errorf("expected the requests %s, got %s", want, got)
+	}
+
+	if _, err := posts.GetVisibleByID(ctx, post, approved); err != ErrorNotFound {
+		t.Errorf("expected the post to be hidden from a requester, got %v", err)
+	}
+
+	if err := follows.ApproveRequest(ctx, owner, approved); err != nil {
+		t.Fatal(err)
+	}
+	if err := follows.RejectRequest(ctx, owner, rejected); err != nil {
+		t.Fatal(err)
+	}
+	if err := follows.ApproveRequest(ctx, owner, rejected); err != ErrorNotFound {
+		t.Errorf("expected approving a rejected request to be ErrorNotFound, got %v", err)
+	}
+
+	if _, err := posts.GetVisibleByID(ctx, post, approved); err != nil {
+		t.Errorf("expected the post to be visible once approved, got %v", err)
+	}
+	if _, err := posts.GetVisibleByID(ctx, post, rejected); err != ErrorNotFound {
+		t.Errorf("expected the post to stay hidden once rejected, got %v", err)
+	}
+	if _, err := follows.Follow(ctx, owner, approved); err != ErrConflict {
+		t.Errorf("expected requesting while following to be a conflict, got %v", err)
+	}
+
+	if err := users.SetPrivacy(ctx, owner, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := requesterIDs(t, follows, owner); got != "[]" {
+		t.Errorf("expected going public to approve the pending requests, got %s", got)
+	}
+
+	entries, err := follows.GetFollowers(ctx, owner, owner, PaginationQuery{Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := followListIDs(entries), fmt.Sprint([]int64{approved, pending}); got != want {
+		t.Errorf("expected the followers %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,369 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MaxPostMedia is how many uploads can be attached to a single post.
+const MaxPostMedia = 4
+
+var ErrInvalidMedia = errors.New("media not found or already attached")
+
+const (
+	MediaStatusPending = "pending"
+	MediaStatusReady   = "ready"
+	MediaStatusFailed  = "failed"
+)
+
+// MaxMediaProcessingAttempts is how many runs may fail to process an image
+// before it is marked failed.
+const MaxMediaProcessingAttempts = 5
+
+// Media is an uploaded file, unattached until a post is created with it.
+type Media struct {
+	ID          int64  `json:"id"`
+	UserID      int64  `json:"user_id"`
+	PostID      *int64 `json:"post_id,omitempty"`
+	Kind        string `json:"kind" enums:"image,video"`
+	ContentType string `json:"content_type"`
+	URL         string `json:"url"`
+	Size        int64  `json:"size"`
+	CreatedAt   string `json:"created_at"`
+	// Status is pending until the variants of an image are generated, videos
+	// are ready as uploaded. URL always serves the upload itself.
+	Status   string        `json:"status" enums:"pending,ready,failed"`
+	Width    *int          `json:"width,omitempty"`
+	Height   *int          `json:"height,omitempty"`
+	BlurHash *string       `json:"blurhash,omitempty"`
+	Variants MediaVariants `json:"variants"`
+	// StorageKey is where the blob store keeps the file.
+	StorageKey string `json:"-"`
+	// VariantKeys are where the blob store keeps the variants.
+	VariantKeys []string `json:"-"`
+}
+
+// MediaVariant is a resized copy of an image, such as its thumbnail.
+type MediaVariant struct {
+	URL         string `json:"url"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	ContentType string `json:"content_type"`
+}
+
+// MediaVariants are the variants of an image by name: thumbnail, feed and full.
+type MediaVariants map[string]MediaVariant
+
+func (v *MediaVariants) Scan(src any) error {
+	var data []byte
+	switch s := src.(type) {
+	case nil:
+		*v = MediaVariants{}
+		return nil
+	case []byte:
+		data = s
+	case string:
+		data = []byte(s)
+	default:
+		return fmt.Errorf("cannot scan %T into MediaVariants", src)
+	}
+
+	variants := MediaVariants{}
+	if err := json.Unmarshal(data, &variants); err != nil {
+		return err
+	}
+	*v = variants
+	return nil
+}
+
+// MediaList holds the media of a post, in order, as aggregated by postMediaSQL.
+type MediaList []Media
+
+func (l *MediaList) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*l = MediaList{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into MediaList", src)
+	}
+
+	list := MediaList{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// postMediaSQL aggregates the media attached to a post into a JSON array.
+func postMediaSQL(post string) string {
+	return fmt.Sprintf(`COALESCE((
+			SELECT json_agg(json_build_object(
+				'id', m.id, 'user_id', m.user_id, 'post_id', m.post_id, 'kind', m.kind,
+				'content_type', m.content_type, 'url', m.url, 'size', m.size, 'created_at', m.created_at,
+				'status', m.status, 'width', m.width, 'height', m.height, 'blurhash', m.blurhash, 'variants', m.variants
+			) ORDER BY m.position)
+			FROM media m
+			WHERE m.post_id = %s.id
+		), '[]')`, post)
+}
+
+type MediaStore struct {
+	db *sql.DB
+}
+
+// Create stores an upload, images are left pending for the processing job.
+func (store *MediaStore) Create(ctx context.Context, media *Media) error {
+	query := `
+		INSERT INTO media (user_id, kind, content_type, storage_key, url, size, status)
+		VALUES ($1, $2, $3, $4, $5, $6, CASE WHEN $2 = 'image' THEN 'pending' ELSE 'ready' END)
+		RETURNING id, created_at, status
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return store.db.QueryRowContext(
+		ctx,
+		query,
+		media.UserID,
+		media.Kind,
+		media.ContentType,
+		media.StorageKey,
+		media.URL,
+		media.Size,
+	).Scan(&media.ID, &media.CreatedAt, &media.Status)
+}
+
+// GetPending returns up to limit images waiting to be processed, oldest first
+// after the ones no run failed on yet, so that failing images do not hold the
+// others back.
+func (store *MediaStore) GetPending(ctx context.Context, limit int) ([]Media, error) {
+	query := `
+		SELECT id, user_id, post_id, kind, content_type, storage_key, url, size, created_at, status
+		FROM media
+		WHERE status = 'pending'
+		ORDER BY processing_attempts, created_at
+		LIMIT $1
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pending := []Media{}
+	for rows.Next() {
+		var media Media
+		if err := rows.Scan(
+			&media.ID,
+			&media.UserID,
+			&media.PostID,
+			&media.Kind,
+			&media.ContentType,
+			&media.StorageKey,
+			&media.URL,
+			&media.Size,
+			&media.CreatedAt,
+			&media.Status,
+		); err != nil {
+			return nil, err
+		}
+		pending = append(pending, media)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// SetProcessed records the outcome of processing a pending image: its status,
+// dimensions, placeholder and variants. ErrorNotFound is returned when the
+// upload was deleted or processed in the meantime.
+func (store *MediaStore) SetProcessed(ctx context.Context, media *Media) error {
+	query := `
+		UPDATE media
+		SET status = $2, width = $3, height = $4, blurhash = $5, variants = $6, variant_keys = $7
+		WHERE id = $1 AND status = 'pending'
+		`
+
+	variants, err := json.Marshal(media.Variants)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := store.db.ExecContext(
+		ctx,
+		query,
+		media.ID,
+		media.Status,
+		media.Width,
+		media.Height,
+		media.BlurHash,
+		variants,
+		pq.Array(media.VariantKeys),
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrorNotFound
+	}
+	return nil
+}
+
+// SetAttemptFailed records a run that failed to process a pending image, and
+// marks it failed after MaxMediaProcessingAttempts of them. ErrorNotFound is
+// returned when the upload was deleted or processed in the meantime.
+func (store *MediaStore) SetAttemptFailed(ctx context.Context, id int64) error {
+	query := `
+		UPDATE media
+		SET processing_attempts = processing_attempts + 1,
+			status = CASE WHEN processing_attempts + 1 >= $2 THEN 'failed' ELSE status END
+		WHERE id = $1 AND status = 'pending'
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := store.db.ExecContext(ctx, query, id, MaxMediaProcessingAttempts)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrorNotFound
+	}
+	return nil
+}
+
+// GetOrphans returns up to limit uploads created before the given time that
+// are not attached to any post, either never attached or left behind by a
+// purged post.
+func (store *MediaStore) GetOrphans(ctx context.Context, before time.Time, limit int) ([]Media, error) {
+	query := `
+		SELECT id, user_id, kind, content_type, storage_key, url, size, created_at
+		FROM media
+		WHERE post_id IS NULL AND created_at < $1
+		ORDER BY created_at
+		LIMIT $2
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orphans := []Media{}
+	for rows.Next() {
+		var media Media
+		if err := rows.Scan(
+			&media.ID,
+			&media.UserID,
+			&media.Kind,
+			&media.ContentType,
+			&media.StorageKey,
+			&media.URL,
+			&media.Size,
+			&media.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		orphans = append(orphans, media)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orphans, nil
+}
+
+// Delete removes an upload as long as it is still unattached, so a post
+// created in the meantime keeps its media. The storage keys of the deleted
+// upload and of its variants are returned for the blobs to be deleted too.
+func (store *MediaStore) Delete(ctx context.Context, id int64) ([]string, error) {
+	query := `
+		DELETE FROM media
+		WHERE id = $1 AND post_id IS NULL
+		RETURNING storage_key, variant_keys
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var key string
+	var variantKeys []string
+	err := store.db.QueryRowContext(ctx, query, id).Scan(&key, pq.Array(&variantKeys))
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return append([]string{key}, variantKeys...), nil
+}
+
+// attachMedia attaches uploads of the user to a post in the given order. It
+// fails with ErrInvalidMedia unless every upload exists, belongs to the user
+// and is not attached yet.
+func attachMedia(ctx context.Context, tx *sql.Tx, postID, userID int64, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE media m
+		SET post_id = $1, position = a.position
+		FROM unnest($3::BIGINT[]) WITH ORDINALITY AS a(id, position)
+		WHERE m.id = a.id AND m.user_id = $2 AND m.post_id IS NULL
+		`
+
+	result, err := tx.ExecContext(ctx, query, postID, userID, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected != int64(len(ids)) {
+		return ErrInvalidMedia
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+// mentionPattern matches @username, not when glued to a word as in an email address.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@(\w+)`)
+
+// MentionEntity locates a mention of a known user in the content, so clients
+// can render it as a link. Start and End are offsets in Unicode code points,
+// End excluded, and cover the @ sign.
+type MentionEntity struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+}
+
+// mentionedUsernames returns the distinct usernames mentioned in text, in order.
+func mentionedUsernames(text string) []string {
+	usernames := []string{}
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		if seen[match[1]] {
+			continue
+		}
+		seen[match[1]] = true
+		usernames = append(usernames, match[1])
+	}
+	return usernames
+}
+
+// mentionedUsers are the users a post or comment mentions, as aggregated by
+// mentionedUsersSQL.
+type mentionedUsers []struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+func (m *mentionedUsers) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*m = mentionedUsers{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into mentionedUsers", src)
+	}
+
+	users := mentionedUsers{}
+	if err := json.Unmarshal(data, &users); err != nil {
+		return err
+	}
+	*m = users
+	return nil
+}
+
+// entities locates the mentions of the users in text. Mentions of usernames
+// that are not among them stay plain text.
+func (m mentionedUsers) entities(text string) []MentionEntity {
+	ids := make(map[string]int64, len(m))
+	for _, user := range m {
+		ids[user.Username] = user.ID
+	}
+
+	entities := []MentionEntity{}
+	if len(ids) == 0 {
+		return entities
+	}
+
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		username := text[match[2]:match[3]]
+		id, ok := ids[username]
+		if !ok {
+			continue
+		}
+
+		start := utf8.RuneCountInString(text[:match[2]-1])
+		entities = append(entities, MentionEntity{
+			UserID:   id,
+			Username: username,
+			Start:    start,
+			End:      start + 1 + utf8.RuneCountInString(username),
+		})
+	}
+	return entities
+}
+
+// mentionedUsersSQL aggregates the users recorded in a mentions table for the
+// given row into a JSON array, e.g. mentionedUsersSQL("post_mentions", "post_id", "p.id").
+func mentionedUsersSQL(table, column, id string) string {
+	return fmt.Sprintf(`COALESCE((
+			SELECT json_agg(json_build_object('id', mu.id, 'username', mu.username))
+			FROM %[1]s mt
+			JOIN users mu ON mu.id = mt.user_id
+			WHERE mt.%[2]s = %[3]s
+		), '[]')`, table, column, id)
+}
+
+// setMentions records the users mentioned in content in a mentions table,
+// replacing the previous mentions of the row. Unknown usernames are ignored.
+func setMentions(ctx context.Context, tx *sql.Tx, table, column string, id int64, content string) error {
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s = $1`, table, column), id); err != nil {
+		return err
+	}
+
+	usernames := mentionedUsernames(content)
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (%s, user_id)
+		SELECT $1, u.id
+		FROM users u
+		WHERE u.username = ANY($2)
+		ON CONFLICT DO NOTHING
+		`, table, column)
+
+	_, err := tx.ExecContext(ctx, query, id, pq.Array(usernames))
+	return err
+}
+
+// setPostMentions records the users mentioned in the content of the post.
+func setPostMentions(ctx context.Context, tx *sql.Tx, postID int64, content string) error {
+	return setMentions(ctx, tx, "post_mentions", "post_id", postID, content)
+}
+
+// setCommentMentions records the users mentioned in the content of the comment.
+func setCommentMentions(ctx context.Context, tx *sql.Tx, commentID int64, content string) error {
+	return setMentions(ctx, tx, "comment_mentions", "comment_id", commentID, content)
+}
+
+// notifyPostMentions notifies the users mentioned in the posts, once the posts
+// are live. Users who cannot see a post, blocked or muted its author, or were
+// already notified of it are skipped.
+func notifyPostMentions(ctx context.Context, tx *sql.Tx, postIDs ...int64) error {
+	query := `
+		INSERT INTO notifications (user_id, actor_id, kind, post_id)
+		SELECT pm.user_id, p.user_id, 'mention', p.id
+		FROM post_mentions pm
+		JOIN posts p ON p.id = pm.post_id
+		WHERE p.id = ANY($1)
+			AND pm.user_id <> p.user_id
+			AND ` + livePostSQL("p") + `
+			AND ` + canViewPostSQL("p", "pm.user_id") + `
+			AND ` + notMutedSQL("p.user_id", "pm.user_id") + `
+		ON CONFLICT DO NOTHING
+		`
+
+	_, err := tx.ExecContext(ctx, query, pq.Array(postIDs))
+	return err
+}
+
+// notifyCommentMentions notifies the users mentioned in the comment, like
+// notifyPostMentions.
+func notifyCommentMentions(ctx context.Context, tx *sql.Tx, commentID int64) error {
+	query := `
+		INSERT INTO notifications (user_id, actor_id, kind, post_id, comment_id)
+		SELECT cm.user_id, c.user_id, 'mention', c.post_id, c.id
+		FROM comment_mentions cm
+		JOIN comments c ON c.id = cm.comment_id
+		JOIN posts p ON p.id = c.post_id
+		WHERE c.id = $1
+			AND cm.user_id <> c.user_id
+			AND c.deleted_at IS NULL
+			AND ` + livePostSQL("p") + `
+			AND ` + canViewPostSQL("p", "cm.user_id") + `
+			AND ` + notBlockedSQL("c.user_id", "cm.user_id") + `
+			AND ` + notMutedSQL("c.user_id", "cm.user_id") + `
+		ON CONFLICT DO NOTHING
+		`
+
+	_, err := tx.ExecContext(ctx, query, commentID)
+	return err
+}
@@ -0,0 +1,269 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMentionEntities(t *testing.T) {
+	users := mentionedUsers{
+		{ID: 1, Username: "alice"},
+		{ID: 2, Username: "bob_2"},
+	}
+
+	tests := map[string]struct {
+		text string
+		want []MentionEntity
+	}{
+		"plain text": {
+			text: "hi @alice",
+			want: []MentionEntity{{UserID: 1, Username: "alice", Start: 3, End: 9}},
+		},
+		"at the start": {
+			text: "@bob_2, look",
+			want: []MentionEntity{{UserID: 2, Username: "bob_2", Start: 0, End: 6}},
+		},
+		"after non-ASCII text": {
+			text: "héllo 🐹 @alice!",
+			want: []MentionEntity{{UserID: 1, Username: "alice", Start: 8, End: 14}},
+		},
+		"twice": {
+			text: "@alice and @alice",
+			want: []MentionEntity{
+				{UserID: 1, Username: "alice", Start: 0, End: 6},
+				{UserID: 1, Username: "alice", Start: 11, End: 17},
+			},
+		},
+		"unknown username": {
+			text: "@carol and @alice",
+			want: []MentionEntity{{UserID: 1, Username: "alice", Start: 11, End: 17}},
+		},
+		"email address": {
+			text: "write to a@alice.com",
+			want: []MentionEntity{},
+		},
+		"other case": {
+			text: "@Alice",
+			want: []MentionEntity{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := users.entities(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("no users", func(t *testing.T) {
+		if got := (mentionedUsers{}).entities("hi @alice"); got == nil || len(got) != 0 {
+			t.Errorf("expected no entities, got %+v", got)
+		}
+	})
+}
+
+func TestMentionNotifications(t *testing.T) {
+	db := newTestDB(t)
+	posts := &PostStore{db: db}
+	comments := &CommentStore{db: db}
+	ctx := context.Background()
+
+	author := insertUser(t, db, "author")
+	mentioned := insertUser(t, db, "mentioned")
+	blocker := insertUser(t, db, "blocker")
+	muter := insertUser(t, db, "muter")
+
+	mustExec(t, db, `INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)`, blocker, author)
+	mustExec(t, db, `INSERT INTO user_mutes (muter_id, muted_id) VALUES ($1, $2)`, muter, author)
+
+	notified := func(t *testing.T, userID, postID int64) int {
+		t.Helper()
+
+		var count int
+		err := db.QueryRow(
+			`SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND post_id = $2 AND kind = 'mention'`,
+			userID,
+			postID,
+		).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	create := func(t *testing.T, post *Post) *Post {
+		t.Helper()
+
+		post.UserID = author
+		post.Title = "title"
+		post.Tags = []string{}
+		response, err := posts.Create(ctx, post)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		created, err := posts.GetByID(ctx, response.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return created
+	}
+
+	t.Run("should not notify of a draft until it is published", func(t *testing.T) {
+		post := create(t, &Post{Content: "hi @mentioned", Status: PostStatusDraft})
+
+		if n := notified(t, mentioned, post.ID); n != 0 {
+			t.Fatalf("expected no notification of the draft, got %d", n)
+		}
+
+		post.Status = PostStatusPublished
+		if err := posts.Update(ctx, post, author); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := notified(t, mentioned, post.ID); n != 1 {
+			t.Errorf("expected a notification once published, got %d", n)
+		}
+	})
+
+	t.Run("should notify of a scheduled post once it is published", func(t *testing.T) {
+		publishAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		post := create(t, &Post{Content: "hi @mentioned", Status: PostStatusScheduled, PublishAt: &publishAt})
+
+		if n := notified(t, mentioned, post.ID); n != 0 {
+			t.Fatalf("expected no notification of the scheduled post, got %d", n)
+		}
+
+		if _, err := posts.PublishScheduled(ctx, time.Now().Add(2*time.Hour), 100); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := notified(t, mentioned, post.ID); n != 1 {
+			t.Errorf("expected a notification once published, got %d", n)
+		}
+	})
+
+	t.Run("should notify once per post", func(t *testing.T) {
+		post := create(t, &Post{Content: "hi @mentioned"})
+
+		post.Content = "hello again @mentioned"
+		if err := posts.Update(ctx, post, author); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := notified(t, mentioned, post.ID); n != 1 {
+			t.Errorf("expected a single notification, got %d", n)
+		}
+	})
+
+	t.Run("should skip users who blocked or muted the author", func(t *testing.T) {
+		post := create(t, &Post{Content: "hi @blocker and @muter"})
+
+		if n := notified(t, blocker, post.ID); n != 0 {
+			t.Errorf("expected no notification of the blocker, got %d", n)
+		}
+		if n := notified(t, muter, post.ID); n != 0 {
+			t.Errorf("expected no notification of the muter, got %d", n)
+		}
+	})
+
+	t.Run("should skip users who cannot see the post", func(t *testing.T) {
+		post := create(t, &Post{Content: "hi @mentioned", Visibility: PostVisibilityFollowers})
+
+		if n := notified(t, mentioned, post.ID); n != 0 {
+			t.Errorf("expected no notification, got %d", n)
+		}
+	})
+
+	t.Run("should notify of a comment apart from its post", func(t *testing.T) {
+		post := create(t, &Post{Content: "hi @mentioned"})
+
+		comment := &Comment{PostID: post.ID, UserID: author, Content: "and @mentioned again, @blocker"}
+		if err := comments.Create(ctx, comment); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := notified(t, mentioned, post.ID); n != 2 {
+			t.Errorf("expected a notification of the post and one of the comment, got %d", n)
+		}
+		if n := notified(t, blocker, post.ID); n != 0 {
+			t.Errorf("expected no notification of the blocker, got %d", n)
+		}
+	})
+}
+
+func TestMentionListings(t *testing.T) {
+	db := newTestDB(t)
+	posts := &PostStore{db: db}
+	bookmarks := &BookmarkStore{db: db}
+	trends := &TrendStore{db: db}
+	ctx := context.Background()
+
+	author := insertUser(t, db, "author")
+	mentioned := insertUser(t, db, "mentioned")
+	want := []MentionEntity{{UserID: mentioned, Username: "mentioned", Start: 3, End: 13}}
+
+	create := func(t *testing.T, status string) int64 {
+		t.Helper()
+
+		response, err := posts.Create(ctx, &Post{UserID: author, Title: "title", Content: "hi @mentioned", Tags: []string{}, Status: status})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response.ID
+	}
+
+	published := create(t, PostStatusPublished)
+	mustExec(t, db, `INSERT INTO post_reactions (post_id, user_id, reaction) VALUES ($1, $2, 'like')`, published, author)
+
+	t.Run("bookmarks", func(t *testing.T) {
+		if _, err := bookmarks.Save(ctx, author, published, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := bookmarks.List(ctx, author, BookmarkQuery{CursorQuery: CursorQuery{Limit: 20}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 {
+			t.Fatalf("expected a bookmark, got %d", len(list))
+		}
+		if got := list[0].Post; !reflect.DeepEqual(got.Mentions, want) || !reflect.DeepEqual(got.MyReactions, []string{"like"}) {
+			t.Errorf("expected the mentions and reactions, got %+v", got)
+		}
+	})
+
+	t.Run("trends", func(t *testing.T) {
+		mustExec(t, db, `
+			INSERT INTO trending_posts (time_window, post_id, score, rank, computed_at)
+			VALUES ('24h', $1, 1, 1, now())
+			`, published)
+
+		list, err := trends.GetPosts(ctx, TrendQuery{Window: "24h", Limit: 10}, author)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 {
+			t.Fatalf("expected a trending post, got %d", len(list))
+		}
+		got := list[0].Post
+		if !reflect.DeepEqual(got.Mentions, want) || !reflect.DeepEqual(got.MyReactions, []string{"like"}) || got.ReactionCounts["like"] != 1 {
+			t.Errorf("expected the mentions and reactions, got %+v", got)
+		}
+	})
+
+	t.Run("drafts", func(t *testing.T) {
+		create(t, PostStatusDraft)
+
+		list, err := posts.GetDrafts(ctx, author, PaginationQuery{Limit: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 || !reflect.DeepEqual(list[0].Mentions, want) {
+			t.Errorf("expected a draft with its mentions, got %+v", list)
+		}
+	})
+}
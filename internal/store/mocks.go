@@ -0,0 +1,487 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// NewMockStore returns a Storage keeping users, posts and comments in memory,
+// for handler tests. Only the stores the tests go through are set.
+func NewMockStore() Storage {
+	return Storage{
+		Post:    &MockPostStore{Posts: make(map[int64]*Post)},
+		User:    &MockUserStore{Users: make(map[int64]*User)},
+		Comment: &MockCommentStore{},
+		Roles:   &MockRoleStore{},
+		Media:   &MockMediaStore{},
+		Trash:   &MockTrashStore{},
+		Search:  &MockSearchStore{},
+		Polls:   &MockPollStore{Polls: make(map[int64]*Poll)},
+	}
+}
+
+// MockPostStore serves Posts. A post is visible to its author, and to everyone
+// else unless its ID is in Hidden.
+type MockPostStore struct {
+	Posts  map[int64]*Post
+	Hidden map[int64]bool
+}
+
+func (m *MockPostStore) visible(post *Post, viewerID int64) bool {
+	return post.UserID == viewerID || !m.Hidden[post.ID]
+}
+
+func (m *MockPostStore) Create(ctx context.Context, post *Post) (*CreatePostResponse, error) {
+	post.ID = int64(len(m.Posts) + 1)
+	m.Posts[post.ID] = post
+	return &CreatePostResponse{ID: post.ID}, nil
+}
+
+func (m *MockPostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
+	post, ok := m.Posts[id]
+	if !ok {
+		return nil, ErrorNotFound
+	}
+	return post, nil
+}
+
+func (m *MockPostStore) GetVisibleByID(ctx context.Context, id, viewerID int64) (*Post, error) {
+	post, ok := m.Posts[id]
+	if !ok || !m.visible(post, viewerID) {
+		return nil, ErrorNotFound
+	}
+	return post, nil
+}
+
+func (m *MockPostStore) Repost(ctx context.Context, postID, userID int64) (*Post, error) {
+	return nil, ErrorNotFound
+}
+
+func (m *MockPostStore) Quote(ctx context.Context, postID int64, post *Post) error {
+	return ErrorNotFound
+}
+
+func (m *MockPostStore) DeleteRepost(ctx context.Context, postID, userID int64) (int64, error) {
+	return 0, ErrorNotFound
+}
+
+func (m *MockPostStore) checkVersion(id, version int64) error {
+	post, ok := m.Posts[id]
+	if !ok {
+		return ErrorNotFound
+	}
+	if post.Version != version {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+func (m *MockPostStore) Delete(ctx context.Context, id, version, deletedBy int64) error {
+	if err := m.checkVersion(id, version); err != nil {
+		return err
+	}
+	delete(m.Posts, id)
+	return nil
+}
+
+func (m *MockPostStore) Update(ctx context.Context, post *Post, editorID int64) error {
+	if err := m.checkVersion(post.ID, post.Version); err != nil {
+		return err
+	}
+	post.Version++
+	m.Posts[post.ID] = post
+	return nil
+}
+
+func (m *MockPostStore) GetRevisions(ctx context.Context, postID int64, page PaginationQuery) ([]PostRevision, error) {
+	return []PostRevision{}, nil
+}
+
+func (m *MockPostStore) GetRevision(ctx context.Context, postID, version int64) (*PostRevision, error) {
+	return nil, ErrorNotFound
+}
+
+// RestoreRevision knows no revisions, it only bumps the version of the post.
+func (m *MockPostStore) RestoreRevision(ctx context.Context, post *Post, version, editorID int64) error {
+	if err := m.checkVersion(post.ID, post.Version); err != nil {
+		return err
+	}
+	post.Version++
+	return nil
+}
+
+func (m *MockPostStore) GetByUserId(ctx context.Context, userID int64, fq PaginationFeedQuery) ([]*PostWithMetadata, error) {
+	return []*PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) SetModerated(ctx context.Context, postID, moderatorID int64, moderated bool) error {
+	return nil
+}
+
+func (m *MockPostStore) GetDrafts(ctx context.Context, userID int64, page PaginationQuery) ([]Post, error) {
+	posts := []Post{}
+	for _, post := range m.Posts {
+		if post.UserID == userID && post.Status != PostStatusPublished {
+			posts = append(posts, *post)
+		}
+	}
+	return posts, nil
+}
+
+func (m *MockPostStore) PublishScheduled(ctx context.Context, now time.Time, limit int) ([]*Post, error) {
+	return []*Post{}, nil
+}
+
+type MockUserStore struct {
+	Users map[int64]*User
+}
+
+func (m *MockUserStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
+	user.ID = int64(len(m.Users) + 1)
+	m.Users[user.ID] = user
+	return nil
+}
+
+func (m *MockUserStore) GetById(ctx context.Context, id int64) (*User, error) {
+	user, ok := m.Users[id]
+	if !ok {
+		return nil, ErrorNotFound
+	}
+	return user, nil
+}
+
+func (m *MockUserStore) GetProfile(ctx context.Context, id, viewerID int64) (*UserProfile, error) {
+	user, err := m.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &UserProfile{User: *user}, nil
+}
+
+func (m *MockUserStore) CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration) error {
+	return m.Create(ctx, nil, user)
+}
+
+func (m *MockUserStore) Activate(ctx context.Context, token string) (*User, error) {
+	return nil, ErrorNotFound
+}
+
+func (m *MockUserStore) Delete(ctx context.Context, id int64) error {
+	delete(m.Users, id)
+	return nil
+}
+
+func (m *MockUserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	for _, user := range m.Users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, ErrorNotFound
+}
+
+func (m *MockUserStore) SetPrivacy(ctx context.Context, id int64, private bool) error {
+	user, err := m.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	user.IsPrivate = private
+	return nil
+}
+
+// MockCommentStore keeps the created comments in order.
+type MockCommentStore struct {
+	Comments []*Comment
+}
+
+func (m *MockCommentStore) GetByID(ctx context.Context, postID, commentID, viewerID int64) (*Comment, error) {
+	for _, comment := range m.Comments {
+		if comment.ID == commentID && comment.PostID == postID && !comment.Deleted {
+			return comment, nil
+		}
+	}
+	return nil, ErrorNotFound
+}
+
+// GetShownByID also returns deleted comments that have live replies.
+func (m *MockCommentStore) GetShownByID(ctx context.Context, postID, commentID, viewerID int64) (*Comment, error) {
+	for _, comment := range m.Comments {
+		if comment.ID != commentID || comment.PostID != postID {
+			continue
+		}
+		if !comment.Deleted || m.hasReplies(comment.ID) {
+			return comment, nil
+		}
+	}
+	return nil, ErrorNotFound
+}
+
+func (m *MockCommentStore) hasReplies(commentID int64) bool {
+	for _, comment := range m.Comments {
+		if comment.ParentCommentID != nil && *comment.ParentCommentID == commentID && !comment.Deleted {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MockCommentStore) GetCommentByPostId(ctx context.Context, postID, viewerID int64, cq CursorQuery) ([]*Comment, error) {
+	return m.list(postID, nil, cq), nil
+}
+
+func (m *MockCommentStore) GetReplies(ctx context.Context, postID, commentID, viewerID int64, cq CursorQuery) ([]*Comment, error) {
+	return m.list(postID, &commentID, cq), nil
+}
+
+func (m *MockCommentStore) GetLiveByPostID(ctx context.Context, postID int64) ([]*Comment, error) {
+	comments := []*Comment{}
+	for _, comment := range m.Comments {
+		if comment.PostID == postID && !comment.Deleted {
+			comments = append(comments, comment)
+		}
+	}
+	return comments, nil
+}
+
+func (m *MockCommentStore) list(postID int64, parentID *int64, cq CursorQuery) []*Comment {
+	comments := []*Comment{}
+	for _, comment := range m.Comments {
+		if int64(len(comments)) == cq.Limit {
+			break
+		}
+		if comment.PostID != postID || comment.ID <= cq.Cursor {
+			continue
+		}
+		if (parentID == nil) != (comment.ParentCommentID == nil) {
+			continue
+		}
+		if parentID != nil && *parentID != *comment.ParentCommentID {
+			continue
+		}
+		comments = append(comments, comment)
+	}
+	return comments
+}
+
+func (m *MockCommentStore) Create(ctx context.Context, comment *Comment) error {
+	if comment.ParentCommentID != nil {
+		if _, err := m.GetByID(ctx, comment.PostID, *comment.ParentCommentID, comment.UserID); err != nil {
+			return err
+		}
+	}
+
+	comment.ID = int64(len(m.Comments) + 1)
+	comment.CreatedAt = time.Now().Format(time.RFC3339)
+	m.Comments = append(m.Comments, comment)
+	return nil
+}
+
+func (m *MockCommentStore) Update(ctx context.Context, comment *Comment) error {
+	editedAt := time.Now().Format(time.RFC3339)
+	comment.EditedAt = &editedAt
+	return nil
+}
+
+func (m *MockCommentStore) Delete(ctx context.Context, id, deletedBy int64) error {
+	for _, comment := range m.Comments {
+		if comment.ID == id && !comment.Deleted {
+			comment.Deleted = true
+			return nil
+		}
+	}
+	return ErrorNotFound
+}
+
+// MockTrashStore keeps Items in the trash until they are restored, whatever
+// the retention.
+type MockTrashStore struct {
+	Items []*TrashItem
+}
+
+func (m *MockTrashStore) List(ctx context.Context, userID int64, since time.Time, page PaginationQuery) ([]TrashItem, error) {
+	items := []TrashItem{}
+	for _, item := range m.Items {
+		if item.UserID == userID {
+			items = append(items, *item)
+		}
+	}
+	return items, nil
+}
+
+func (m *MockTrashStore) Get(ctx context.Context, kind string, id int64, since time.Time) (*TrashItem, error) {
+	for _, item := range m.Items {
+		if item.Kind == kind && item.ID == id {
+			return item, nil
+		}
+	}
+	return nil, ErrorNotFound
+}
+
+func (m *MockTrashStore) Restore(ctx context.Context, kind string, id int64, since time.Time) error {
+	for i, item := range m.Items {
+		if item.Kind == kind && item.ID == id {
+			m.Items = append(m.Items[:i], m.Items[i+1:]...)
+			return nil
+		}
+	}
+	return ErrorNotFound
+}
+
+func (m *MockTrashStore) Purge(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return 0, nil
+}
+
+// MockSearchStore finds nothing on its own, it hides the results whose
+// "type:id" is in Hidden from the hits of another backend.
+type MockSearchStore struct {
+	Hidden map[string]bool
+}
+
+func (m *MockSearchStore) Search(ctx context.Context, sq SearchQuery) ([]SearchResult, error) {
+	return []SearchResult{}, nil
+}
+
+func (m *MockSearchStore) Visible(ctx context.Context, results []SearchResult, viewerID int64) ([]SearchResult, error) {
+	kept := []SearchResult{}
+	for _, result := range results {
+		if !m.Hidden[fmt.Sprintf("%s:%d", result.Type, result.ID)] {
+			kept = append(kept, result)
+		}
+	}
+	return kept, nil
+}
+
+// MockPollStore keeps the polls by post ID, with the tallies counted in. A
+// vote marks the poll as voted whoever the voter is.
+type MockPollStore struct {
+	Polls  map[int64]*Poll
+	voters map[int64]map[int64]bool
+}
+
+func (m *MockPollStore) Vote(ctx context.Context, postID, userID int64, optionIDs []int64) error {
+	poll, ok := m.Polls[postID]
+	if !ok {
+		return ErrorNotFound
+	}
+	if poll.Closed {
+		return ErrPollClosed
+	}
+	if len(optionIDs) == 0 || (!poll.Multiple && len(optionIDs) > 1) {
+		return ErrInvalidVote
+	}
+	if m.voters[postID][userID] {
+		return ErrConflict
+	}
+
+	options := make(map[int64]*PollOption)
+	for i := range poll.Options {
+		options[poll.Options[i].ID] = &poll.Options[i]
+	}
+	for _, id := range optionIDs {
+		if options[id] == nil {
+			return ErrInvalidVote
+		}
+	}
+
+	if m.voters == nil {
+		m.voters = make(map[int64]map[int64]bool)
+	}
+	if m.voters[postID] == nil {
+		m.voters[postID] = make(map[int64]bool)
+	}
+	m.voters[postID][userID] = true
+
+	for _, id := range optionIDs {
+		count := int64(1)
+		if votes := options[id].VotesCount; votes != nil {
+			count += *votes
+		}
+		options[id].VotesCount = &count
+	}
+	poll.Voted = true
+	poll.MyVotes = optionIDs
+	return nil
+}
+
+func (m *MockPollStore) Close(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+// MockRoleStore knows the seeded roles.
+type MockRoleStore struct{}
+
+func (m *MockRoleStore) GetByName(ctx context.Context, name string) (*Role, error) {
+	switch name {
+	case "user":
+		return &Role{ID: 1, Name: name, Level: 1}, nil
+	case "moderator":
+		return &Role{ID: 2, Name: name, Level: 2}, nil
+	case "admin":
+		return &Role{ID: 3, Name: name, Level: 3}, nil
+	default:
+		return nil, ErrorNotFound
+	}
+}
+
+// MockMediaStore keeps the uploads in Media, images pending until processed.
+// Attempts counts the failed runs per upload.
+type MockMediaStore struct {
+	Media    []*Media
+	Attempts map[int64]int
+}
+
+func (m *MockMediaStore) Create(ctx context.Context, media *Media) error {
+	media.ID = int64(len(m.Media) + 1)
+	media.Status = MediaStatusReady
+	if media.Kind == "image" {
+		media.Status = MediaStatusPending
+	}
+	m.Media = append(m.Media, media)
+	return nil
+}
+
+func (m *MockMediaStore) GetOrphans(ctx context.Context, before time.Time, limit int) ([]Media, error) {
+	return []Media{}, nil
+}
+
+func (m *MockMediaStore) GetPending(ctx context.Context, limit int) ([]Media, error) {
+	pending := []Media{}
+	for _, media := range m.Media {
+		if media.Status == MediaStatusPending && len(pending) < limit {
+			pending = append(pending, *media)
+		}
+	}
+	return pending, nil
+}
+
+func (m *MockMediaStore) SetProcessed(ctx context.Context, media *Media) error {
+	for i, stored := range m.Media {
+		if stored.ID == media.ID && stored.Status == MediaStatusPending {
+			m.Media[i] = media
+			return nil
+		}
+	}
+	return ErrorNotFound
+}
+
+func (m *MockMediaStore) SetAttemptFailed(ctx context.Context, id int64) error {
+	for _, stored := range m.Media {
+		if stored.ID == id && stored.Status == MediaStatusPending {
+			if m.Attempts == nil {
+				m.Attempts = make(map[int64]int)
+			}
+			m.Attempts[id]++
+			if m.Attempts[id] >= MaxMediaProcessingAttempts {
+				stored.Status = MediaStatusFailed
+			}
+			return nil
+		}
+	}
+	return ErrorNotFound
+}
+
+func (m *MockMediaStore) Delete(ctx context.Context, id int64) ([]string, error) {
+	return nil, ErrorNotFound
+}
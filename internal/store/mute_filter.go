@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	MuteFilterKindWord   = "word"
+	MuteFilterKindPhrase = "phrase"
+	MuteFilterKindTag    = "tag"
+
+	// MuteActionHide drops matching items from results.
+	MuteActionHide = "hide"
+	// MuteActionWarn keeps matching items but marks them with the filter.
+	MuteActionWarn = "warn"
+)
+
+type MuteFilter struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Kind      string     `json:"kind"`
+	Value     string     `json:"value"`
+	WholeWord bool       `json:"whole_word"`
+	Action    string     `json:"action"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt string     `json:"created_at"`
+}
+
+// MuteFilterMatch tells which filter marked an item as hidden.
+type MuteFilterMatch struct {
+	ID    int64  `json:"id"`
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+// NormalizeMuteFilterValue lowercases the value and collapses whitespace.
+// Tags are normalized like post tags, see NormalizeTag, so they keep matching.
+func NormalizeMuteFilterValue(kind, value string) string {
+	if kind == MuteFilterKindTag {
+		return NormalizeTag(value)
+	}
+	return strings.ToLower(whitespace.ReplaceAllString(strings.TrimSpace(value), " "))
+}
+
+// muteFilterPattern builds the case insensitive regular expression matched
+// against text. Tags match as #tag, phrases tolerate any run of whitespace
+// between their words.
+func muteFilterPattern(kind, value string, wholeWord bool) string {
+	words := strings.Fields(value)
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	pattern := strings.Join(words, `\s+`)
+
+	if kind == MuteFilterKindTag {
+		return `(^|[^[:alnum:]_])#` + pattern + `($|[^[:alnum:]_])`
+	}
+	if wholeWord {
+		return `(^|[^[:alnum:]_])` + pattern + `($|[^[:alnum:]_])`
+	}
+	return pattern
+}
+
+// muteFilterJoinSQL returns a lateral join exposing, as mf, the active filter
+// of the viewer matching the given text and tags, preferring hide filters.
+// Callers drop rows with mf.action = 'hide' and report the others as hidden.
+//
+// All arguments are SQL expressions, e.g. muteFilterJoinSQL("$1", "c.content", "'{}'::TEXT[]").
+func muteFilterJoinSQL(viewer, text, tags string) string {
+	return fmt.Sprintf(`LEFT JOIN LATERAL (
+			SELECT f.id, f.kind, f.value, f.action
+			FROM mute_filters f
+			WHERE f.user_id = %[1]s
+				AND (f.expires_at IS NULL OR f.expires_at > now())
+				AND (
+					%[2]s ~* f.pattern
+					OR (f.kind = 'tag' AND EXISTS (SELECT 1 FROM unnest(%[3]s) AS ft(tag) WHERE ft.tag = f.value))
+				)
+			ORDER BY f.action = 'hide' DESC, f.id
+			LIMIT 1
+		) mf ON true`, viewer, text, tags)
+}
+
+const notHiddenByFilterSQL = `mf.action IS DISTINCT FROM 'hide'`
+
+// muteFilterColumns scans the mf.id, mf.kind and mf.value columns of a
+// muteFilterJoinSQL join.
+type muteFilterColumns struct {
+	id    sql.NullInt64
+	kind  sql.NullString
+	value sql.NullString
+}
+
+func (c *muteFilterColumns) dest() []any {
+	return []any{&c.id, &c.kind, &c.value}
+}
+
+func (c *muteFilterColumns) match() *MuteFilterMatch {
+	if !c.id.Valid {
+		return nil
+	}
+	return &MuteFilterMatch{ID: c.id.Int64, Kind: c.kind.String, Value: c.value.String}
+}
+
+type MuteFilterStore struct {
+	db *sql.DB
+}
+
+func (store *MuteFilterStore) Create(ctx context.Context, filter *MuteFilter) error {
+	query := `
+		INSERT INTO mute_filters (user_id, kind, value, pattern, whole_word, action, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	filter.Value = NormalizeMuteFilterValue(filter.Kind, filter.Value)
+
+	err := store.db.QueryRowContext(
+		ctx,
+		query,
+		filter.UserID,
+		filter.Kind,
+		filter.Value,
+		muteFilterPattern(filter.Kind, filter.Value, filter.WholeWord),
+		filter.WholeWord,
+		filter.Action,
+		filter.ExpiresAt,
+	).Scan(
+		&filter.ID,
+		&filter.CreatedAt,
+	)
+	if err := constraintError(err); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (store *MuteFilterStore) Delete(ctx context.Context, userID, filterID int64) error {
+	query := `
+		DELETE FROM mute_filters
+		WHERE id = $1 AND user_id = $2
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := store.db.ExecContext(ctx, query, filterID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// GetByUserID lists the filters of a user, expired ones included so they can
+// be renewed or removed.
+func (store *MuteFilterStore) GetByUserID(ctx context.Context, userID int64) ([]MuteFilter, error) {
+	query := `
+		SELECT id, user_id, kind, value, whole_word, action, expires_at, created_at
+		FROM mute_filters
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	filters := []MuteFilter{}
+	for rows.Next() {
+		var filter MuteFilter
+		if err := rows.Scan(
+			&filter.ID,
+			&filter.UserID,
+			&filter.Kind,
+			&filter.Value,
+			&filter.WholeWord,
+			&filter.Action,
+			&filter.ExpiresAt,
+			&filter.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return filters, nil
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestNormalizeMuteFilterValue(t *testing.T) {
+	tests := map[string]struct {
+		kind  string
+		value string
+		want  string
+	}{
+		"word":               {MuteFilterKindWord, " Spoiler ", "spoiler"},
+		"phrase":             {MuteFilterKindPhrase, "Season   Finale", "season finale"},
+		"tag":                {MuteFilterKindTag, "#Café", "café"},
+		"compatibility form": {MuteFilterKindTag, "ＧＯ", "go"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := NormalizeMuteFilterValue(tt.kind, tt.value); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMuteFilterPattern(t *testing.T) {
+	tests := map[string]struct {
+		kind      string
+		value     string
+		wholeWord bool
+		text      string
+		want      bool
+	}{
+		"word":                 {MuteFilterKindWord, "spoiler", false, "Spoilers ahead", true},
+		"whole word":           {MuteFilterKindWord, "spoiler", true, "a spoiler!", true},
+		"whole word in a word": {MuteFilterKindWord, "spoiler", true, "spoilers ahead", false},
+		"phrase":               {MuteFilterKindPhrase, "season finale", false, "the Season\n  finale", true},
+		"phrase out of order":  {MuteFilterKindPhrase, "season finale", false, "finale of the season", false},
+		"quoted":               {MuteFilterKindWord, "c++", false, "learning C++", true},
+		"tag":                  {MuteFilterKindTag, "go", false, "written in #Go.", true},
+		"tag without its hash": {MuteFilterKindTag, "go", false, "let's go", false},
+		"tag prefix of a tag":  {MuteFilterKindTag, "go", false, "#golang", false},
+		"hash inside a word":   {MuteFilterKindTag, "go", false, "a#go", false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			pattern := regexp.MustCompile(`(?i)` + muteFilterPattern(tt.kind, tt.value, tt.wholeWord))
+			if got := pattern.MatchString(tt.text); got != tt.want {
+				t.Errorf("expected %q to match %q: %v, got %v", pattern, tt.text, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMuteFilters(t *testing.T) {
+	db := newTestDB(t)
+	filters := &MuteFilterStore{db: db}
+	posts := &PostStore{db: db}
+	comments := &CommentStore{db: db}
+	ctx := context.Background()
+
+	reader := insertUser(t, db, "reader")
+	author := insertUser(t, db, "author")
+	mustExec(t, db, `INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)`, author, reader)
+
+	insertContent := func(content, tags string) int64 {
+		return insertID(t, db, `
+			INSERT INTO posts (user_id, title, content, tags, published_at)
+			VALUES ($1, 'title', $2, $3, now())
+			RETURNING id
+			`, author, content, tags)
+	}
+
+	warned := insertContent("the season  finale was great", "{}")
+	partial := insertContent("spoilers ahead", "{}")
+	insertContent("a big spoiler here", "{}")
+	insertContent("content", "{go}")
+	expired := insertContent("old news", "{}")
+
+	past := time.Now().Add(-time.Hour)
+	for _, filter := range []*MuteFilter{
+		{UserID: reader, Kind: MuteFilterKindPhrase, Value: "Season Finale", Action: MuteActionWarn},
+		{UserID: reader, Kind: MuteFilterKindWord, Value: "spoiler", WholeWord: true, Action: MuteActionHide},
+		{UserID: reader, Kind: MuteFilterKindTag, Value: "#Go", Action: MuteActionHide},
+		{UserID: reader, Kind: MuteFilterKindWord, Value: "old", Action: MuteActionHide, ExpiresAt: &past},
+	} {
+		if err := filters.Create(ctx, filter); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("should hide or warn about the posts of the feed", func(t *testing.T) {
+		feed, err := posts.GetByUserId(ctx, reader, PaginationFeedQuery{Limit: 20, Sort: "desc"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := map[int64]string{}
+		for _, post := range feed {
+			got[post.ID] = ""
+			if post.HiddenBy != nil {
+				got[post.ID] = post.HiddenBy.Value
+			}
+		}
+		want := map[int64]string{warned: "season finale", partial: "", expired: ""}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("should hide comments", func(t *testing.T) {
+		post := insertPost(t, db, reader, "public")
+		insertID(t, db, `
+			INSERT INTO comments (post_id, user_id, content)
+			VALUES ($1, $2, 'no spoiler please')
+			RETURNING id
+			`, post, author)
+		kept := insertComment(t, db, post, author)
+
+		thread, err := comments.GetCommentByPostId(ctx, post, reader, CursorQuery{Limit: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := commentIDs(thread), fmt.Sprint([]int64{kept}); got != want {
+			t.Errorf("expected the comments %s, got %s", want, got)
+		}
+	})
+
+	t.Run("should not filter for other users", func(t *testing.T) {
+		feed, err := posts.GetByUserId(ctx, author, PaginationFeedQuery{Limit: 20, Sort: "desc"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(feed) != 5 {
+			t.Errorf("expected the 5 posts, got %d", len(feed))
+		}
+	})
+
+	t.Run("should conflict with an existing filter", func(t *testing.T) {
+		filter := &MuteFilter{UserID: reader, Kind: MuteFilterKindWord, Value: " SPOILER ", Action: MuteActionWarn}
+		if err := filters.Create(ctx, filter); err != ErrConflict {
+			t.Errorf("expected ErrConflict, got %v", err)
+		}
+	})
+}
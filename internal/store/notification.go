@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+const NotificationKindMention = "mention"
+
+// Notification tells a user about something another user did, such as
+// mentioning them in a post or, when CommentID is set, in a comment.
+type Notification struct {
+	ID        int64  `json:"id"`
+	Kind      string `json:"kind" enums:"mention"`
+	Actor     User   `json:"actor"`
+	PostID    int64  `json:"post_id"`
+	CommentID *int64 `json:"comment_id,omitempty"`
+	Read      bool   `json:"read"`
+	CreatedAt string `json:"created_at"`
+}
+
+type NotificationStore struct {
+	db *sql.DB
+}
+
+// List returns the notifications of a user, newest first. Notifications about
+// posts or comments the user can no longer see, or from users they blocked,
+// were blocked by or muted, are skipped.
+func (store *NotificationStore) List(ctx context.Context, userID int64, cq CursorQuery) ([]Notification, error) {
+	query := `
+		SELECT n.id, n.kind, n.post_id, n.comment_id, n.read_at IS NOT NULL, n.created_at, u.id, u.username
+		FROM notifications n
+		JOIN users u ON u.id = n.actor_id
+		JOIN posts p ON p.id = n.post_id
+		LEFT JOIN comments c ON c.id = n.comment_id
+		WHERE n.user_id = $1
+			AND (n.id < $2 OR $2 = 0)
+			AND (n.comment_id IS NULL OR c.deleted_at IS NULL)
+			AND ` + livePostSQL("p") + `
+			AND ` + canViewPostSQL("p", "$1") + `
+			AND ` + notBlockedSQL("n.actor_id", "$1") + `
+			AND ` + notMutedSQL("n.actor_id", "$1") + `
+		ORDER BY n.id DESC
+		LIMIT $3
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, userID, cq.Cursor, cq.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		var notification Notification
+		if err := rows.Scan(
+			&notification.ID,
+			&notification.Kind,
+			&notification.PostID,
+			&notification.CommentID,
+			&notification.Read,
+			&notification.CreatedAt,
+			&notification.Actor.ID,
+			&notification.Actor.Username,
+		); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// MarkRead marks the notifications of a user as read, up to and including
+// upToID so that the ones that arrived since the client last listed them stay
+// unread, or all of them when upToID is 0.
+func (store *NotificationStore) MarkRead(ctx context.Context, userID, upToID int64) error {
+	query := `
+		UPDATE notifications
+		SET read_at = now()
+		WHERE user_id = $1 AND read_at IS NULL AND (id <= $2 OR $2 = 0)
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := store.db.ExecContext(ctx, query, userID, upToID)
+	return err
+}
@@ -1,6 +1,8 @@
 package store
 
 import (
+	"encoding/base64"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -46,6 +48,9 @@ func (fq PaginationFeedQuery) Parse(r *http.Request) (PaginationFeedQuery, error
 	tags := query.Get("tags")
 	if tags != "" {
 		fq.Tags = strings.Split(tags, ",")
+		for i, tag := range fq.Tags {
+			fq.Tags[i] = NormalizeTag(tag)
+		}
 	} else {
 		fq.Tags = []string{}
 	}
@@ -68,6 +73,87 @@ func (fq PaginationFeedQuery) Parse(r *http.Request) (PaginationFeedQuery, error
 	return fq, nil
 }
 
+// PaginationQuery is the plain limit/offset pagination used by list endpoints.
+type PaginationQuery struct {
+	Limit  int64 `json:"limit" validate:"gte=1,lte=50"`
+	Offset int64 `json:"offset" validate:"gte=0"`
+}
+
+func (p PaginationQuery) Parse(r *http.Request) (PaginationQuery, error) {
+	query := r.URL.Query()
+
+	limit := query.Get("limit")
+	if limit != "" {
+		l, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			return p, err
+		}
+		p.Limit = l
+	}
+
+	offset := query.Get("offset")
+	if offset != "" {
+		o, err := strconv.ParseInt(offset, 10, 64)
+		if err != nil {
+			return p, err
+		}
+		p.Offset = o
+	}
+
+	return p, nil
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorQuery is keyset pagination: Cursor is the opaque position returned as
+// next_cursor by the previous page, empty for the first page.
+type CursorQuery struct {
+	Limit  int64 `json:"limit" validate:"gte=1,lte=50"`
+	Cursor int64 `json:"cursor" validate:"gte=0"`
+}
+
+func (cq CursorQuery) Parse(r *http.Request) (CursorQuery, error) {
+	query := r.URL.Query()
+
+	limit := query.Get("limit")
+	if limit != "" {
+		l, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			return cq, err
+		}
+		cq.Limit = l
+	}
+
+	cursor := query.Get("cursor")
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return cq, err
+		}
+		cq.Cursor = c
+	}
+
+	return cq, nil
+}
+
+func EncodeCursor(position int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(position, 10)))
+}
+
+func DecodeCursor(cursor string) (int64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	position, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil || position <= 0 {
+		return 0, ErrInvalidCursor
+	}
+
+	return position, nil
+}
+
 func parseTime(s string) *time.Time {
 	if s == "" {
 		return nil
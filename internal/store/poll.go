@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrPollClosed  = errors.New("poll is closed")
+	ErrInvalidVote = errors.New("invalid poll options")
+)
+
+// Poll is attached to a post. The tallies are null while they are hidden
+// from the viewer: when the poll hides its results until the viewer voted or
+// the poll closed.
+type Poll struct {
+	ID          int64        `json:"id"`
+	Multiple    bool         `json:"multiple"`
+	HideResults bool         `json:"hide_results"`
+	ClosesAt    string       `json:"closes_at"`
+	Closed      bool         `json:"closed"`
+	Voted       bool         `json:"voted"`
+	MyVotes     []int64      `json:"my_votes"`
+	VotersCount *int64       `json:"voters_count"`
+	Options     []PollOption `json:"options"`
+}
+
+type PollOption struct {
+	ID         int64  `json:"id"`
+	Text       string `json:"text"`
+	VotesCount *int64 `json:"votes_count"`
+}
+
+// postPollSQL builds the poll of a post as a JSON object, NULL when the post
+// has none. The tallies are shown to the author, to voters and once the poll
+// is closed, and to everyone unless the poll hides its results.
+func postPollSQL(post, viewer string) string {
+	shown := fmt.Sprintf("(NOT pl.hide_results OR s.closed OR s.voted OR %s.user_id = %s)", post, viewer)
+
+	return fmt.Sprintf(`(
+			SELECT json_build_object(
+				'id', pl.id, 'multiple', pl.multiple, 'hide_results', pl.hide_results,
+				'closes_at', pl.closes_at, 'closed', s.closed, 'voted', s.voted,
+				'my_votes', ARRAY(SELECT pv.option_id FROM poll_votes pv WHERE pv.poll_id = pl.id AND pv.user_id = %[2]s ORDER BY pv.option_id),
+				'voters_count', CASE WHEN %[3]s THEN pl.voters_count END,
+				'options', (
+					SELECT json_agg(json_build_object(
+						'id', po.id, 'text', po.text, 'votes_count', CASE WHEN %[3]s THEN po.votes_count END
+					) ORDER BY po.position)
+					FROM poll_options po
+					WHERE po.poll_id = pl.id
+				)
+			)
+			FROM polls pl
+			CROSS JOIN LATERAL (
+				SELECT
+					pl.closed_at IS NOT NULL OR pl.closes_at <= now() AS closed,
+					EXISTS (SELECT 1 FROM poll_voters v WHERE v.poll_id = pl.id AND v.user_id = %[2]s) AS voted
+			) s
+			WHERE pl.post_id = %[1]s.id
+		)`, post, viewer, shown)
+}
+
+// scanPoll decodes the postPollSQL column, nil when the post has no poll.
+func scanPoll(data []byte) (*Poll, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	var poll Poll
+	if err := json.Unmarshal(data, &poll); err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// createPoll attaches a poll to a post, its options in order.
+func createPoll(ctx context.Context, tx *sql.Tx, postID int64, poll *Poll) error {
+	if poll == nil {
+		return nil
+	}
+
+	query := `
+		INSERT INTO polls (post_id, multiple, hide_results, closes_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+		`
+
+	if err := tx.QueryRowContext(ctx, query, postID, poll.Multiple, poll.HideResults, poll.ClosesAt).Scan(&poll.ID); err != nil {
+		return err
+	}
+
+	texts := make([]string, len(poll.Options))
+	for i, option := range poll.Options {
+		texts[i] = option.Text
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`INSERT INTO poll_options (poll_id, position, text)
+		SELECT $1, o.position, o.text
+		FROM unnest($2::TEXT[]) WITH ORDINALITY AS o(text, position)
+		RETURNING id, position`,
+		poll.ID,
+		pq.Array(texts),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var position int
+		if err := rows.Scan(&id, &position); err != nil {
+			return err
+		}
+		poll.Options[position-1].ID = id
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type PollStore struct {
+	db *sql.DB
+}
+
+// Vote records the ballot of a user on the poll of a published post, for a
+// single option unless the poll allows several. ErrConflict is returned when
+// the user already voted, ErrPollClosed once the poll closed and
+// ErrInvalidVote for options outside the poll. Votes on a poll queue up on
+// its row, which each of them updates.
+func (store *PollStore) Vote(ctx context.Context, postID, userID int64, optionIDs []int64) error {
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var pollID int64
+		var multiple, closed bool
+		err := tx.QueryRowContext(
+			ctx,
+			`SELECT pl.id, pl.multiple, pl.closed_at IS NOT NULL OR pl.closes_at <= now()
+			FROM polls pl
+			JOIN posts p ON p.id = pl.post_id
+			WHERE pl.post_id = $1 AND `+livePostSQL("p")+`
+			FOR NO KEY UPDATE OF pl`,
+			postID,
+		).Scan(&pollID, &multiple, &closed)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrorNotFound
+			default:
+				return err
+			}
+		}
+
+		if closed {
+			return ErrPollClosed
+		}
+		if len(optionIDs) == 0 || (!multiple && len(optionIDs) > 1) {
+			return ErrInvalidVote
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO poll_voters (poll_id, user_id) VALUES ($1, $2)`, pollID, userID)
+		if err != nil {
+			return constraintError(err)
+		}
+
+		result, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO poll_votes (poll_id, user_id, option_id)
+			SELECT $1, $2, o.id
+			FROM poll_options o
+			WHERE o.poll_id = $1 AND o.id = ANY($3)`,
+			pollID,
+			userID,
+			pq.Array(optionIDs),
+		)
+		if err != nil {
+			return err
+		}
+		voted, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if voted != int64(len(optionIDs)) {
+			return ErrInvalidVote
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE poll_options SET votes_count = votes_count + 1 WHERE poll_id = $1 AND id = ANY($2)`,
+			pollID,
+			pq.Array(optionIDs),
+		); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE polls SET voters_count = voters_count + 1 WHERE id = $1`, pollID)
+		return err
+	})
+}
+
+// Close marks the polls whose closing time passed by now as closed and
+// returns how many were.
+func (store *PollStore) Close(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := store.db.ExecContext(
+		ctx,
+		`UPDATE polls SET closed_at = closes_at WHERE closed_at IS NULL AND closes_at <= $1`,
+		now,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,162 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestPollVote(t *testing.T) {
+	db := newTestDB(t)
+	polls := &PollStore{db: db}
+	posts := &PostStore{db: db}
+	ctx := context.Background()
+
+	author := insertUser(t, db, "author")
+	voter := insertUser(t, db, "voter")
+	other := insertUser(t, db, "other")
+
+	// newPoll attaches a poll with two options to a new post and returns the
+	// post and the option ids.
+	newPoll := func(t *testing.T, multiple, hideResults bool, closesIn string) (int64, []int64) {
+		t.Helper()
+
+		postID := insertPost(t, db, author, "public")
+		pollID := insertID(t, db, `
+			INSERT INTO polls (post_id, multiple, hide_results, closes_at)
+			VALUES ($1, $2, $3, now() + $4::INTERVAL)
+			RETURNING id
+			`, postID, multiple, hideResults, closesIn)
+		yes := insertID(t, db, `INSERT INTO poll_options (poll_id, position, text) VALUES ($1, 1, 'yes') RETURNING id`, pollID)
+		no := insertID(t, db, `INSERT INTO poll_options (poll_id, position, text) VALUES ($1, 2, 'no') RETURNING id`, pollID)
+		return postID, []int64{yes, no}
+	}
+
+	t.Run("should count a vote once", func(t *testing.T) {
+		postID, options := newPoll(t, false, false, "1 day")
+
+		if err := polls.Vote(ctx, postID, voter, options[:1]); err != nil {
+			t.Fatal(err)
+		}
+		if err := polls.Vote(ctx, postID, voter, options[1:]); err != ErrConflict {
+			t.Errorf("expected ErrConflict, got %v", err)
+		}
+
+		post, err := posts.GetVisibleByID(ctx, postID, voter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count := post.Poll.VotersCount; count == nil || *count != 1 {
+			t.Errorf("expected a single voter, got %v", count)
+		}
+	})
+
+	t.Run("should take a single option on a single choice poll", func(t *testing.T) {
+		postID, options := newPoll(t, false, false, "1 day")
+
+		if err := polls.Vote(ctx, postID, voter, options); err != ErrInvalidVote {
+			t.Errorf("expected ErrInvalidVote, got %v", err)
+		}
+	})
+
+	t.Run("should take several options when the poll allows it", func(t *testing.T) {
+		postID, options := newPoll(t, true, false, "1 day")
+
+		if err := polls.Vote(ctx, postID, voter, options); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("should reject an option of another poll", func(t *testing.T) {
+		postID, _ := newPoll(t, false, false, "1 day")
+		_, others := newPoll(t, false, false, "1 day")
+
+		if err := polls.Vote(ctx, postID, voter, others[:1]); err != ErrInvalidVote {
+			t.Errorf("expected ErrInvalidVote, got %v", err)
+		}
+	})
+
+	t.Run("should not vote on a closed poll", func(t *testing.T) {
+		postID, options := newPoll(t, false, false, "-1 hour")
+
+		if err := polls.Vote(ctx, postID, voter, options[:1]); err != ErrPollClosed {
+			t.Errorf("expected ErrPollClosed, got %v", err)
+		}
+	})
+
+	t.Run("should hide the tallies until the viewer voted", func(t *testing.T) {
+		postID, options := newPoll(t, false, true, "1 day")
+
+		if err := polls.Vote(ctx, postID, other, options[:1]); err != nil {
+			t.Fatal(err)
+		}
+
+		post, err := posts.GetVisibleByID(ctx, postID, voter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post.Poll.VotersCount != nil || post.Poll.Options[0].VotesCount != nil {
+			t.Error("expected the tallies to be hidden before voting")
+		}
+
+		if err := polls.Vote(ctx, postID, voter, options[:1]); err != nil {
+			t.Fatal(err)
+		}
+
+		post, err = posts.GetVisibleByID(ctx, postID, voter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if votes := post.Poll.Options[0].VotesCount; votes == nil || *votes != 2 {
+			t.Errorf("expected the tallies to be shown after voting, got %v", votes)
+		}
+	})
+
+	t.Run("should count votes cast at the same time", func(t *testing.T) {
+		postID, options := newPoll(t, false, false, "1 day")
+
+		voters := make([]int64, 10)
+		for i := range voters {
+			voters[i] = insertUser(t, db, fmt.Sprintf("parallel_%d", i))
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, len(voters))
+		for _, id := range voters {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- polls.Vote(ctx, postID, id, options[:1])
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Errorf("expected every vote to go through, got %v", err)
+			}
+		}
+
+		post, err := posts.GetVisibleByID(ctx, postID, author)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count := post.Poll.VotersCount; count == nil || *count != int64(len(voters)) {
+			t.Errorf("expected %d voters, got %v", len(voters), count)
+		}
+	})
+
+	t.Run("should show the tallies to the author", func(t *testing.T) {
+		postID, _ := newPoll(t, false, true, "1 day")
+
+		post, err := posts.GetVisibleByID(ctx, postID, author)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post.Poll.VotersCount == nil {
+			t.Error("expected the tallies to be shown")
+		}
+	})
+}
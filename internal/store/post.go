@@ -8,28 +8,89 @@ import (
 )
 
 type Post struct {
-	ID        int64     `json:"id"`
-	Content   string    `json:"content"`
-	Title     string    `json:"title"`
-	UserID    int64     `json:"user_id"`
-	Tags      []string  `json:"tags"`
-	CreatedAt string    `json:"created_at"`
-	UpdatedAt string    `json:"updated_at"`
-	Version   int64     `json:"version"`
-	Comments  []Comment `json:"comments"`
-	User      *User     `json:"user"`
+	ID          int64    `json:"id"`
+	Content     string   `json:"content"`
+	Title       string   `json:"title"`
+	UserID      int64    `json:"user_id"`
+	Tags        []string `json:"tags"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at"`
+	Version     int64    `json:"version"`
+	ModeratedAt *string  `json:"moderated_at,omitempty"`
+	// EditedAt is set once the post has been edited, see its revisions.
+	EditedAt *string    `json:"edited_at"`
+	Comments []*Comment `json:"comments"`
+	User     *User      `json:"user"`
+	// ReactionCounts is maintained by a trigger on post_reactions.
+	ReactionCounts ReactionCounts `json:"reaction_counts"`
+	// MyReactions are the reactions of the viewer, when there is one.
+	MyReactions []string `json:"my_reactions"`
+	// Bookmarked tells whether the viewer bookmarked the post.
+	Bookmarked bool `json:"bookmarked"`
+	// Kind is post, repost or quote. Reposts and quotes embed their original.
+	Kind       string `json:"kind"`
+	OriginalID *int64 `json:"original_id,omitempty"`
+	Original   *Post  `json:"original,omitempty"`
+	// OriginalUnavailable is set when the original was deleted or is hidden from the viewer.
+	OriginalUnavailable bool `json:"original_unavailable,omitempty"`
+	// Status is draft, scheduled or published. Until published, only the author sees the post.
+	Status      string  `json:"status"`
+	PublishAt   *string `json:"publish_at,omitempty"`
+	PublishedAt *string `json:"published_at,omitempty"`
+	// Visibility is who can see the post: public, followers, mentioned or private.
+	Visibility string `json:"visibility" enums:"public,followers,mentioned,private"`
+	// Media are the attached uploads, in order.
+	Media MediaList `json:"media"`
+	// MediaIDs are the uploads to attach when creating the post.
+	MediaIDs []int64 `json:"-"`
+	Poll     *Poll   `json:"poll,omitempty"`
+	// Mentions locate the mentions of existing users in the content.
+	Mentions []MentionEntity `json:"mentions"`
 }
 
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+)
+
+const (
+	PostVisibilityPublic    = "public"
+	PostVisibilityFollowers = "followers"
+	PostVisibilityMentioned = "mentioned"
+	PostVisibilityPrivate   = "private"
+)
+
+const (
+	FeedReasonOwn         = "own"
+	FeedReasonFollowing   = "following"
+	FeedReasonFollowedTag = "followed_tag"
+)
+
 type PostWithMetadata struct {
 	Post
-	User
-	CommentsCount int64 `json:"comments_count"`
+	// The author is flattened into feed entries too, as feed clients have
+	// always read it. Only Username and Email are filled.
+	Username      string   `json:"username"`
+	Email         string   `json:"email"`
+	IsActivated   bool     `json:"is_activated"`
+	RoleID        int64    `json:"role_id"`
+	Role          Role     `json:"role"`
+	CommentsCount int64    `json:"comments_count"`
+	FeedReason    string   `json:"feed_reason"`
+	MatchedTags   []string `json:"matched_tags,omitempty"`
+	// HiddenBy is set when one of the viewer's mute filters asks to warn about the post.
+	HiddenBy *MuteFilterMatch `json:"hidden_by,omitempty"`
 }
 
 type CreatePostResponse struct {
-	ID        int64  `json:"id"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID          int64   `json:"id"`
+	CreatedAt   string  `json:"created_at"`
+	UpdatedAt   string  `json:"updated_at"`
+	Status      string  `json:"status"`
+	PublishAt   *string `json:"publish_at,omitempty"`
+	PublishedAt *string `json:"published_at,omitempty"`
+	Visibility  string  `json:"visibility"`
 }
 
 type PostStore struct {
@@ -40,33 +101,69 @@ func NewPostStore(db *sql.DB) *PostStore {
 	return &PostStore{db: db}
 }
 
+// Create stores a post with the given status, published unless set otherwise,
+// and visibility, public unless set otherwise. Scheduled posts carry the time
+// the publisher makes them live. The users mentioned in the content are
+// recorded, and notified once the post is published. MediaIDs must be
+// unattached uploads of the author, ErrInvalidMedia is returned otherwise.
+// The poll, if any, is created along with the post.
 func (store *PostStore) Create(ctx context.Context, post *Post) (*CreatePostResponse, error) {
 	query := `
-		INSERT INTO posts (content, title, user_id, tags)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at, updated_at
+		INSERT INTO posts (content, title, user_id, tags, kind, status, publish_at, published_at, visibility)
+		VALUES ($1, $2, $3, $4, 'post', $5, $6, CASE WHEN $5 = 'published' THEN now() END, $7)
+		RETURNING id, created_at, updated_at, status, publish_at, published_at, visibility
 		`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
+	if post.Status == "" {
+		post.Status = PostStatusPublished
+	}
+	if post.Visibility == "" {
+		post.Visibility = PostVisibilityPublic
+	}
 
 	response := &CreatePostResponse{}
 
-	row := store.db.QueryRowContext(
-		ctx,
-		query,
-		post.Content,
-		post.Title,
-		post.UserID,
-		pq.Array(post.Tags),
-	)
+	err := withTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
 
-	err := row.Scan(
-		&response.ID,
-		&response.CreatedAt,
-		&response.UpdatedAt,
-	)
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			post.Content,
+			post.Title,
+			post.UserID,
+			pq.Array(post.Tags),
+			post.Status,
+			post.PublishAt,
+			post.Visibility,
+		).Scan(
+			&response.ID,
+			&response.CreatedAt,
+			&response.UpdatedAt,
+			&response.Status,
+			&response.PublishAt,
+			&response.PublishedAt,
+			&response.Visibility,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := setPostMentions(ctx, tx, response.ID, post.Content); err != nil {
+			return err
+		}
+
+		if err := notifyPostMentions(ctx, tx, response.ID); err != nil {
+			return err
+		}
+
+		if err := attachMedia(ctx, tx, response.ID, post.UserID, post.MediaIDs); err != nil {
+			return err
+		}
 
+		return createPoll(ctx, tx, response.ID, post.Poll)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -76,20 +173,60 @@ func (store *PostStore) Create(ctx context.Context, post *Post) (*CreatePostResp
 
 func (store *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 	query := `
-		SELECT id, content, title, user_id, tags, created_at, updated_at, version
-		FROM posts
-		WHERE id = $1
+		SELECT p.id, p.content, p.title, p.user_id, p.tags, p.created_at, p.updated_at, p.version, p.moderated_at, p.edited_at,
+			p.status, p.publish_at, p.published_at, p.visibility,
+			p.reaction_counts, '{}'::TEXT[], false, p.kind, p.original_id,
+			` + postMediaSQL("p") + `,
+			` + postPollSQL("p", "p.user_id") + `,
+			` + mentionedUsersSQL("post_mentions", "post_id", "p.id") + `,
+			` + originalColumnsSQL("") + `
+		FROM posts p
+		` + originalJoinSQL("p", "") + `
+		WHERE p.id = $1 AND p.deleted_at IS NULL
 		`
 
+	return store.getOne(ctx, query, id)
+}
+
+// GetVisibleByID returns the post only if viewerID is allowed to see it, and
+// ErrorNotFound otherwise so hidden posts are indistinguishable from missing ones.
+// Drafts and scheduled posts are visible to their author only.
+func (store *PostStore) GetVisibleByID(ctx context.Context, id, viewerID int64) (*Post, error) {
+	query := `
+		SELECT p.id, p.content, p.title, p.user_id, p.tags, p.created_at, p.updated_at, p.version, p.moderated_at, p.edited_at,
+			p.status, p.publish_at, p.published_at, p.visibility,
+			p.reaction_counts,
+			ARRAY(SELECT r.reaction FROM post_reactions r WHERE r.post_id = p.id AND r.user_id = $2 ORDER BY r.reaction),
+			EXISTS (SELECT 1 FROM bookmarks bm WHERE bm.post_id = p.id AND bm.user_id = $2),
+			p.kind, p.original_id,
+			` + postMediaSQL("p") + `,
+			` + postPollSQL("p", "$2") + `,
+			` + mentionedUsersSQL("post_mentions", "post_id", "p.id") + `,
+			` + originalColumnsSQL("$2") + `
+		FROM posts p
+		` + originalJoinSQL("p", "$2") + `
+		WHERE p.id = $1
+			AND ` + canViewPostSQL("p", "$2") + `
+			AND p.deleted_at IS NULL
+			AND (p.status = 'published' OR p.user_id = $2)
+		`
+
+	return store.getOne(ctx, query, id, viewerID)
+}
+
+func (store *PostStore) getOne(ctx context.Context, query string, args ...any) (*Post, error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
 	post := Post{}
+	var poll []byte
+	var mentions mentionedUsers
+	var original originalColumns
 	err := store.db.QueryRowContext(
 		ctx,
 		query,
-		id).
-		Scan(
+		args...).
+		Scan(append([]any{
 			&post.ID,
 			&post.Content,
 			&post.Title,
@@ -98,7 +235,21 @@ func (store *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 			&post.CreatedAt,
 			&post.UpdatedAt,
 			&post.Version,
-		)
+			&post.ModeratedAt,
+			&post.EditedAt,
+			&post.Status,
+			&post.PublishAt,
+			&post.PublishedAt,
+			&post.Visibility,
+			&post.ReactionCounts,
+			pq.Array(&post.MyReactions),
+			&post.Bookmarked,
+			&post.Kind,
+			&post.OriginalID,
+			&post.Media,
+			&poll,
+			&mentions,
+		}, original.dest()...)...)
 	if err != nil {
 		switch err {
 		case sql.ErrNoRows:
@@ -107,20 +258,64 @@ func (store *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 			return nil, err
 		}
 	}
+	if err := original.apply(&post); err != nil {
+		return nil, err
+	}
+	post.Mentions = mentions.entities(post.Content)
+
+	if post.Poll, err = scanPoll(poll); err != nil {
+		return nil, err
+	}
 
 	return &post, nil
 }
 
-func (store *PostStore) Delete(ctx context.Context, id int64) error {
+// Delete moves the post to the trash as long as version is still its current
+// version, ErrVersionMismatch is returned otherwise. Its comments are hidden
+// along with it.
+func (store *PostStore) Delete(ctx context.Context, id, version, deletedBy int64) error {
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if err := lockPostVersion(ctx, tx, id, version); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			`UPDATE posts SET deleted_at = now(), deleted_by = $2 WHERE id = $1`,
+			id,
+			deletedBy,
+		)
+		return err
+	})
+}
+
+// Update writes a new version of the post, keeping the current one as a
+// revision. It fails with ErrVersionMismatch when post.Version is no longer current.
+func (store *PostStore) Update(ctx context.Context, post *Post, editorID int64) error {
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		return updatePost(ctx, tx, post, editorID)
+	})
+}
+
+// SetModerated hides (or unhides) a post on behalf of a moderator.
+func (store *PostStore) SetModerated(ctx context.Context, postID, moderatorID int64, moderated bool) error {
 	query := `
-		DELETE FROM posts
+		UPDATE posts
+		SET moderated_at = CASE WHEN $3 THEN now() ELSE NULL END,
+			moderated_by = CASE WHEN $3 THEN $2::BIGINT ELSE NULL END
 		WHERE id = $1
 		`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	result, err := store.db.ExecContext(ctx, query, id)
+	result, err := store.db.ExecContext(ctx, query, postID, moderatorID, moderated)
 	if err != nil {
 		return err
 	}
@@ -134,59 +329,56 @@ func (store *PostStore) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
-func (store *PostStore) Update(ctx context.Context, post *Post) error {
-	query := `
-		UPDATE posts
-		SET title = $1,content = $2, tags = $3, version = version + 1
-		WHERE id = $4
-		AND version = $5
-		RETURNING version
-		`
-
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
-
-	err := store.db.QueryRowContext(
-		ctx,
-		query,
-		post.Title,
-		post.Content,
-		pq.Array(post.Tags),
-		post.ID,
-		post.Version,
-	).Scan(
-		&post.Version,
-	)
-	if err != nil {
-		switch err {
-		case sql.ErrNoRows:
-			return ErrorNotFound
-		default:
-			return err
-		}
-	}
-
-	return nil
-
-}
-
+// GetByUserId builds the home feed of a user: their own posts, posts of the
+// users they follow and posts carrying a tag they follow. FeedReason tells the
+// client which of those made a post show up.
 func (store *PostStore) GetByUserId(ctx context.Context, userID int64, fq PaginationFeedQuery) ([]*PostWithMetadata, error) {
 	query := `
-		SELECT 
-			p.id, p.title, p."content", p.tags, p."version", p.created_at,
+		SELECT
+			p.id, p.title, p."content", p.tags, p."version", p.created_at, p.edited_at, p.published_at, p.visibility, p.kind, p.original_id,
 			u."id" AS user_id, u.username, u.email,
-			COUNT(c."id") AS comments_count
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = p."id" AND c.deleted_at IS NULL) AS comments_count,
+			p.reaction_counts,
+			ARRAY(SELECT r.reaction FROM post_reactions r WHERE r.post_id = p.id AND r.user_id = $1 ORDER BY r.reaction) AS my_reactions,
+			EXISTS (SELECT 1 FROM bookmarks bm WHERE bm.post_id = p.id AND bm.user_id = $1) AS bookmarked,
+			CASE
+				WHEN p.user_id = $1 THEN 'own'
+				WHEN f.user_id IS NOT NULL THEN 'following'
+				ELSE 'followed_tag'
+			END AS feed_reason,
+			ARRAY(
+				SELECT DISTINCT tf.tag
+				FROM user_tag_follows tf, unnest(p.tags) AS pt(tag)
+				WHERE tf.user_id = $1 AND tf.tag = pt.tag
+			) AS matched_tags,
+			` + postMediaSQL("p") + ` AS media,
+			` + postPollSQL("p", "$1") + ` AS poll,
+			` + mentionedUsersSQL("post_mentions", "post_id", "p.id") + ` AS mentions,
+			mf.id, mf.kind, mf.value,
+			` + originalColumnsSQL("$1") + `
 		FROM posts p
-		LEFT JOIN comments c ON p."id" = c.post_id
-		LEFT JOIN users u ON p.user_id = u."id"
-		JOIN followers f ON p.user_id = f.user_id OR p.user_id = $1
-		WHERE 
-			f.user_id= $1 
+		JOIN users u ON p.user_id = u."id"
+		LEFT JOIN followers f ON f.user_id = p.user_id AND f.follower_id = $1
+		` + originalJoinSQL("p", "$1") + `
+		` + muteFilterJoinSQL("$1", "p.title || ' ' || p.content", "p.tags") + `
+		WHERE
+			(
+				p.user_id = $1
+				OR f.user_id IS NOT NULL
+				OR EXISTS (
+					SELECT 1
+					FROM user_tag_follows tf, unnest(p.tags) AS pt(tag)
+					WHERE tf.user_id = $1 AND tf.tag = pt.tag
+				)
+			)
+			AND ` + livePostSQL("p") + `
+			AND ` + canViewPostSQL("p", "$1") + `
+			AND ` + notMutedSQL("p.user_id", "$1") + `
+			AND ` + notHiddenByFilterSQL + `
 			AND (p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%')
 			AND (p.tags @> $5 OR $5 = '{}')
-			AND (p.created_at >= $6 OR $6 IS NULL)
-		GROUP BY p.id, u."id"
-		ORDER BY p.created_at ` + fq.Sort + `
+			AND (p.published_at >= $6 OR $6 IS NULL)
+		ORDER BY p.published_at ` + fq.Sort + `
 		LIMIT $2 OFFSET $3
 		`
 
@@ -211,22 +403,56 @@ func (store *PostStore) GetByUserId(ctx context.Context, userID int64, fq Pagina
 	var posts []*PostWithMetadata
 	for rows.Next() {
 		var post PostWithMetadata
-		if err := rows.Scan(
+		var filter muteFilterColumns
+		var original originalColumns
+		var poll []byte
+		var mentions mentionedUsers
+		post.User = &User{}
+		if err := rows.Scan(append([]any{
 			&post.Post.ID,
 			&post.Post.Title,
 			&post.Post.Content,
 			pq.Array(&post.Post.Tags),
 			&post.Post.Version,
 			&post.Post.CreatedAt,
+			&post.Post.EditedAt,
+			&post.Post.PublishedAt,
+			&post.Post.Visibility,
+			&post.Post.Kind,
+			&post.Post.OriginalID,
 			&post.User.ID,
 			&post.User.Username,
 			&post.User.Email,
 			&post.CommentsCount,
-		); err != nil {
+			&post.ReactionCounts,
+			pq.Array(&post.MyReactions),
+			&post.Bookmarked,
+			&post.FeedReason,
+			pq.Array(&post.MatchedTags),
+			&post.Media,
+			&poll,
+			&mentions,
+		}, append(filter.dest(), original.dest()...)...)...); err != nil {
+			return nil, err
+		}
+		post.UserID = post.User.ID
+		post.Username = post.User.Username
+		post.Email = post.User.Email
+		post.Status = PostStatusPublished
+		if err := original.apply(&post.Post); err != nil {
+			return nil, err
+		}
+		post.HiddenBy = filter.match()
+		post.Mentions = mentions.entities(post.Content)
+		if post.Poll, err = scanPoll(poll); err != nil {
 			return nil, err
 		}
 		posts = append(posts, &post)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
 	return posts, nil
 }
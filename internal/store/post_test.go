@@ -0,0 +1,27 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestFeedSearch(t *testing.T) {
+	db := newTestDB(t)
+	posts := &PostStore{db: db}
+	ctx := context.Background()
+
+	author := insertUser(t, db, "author")
+	gophers := insertID(t, db, `INSERT INTO posts (user_id, title, content, tags) VALUES ($1, 'Gophers', 'the ones that dig', '{}') RETURNING id`, author)
+	insertID(t, db, `INSERT INTO posts (user_id, title, content, tags) VALUES ($1, 'Rust', 'crabs', '{}') RETURNING id`, author)
+
+	for _, search := range []string{"goph", "THE", "ones that"} {
+		feed, err := posts.GetByUserId(ctx, author, PaginationFeedQuery{Limit: 20, Sort: "desc", Search: search})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := feedIDs(feed), fmt.Sprint([]int64{gophers}); got != want {
+			t.Errorf("expected %q to match %s, got %s", search, want, got)
+		}
+	}
+}
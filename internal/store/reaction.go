@@ -0,0 +1,209 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// ReactionLike is always available, other reactions are configured.
+const ReactionLike = "like"
+
+// ReactionCounts holds the number of reactions per reaction, as denormalised
+// onto posts and comments.
+type ReactionCounts map[string]int64
+
+func (c *ReactionCounts) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*c = ReactionCounts{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into ReactionCounts", src)
+	}
+
+	counts := ReactionCounts{}
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return err
+	}
+	*c = counts
+	return nil
+}
+
+type Reactor struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	Reaction  string `json:"reaction"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ReactionQuery lists who reacted, optionally to a single reaction.
+type ReactionQuery struct {
+	PaginationQuery
+	Reaction string
+}
+
+type ReactionStore struct {
+	db *sql.DB
+}
+
+// AddToPost is idempotent: reacting twice with the same reaction is a no-op.
+func (store *ReactionStore) AddToPost(ctx context.Context, postID, userID int64, reaction string) error {
+	lookup := `
+		SELECT NOT ` + notBlockedSQL("p.user_id", "$2") + `, ` + canViewPostSQL("p", "$2") + ` AND ` + livePostSQL("p") + `
+		FROM posts p
+		WHERE p.id = $1
+		`
+	insert := `
+		INSERT INTO post_reactions (post_id, user_id, reaction)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+		`
+
+	return store.add(ctx, lookup, []any{postID, userID}, insert, postID, userID, reaction)
+}
+
+// RemoveFromPost is idempotent: removing a missing reaction is a no-op.
+func (store *ReactionStore) RemoveFromPost(ctx context.Context, postID, userID int64, reaction string) error {
+	query := `
+		DELETE FROM post_reactions
+		WHERE post_id = $1 AND user_id = $2 AND reaction = $3
+		`
+
+	return store.remove(ctx, query, postID, userID, reaction)
+}
+
+// GetPostReactors lists who reacted to a post, most recent first, leaving out
+// users the viewer blocked or was blocked by.
+func (store *ReactionStore) GetPostReactors(ctx context.Context, postID, viewerID int64, rq ReactionQuery) ([]Reactor, error) {
+	query := `
+		SELECT u.id, u.username, r.reaction, r.created_at
+		FROM post_reactions r
+		JOIN users u ON r.user_id = u.id
+		WHERE r.post_id = $1
+			AND (r.reaction = $3 OR $3 = '')
+			AND ` + notBlockedSQL("r.user_id", "$2") + `
+		ORDER BY r.created_at DESC, u.id DESC
+		LIMIT $4 OFFSET $5
+		`
+
+	return store.list(ctx, query, postID, viewerID, rq.Reaction, rq.Limit, rq.Offset)
+}
+
+// AddToComment is idempotent. The comment must belong to the post and the
+// post must be visible to the user.
+func (store *ReactionStore) AddToComment(ctx context.Context, postID, commentID, userID int64, reaction string) error {
+	lookup := `
+		SELECT NOT ` + notBlockedSQL("c.user_id", "$2") + `, ` + canViewPostSQL("p", "$2") + ` AND ` + livePostSQL("p") + `
+		FROM comments c
+		JOIN posts p ON c.post_id = p.id
+		WHERE c.id = $1 AND c.post_id = $3 AND c.deleted_at IS NULL
+		`
+	insert := `
+		INSERT INTO comment_reactions (comment_id, user_id, reaction)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+		`
+
+	return store.add(ctx, lookup, []any{commentID, userID, postID}, insert, commentID, userID, reaction)
+}
+
+// RemoveFromComment is idempotent: removing a missing reaction is a no-op.
+func (store *ReactionStore) RemoveFromComment(ctx context.Context, commentID, userID int64, reaction string) error {
+	query := `
+		DELETE FROM comment_reactions
+		WHERE comment_id = $1 AND user_id = $2 AND reaction = $3
+		`
+
+	return store.remove(ctx, query, commentID, userID, reaction)
+}
+
+func (store *ReactionStore) GetCommentReactors(ctx context.Context, postID, commentID, viewerID int64, rq ReactionQuery) ([]Reactor, error) {
+	query := `
+		SELECT u.id, u.username, r.reaction, r.created_at
+		FROM comment_reactions r
+		JOIN comments c ON r.comment_id = c.id
+		JOIN users u ON r.user_id = u.id
+		WHERE r.comment_id = $1
+			AND c.post_id = $6
+			AND (r.reaction = $3 OR $3 = '')
+			AND ` + notBlockedSQL("r.user_id", "$2") + `
+		ORDER BY r.created_at DESC, u.id DESC
+		LIMIT $4 OFFSET $5
+		`
+
+	return store.list(ctx, query, commentID, viewerID, rq.Reaction, rq.Limit, rq.Offset, postID)
+}
+
+// add looks the target up first: the lookup returns whether the user and the
+// author blocked each other and whether the user may see the target at all.
+func (store *ReactionStore) add(ctx context.Context, lookup string, lookupArgs []any, insert string, targetID, userID int64, reaction string) error {
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var isBlocked, isVisible bool
+		if err := tx.QueryRowContext(ctx, lookup, lookupArgs...).Scan(&isBlocked, &isVisible); err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrorNotFound
+			default:
+				return err
+			}
+		}
+		if isBlocked {
+			return ErrBlocked
+		}
+		if !isVisible {
+			return ErrorNotFound
+		}
+
+		_, err := tx.ExecContext(ctx, insert, targetID, userID, reaction)
+		return constraintError(err)
+	})
+}
+
+func (store *ReactionStore) remove(ctx context.Context, query string, targetID, userID int64, reaction string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := store.db.ExecContext(ctx, query, targetID, userID, reaction)
+	return err
+}
+
+func (store *ReactionStore) list(ctx context.Context, query string, args ...any) ([]Reactor, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactors := []Reactor{}
+	for rows.Next() {
+		var reactor Reactor
+		if err := rows.Scan(
+			&reactor.ID,
+			&reactor.Username,
+			&reactor.Reaction,
+			&reactor.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		reactors = append(reactors, reactor)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reactors, nil
+}
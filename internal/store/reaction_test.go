@@ -0,0 +1,167 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func TestReactionCountsScan(t *testing.T) {
+	tests := map[string]struct {
+		src  any
+		want ReactionCounts
+	}{
+		"null":   {nil, ReactionCounts{}},
+		"bytes":  {[]byte(`{"like": 2, "🎉": 1}`), ReactionCounts{"like": 2, "🎉": 1}},
+		"string": {`{}`, ReactionCounts{}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var counts ReactionCounts
+			if err := counts.Scan(tt.src); err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(counts) != fmt.Sprint(tt.want) || counts == nil {
+				t.Errorf("expected %v, got %v", tt.want, counts)
+			}
+		})
+	}
+
+	var counts ReactionCounts
+	if err := counts.Scan(42); err == nil {
+		t.Error("expected an error scanning an int")
+	}
+}
+
+func TestPostReactions(t *testing.T) {
+	db := newTestDB(t)
+	reactions := &ReactionStore{db: db}
+	posts := &PostStore{db: db}
+	ctx := context.Background()
+
+	author := insertUser(t, db, "author")
+	fan := insertUser(t, db, "fan")
+	other := insertUser(t, db, "other")
+	blocked := insertUser(t, db, "blocked")
+	stranger := insertUser(t, db, "stranger")
+	mustExec(t, db, `INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)`, author, blocked)
+
+	post := insertPost(t, db, author, "public")
+	private := insertPost(t, db, author, "private")
+
+	counts := func(t *testing.T) string {
+		t.Helper()
+
+		got, err := posts.GetVisibleByID(ctx, post, fan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the order of the reactions depends on the collation
+		sort.Strings(got.MyReactions)
+		return fmt.Sprint(got.ReactionCounts, got.MyReactions)
+	}
+
+	for _, reaction := range []struct {
+		userID   int64
+		reaction string
+	}{
+		{fan, ReactionLike},
+		{fan, ReactionLike},
+		{fan, "🎉"},
+		{other, ReactionLike},
+	} {
+		if err := reactions.AddToPost(ctx, post, reaction.userID, reaction.reaction); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := counts(t), fmt.Sprint(ReactionCounts{"like": 2, "🎉": 1}, []string{"like", "🎉"}); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	t.Run("should list the reactors", func(t *testing.T) {
+		reactors, err := reactions.GetPostReactors(ctx, post, author, ReactionQuery{PaginationQuery: PaginationQuery{Limit: 20}, Reaction: "🎉"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reactors) != 1 || reactors[0].ID != fan {
+			t.Errorf("expected the fan to be the only 🎉 reactor, got %+v", reactors)
+		}
+	})
+
+	t.Run("should refuse reactions the user may not make", func(t *testing.T) {
+		if err := reactions.AddToPost(ctx, post, blocked, ReactionLike); err != ErrBlocked {
+			t.Errorf("expected ErrBlocked, got %v", err)
+		}
+		if err := reactions.AddToPost(ctx, private, stranger, ReactionLike); err != ErrorNotFound {
+			t.Errorf("expected ErrorNotFound on a hidden post, got %v", err)
+		}
+		if err := reactions.AddToPost(ctx, -1, stranger, ReactionLike); err != ErrorNotFound {
+			t.Errorf("expected ErrorNotFound on a missing post, got %v", err)
+		}
+	})
+
+	t.Run("should drop emptied counts", func(t *testing.T) {
+		if err := reactions.RemoveFromPost(ctx, post, fan, "🎉"); err != nil {
+			t.Fatal(err)
+		}
+		if err := reactions.RemoveFromPost(ctx, post, fan, "🎉"); err != nil {
+			t.Errorf("expected removing twice to be a no-op, got %v", err)
+		}
+		if err := reactions.RemoveFromPost(ctx, post, other, ReactionLike); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := counts(t), fmt.Sprint(ReactionCounts{"like": 1}, []string{"like"}); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	})
+}
+
+func TestCommentReactions(t *testing.T) {
+	db := newTestDB(t)
+	reactions := &ReactionStore{db: db}
+	comments := &CommentStore{db: db}
+	ctx := context.Background()
+
+	author := insertUser(t, db, "author")
+	commenter := insertUser(t, db, "commenter")
+	blocked := insertUser(t, db, "blocked")
+	mustExec(t, db, `INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)`, commenter, blocked)
+
+	post := insertPost(t, db, author, "public")
+	other := insertPost(t, db, author, "public")
+	comment := insertComment(t, db, post, commenter)
+
+	if err := reactions.AddToComment(ctx, post, comment, author, ReactionLike); err != nil {
+		t.Fatal(err)
+	}
+	if err := reactions.AddToComment(ctx, other, comment, author, ReactionLike); err != ErrorNotFound {
+		t.Errorf("expected ErrorNotFound for a comment of another post, got %v", err)
+	}
+	if err := reactions.AddToComment(ctx, post, comment, blocked, ReactionLike); err != ErrBlocked {
+		t.Errorf("expected ErrBlocked, got %v", err)
+	}
+
+	thread, err := comments.GetCommentByPostId(ctx, post, author, CursorQuery{Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(thread) != 1 {
+		t.Fatalf("expected the comment, got %d comments", len(thread))
+	}
+	got := fmt.Sprint(thread[0].ReactionCounts, thread[0].MyReactions)
+	if want := fmt.Sprint(ReactionCounts{"like": 1}, []string{"like"}); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	reactors, err := reactions.GetCommentReactors(ctx, other, comment, author, ReactionQuery{PaginationQuery: PaginationQuery{Limit: 20}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reactors) != 0 {
+		t.Errorf("expected no reactor through another post, got %+v", reactors)
+	}
+}
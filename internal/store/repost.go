@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+const (
+	PostKindPost   = "post"
+	PostKindRepost = "repost"
+	PostKindQuote  = "quote"
+)
+
+// originalJoinSQL embeds, as o and ou, the original of a repost or quote when
+// the viewer may see it and it is not in the trash. Pass an empty viewer to
+// skip the visibility check.
+func originalJoinSQL(post, viewer string) string {
+	visible := "o.deleted_at IS NULL"
+	if viewer != "" {
+		visible += " AND " + canViewPostSQL("o", viewer)
+	}
+
+	return fmt.Sprintf(`LEFT JOIN posts o ON o.id = %s.original_id AND %s
+		LEFT JOIN users ou ON ou.id = o.user_id`, post, visible)
+}
+
+// originalColumnsSQL selects the original joined by originalJoinSQL, along
+// with its media and poll. The poll tallies are shown as to the viewer, or to
+// the author of the original for an empty viewer.
+func originalColumnsSQL(viewer string) string {
+	if viewer == "" {
+		viewer = "o.user_id"
+	}
+
+	return `o.id, o.title, o.content, o.tags, o.created_at, ou.id, ou.username,
+			` + postMediaSQL("o") + `,
+			` + postPollSQL("o", viewer)
+}
+
+// originalColumns scans the originalColumnsSQL columns.
+type originalColumns struct {
+	id        sql.NullInt64
+	title     sql.NullString
+	content   sql.NullString
+	tags      []string
+	createdAt sql.NullString
+	userID    sql.NullInt64
+	username  sql.NullString
+	media     MediaList
+	poll      []byte
+}
+
+func (c *originalColumns) dest() []any {
+	return []any{&c.id, &c.title, &c.content, pq.Array(&c.tags), &c.createdAt, &c.userID, &c.username, &c.media, &c.poll}
+}
+
+// apply embeds the original into the post. An original that was deleted or is
+// hidden from the viewer is reported as unavailable.
+func (c *originalColumns) apply(post *Post) error {
+	if post.Kind == PostKindPost || post.Kind == "" {
+		return nil
+	}
+
+	if !c.id.Valid {
+		post.OriginalUnavailable = true
+		return nil
+	}
+
+	poll, err := scanPoll(c.poll)
+	if err != nil {
+		return err
+	}
+
+	post.Original = &Post{
+		ID:        c.id.Int64,
+		Title:     c.title.String,
+		Content:   c.content.String,
+		UserID:    c.userID.Int64,
+		Tags:      c.tags,
+		CreatedAt: c.createdAt.String,
+		User:      &User{ID: c.userID.Int64, Username: c.username.String},
+		Media:     c.media,
+		Poll:      poll,
+	}
+	return nil
+}
+
+// Repost shares a post without content. Reposting a repost shares its original.
+// A user reposts a given post once, further attempts return ErrConflict.
+func (store *PostStore) Repost(ctx context.Context, originalID, userID int64) (*Post, error) {
+	post := &Post{
+		UserID: userID,
+		Kind:   PostKindRepost,
+		Tags:   []string{},
+	}
+
+	err := withTx(store.db, ctx, func(tx *sql.Tx) error {
+		return store.createShare(ctx, tx, post, originalID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// Quote shares a post along with the content of post. Quoting a repost quotes
+// its original. The users mentioned in the content are notified.
+func (store *PostStore) Quote(ctx context.Context, originalID int64, post *Post) error {
+	post.Kind = PostKindQuote
+
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		if err := store.createShare(ctx, tx, post, originalID); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if err := setPostMentions(ctx, tx, post.ID, post.Content); err != nil {
+			return err
+		}
+
+		return notifyPostMentions(ctx, tx, post.ID)
+	})
+}
+
+func (store *PostStore) createShare(ctx context.Context, tx *sql.Tx, post *Post, originalID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	// Only public posts of public accounts, or the user's own, can be shared:
+	// sharing anything else would show it to people its author did not pick.
+	lookup := `
+		SELECT
+			o.id,
+			NOT ` + notBlockedSQL("o.user_id", "$2") + `,
+			o.user_id = $2 OR (NOT u.is_private AND o.visibility = 'public')
+		FROM posts p
+		JOIN posts o ON o.id = CASE WHEN p.kind = 'repost' THEN p.original_id ELSE p.id END
+		JOIN users u ON o.user_id = u.id
+		WHERE p.id = $1
+			AND ` + canViewPostSQL("p", "$2") + `
+			AND ` + livePostSQL("p") + `
+			AND ` + livePostSQL("o") + `
+		`
+
+	var isBlocked, isShareable bool
+	if err := tx.QueryRowContext(ctx, lookup, originalID, post.UserID).Scan(
+		&originalID,
+		&isBlocked,
+		&isShareable,
+	); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return ErrorNotFound
+		default:
+			return err
+		}
+	}
+	if isBlocked || !isShareable {
+		return ErrBlocked
+	}
+
+	insert := `
+		INSERT INTO posts (title, content, user_id, tags, kind, original_id, published_at)
+		VALUES ($1, $2, $3, $4, $5, $6, now())
+		RETURNING id, created_at, updated_at, status, published_at, visibility
+		`
+
+	err := tx.QueryRowContext(
+		ctx,
+		insert,
+		post.Title,
+		post.Content,
+		post.UserID,
+		pq.Array(post.Tags),
+		post.Kind,
+		originalID,
+	).Scan(
+		&post.ID,
+		&post.CreatedAt,
+		&post.UpdatedAt,
+		&post.Status,
+		&post.PublishedAt,
+		&post.Visibility,
+	)
+	if err := constraintError(err); err != nil {
+		return err
+	}
+
+	post.OriginalID = &originalID
+	return nil
+}
+
+// DeleteRepost undoes the user's live repost of a post and returns its ID.
+func (store *PostStore) DeleteRepost(ctx context.Context, originalID, userID int64) (int64, error) {
+	query := `
+		DELETE FROM posts
+		WHERE kind = 'repost'
+			AND user_id = $2
+			AND deleted_at IS NULL
+			AND original_id = (SELECT CASE WHEN p.kind = 'repost' THEN p.original_id ELSE p.id END FROM posts p WHERE p.id = $1)
+		RETURNING id
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var id int64
+	if err := store.db.QueryRowContext(ctx, query, originalID, userID).Scan(&id); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return 0, ErrorNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
@@ -0,0 +1,175 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReposts(t *testing.T) {
+	db := newTestDB(t)
+	posts := &PostStore{db: db}
+	ctx := context.Background()
+
+	author := insertUser(t, db, "author")
+	sharer := insertUser(t, db, "sharer")
+	viewer := insertUser(t, db, "viewer")
+	blocked := insertUser(t, db, "blocked")
+	mustExec(t, db, `INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)`, author, blocked)
+
+	original := insertPost(t, db, author, "public")
+
+	repost, err := posts.Repost(ctx, original, sharer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repost.Kind != PostKindRepost || repost.OriginalID == nil || *repost.OriginalID != original {
+		t.Fatalf("expected a repost of %d, got %+v", original, repost)
+	}
+
+	t.Run("should repost a post once", func(t *testing.T) {
+		if _, err := posts.Repost(ctx, original, sharer); err != ErrConflict {
+			t.Errorf("expected ErrConflict, got %v", err)
+		}
+		if _, err := posts.Repost(ctx, repost.ID, sharer); err != ErrConflict {
+			t.Errorf("expected reposting the repost to be a conflict too, got %v", err)
+		}
+	})
+
+	t.Run("should share the original of a repost", func(t *testing.T) {
+		quote := &Post{UserID: viewer, Title: "title", Content: "look", Tags: []string{}}
+		if err := posts.Quote(ctx, repost.ID, quote); err != nil {
+			t.Fatal(err)
+		}
+		if quote.Kind != PostKindQuote || *quote.OriginalID != original {
+			t.Errorf("expected a quote of %d, got %+v", original, quote)
+		}
+
+		got, err := posts.GetVisibleByID(ctx, quote.ID, viewer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Original == nil || got.Original.ID != original || got.Content != "look" {
+			t.Errorf("expected the quote to embed %d, got %+v", original, got)
+		}
+	})
+
+	t.Run("should refuse to share what the user may not share", func(t *testing.T) {
+		if _, err := posts.Repost(ctx, original, blocked); err != ErrorNotFound {
+			t.Errorf("expected a post hidden by a block to be ErrorNotFound, got %v", err)
+		}
+
+		followers := insertPost(t, db, author, "followers")
+		mustExec(t, db, `INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)`, author, viewer)
+		if _, err := posts.Repost(ctx, followers, viewer); err != ErrBlocked {
+			t.Errorf("expected a followers-only post not to be shareable, got %v", err)
+		}
+		if _, err := posts.Repost(ctx, followers, author); err != nil {
+			t.Errorf("expected the author to share their own post, got %v", err)
+		}
+
+		private := insertPost(t, db, author, "private")
+		if _, err := posts.Repost(ctx, private, viewer); err != ErrorNotFound {
+			t.Errorf("expected a private post to be ErrorNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should report an original no longer visible", func(t *testing.T) {
+		mustExec(t, db, `UPDATE posts SET visibility = 'followers' WHERE id = $1`, original)
+
+		got, err := posts.GetVisibleByID(ctx, repost.ID, sharer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.OriginalUnavailable || got.Original != nil {
+			t.Errorf("expected the original to be unavailable, got %+v", got)
+		}
+
+		got, err = posts.GetVisibleByID(ctx, repost.ID, viewer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.OriginalUnavailable || got.Original == nil {
+			t.Errorf("expected a follower to still see the original, got %+v", got)
+		}
+
+		mustExec(t, db, `UPDATE posts SET deleted_at = now() WHERE id = $1`, original)
+
+		got, err = posts.GetVisibleByID(ctx, repost.ID, viewer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.OriginalUnavailable {
+			t.Errorf("expected a deleted original to be unavailable, got %+v", got)
+		}
+	})
+
+	t.Run("should undo the repost", func(t *testing.T) {
+		id, err := posts.DeleteRepost(ctx, original, sharer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != repost.ID {
+			t.Errorf("expected to delete %d, got %d", repost.ID, id)
+		}
+		if _, err := posts.DeleteRepost(ctx, original, sharer); err != ErrorNotFound {
+			t.Errorf("expected ErrorNotFound, got %v", err)
+		}
+	})
+}
+
+func TestRepostAgain(t *testing.T) {
+	db := newTestDB(t)
+	posts := &PostStore{db: db}
+	trash := &TrashStore{db: db}
+	ctx := context.Background()
+
+	author := insertUser(t, db, "author")
+	sharer := insertUser(t, db, "sharer")
+	original := insertPost(t, db, author, "public")
+
+	t.Run("should repost again after undoing the repost", func(t *testing.T) {
+		if _, err := posts.Repost(ctx, original, sharer); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := posts.DeleteRepost(ctx, original, sharer); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := posts.Repost(ctx, original, sharer); err != nil {
+			t.Errorf("expected to repost again, got %v", err)
+		}
+	})
+
+	t.Run("should repost again while the repost is in the trash", func(t *testing.T) {
+		mustExec(t, db, `DELETE FROM posts WHERE kind = 'repost'`)
+
+		repost, err := posts.Repost(ctx, original, sharer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := posts.Delete(ctx, repost.ID, 0, sharer); err != nil {
+			t.Fatal(err)
+		}
+
+		again, err := posts.Repost(ctx, original, sharer)
+		if err != nil {
+			t.Fatalf("expected to repost again, got %v", err)
+		}
+
+		since := time.Now().Add(-time.Hour)
+		if err := trash.Restore(ctx, TrashKindPost, repost.ID, since); err != ErrConflict {
+			t.Errorf("expected restoring the old repost to be a conflict, got %v", err)
+		}
+
+		id, err := posts.DeleteRepost(ctx, original, sharer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != again.ID {
+			t.Errorf("expected to undo the live repost %d, got %d", again.ID, id)
+		}
+		if err := trash.Restore(ctx, TrashKindPost, repost.ID, since); err != nil {
+			t.Errorf("expected to restore the old repost, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// PostRevision is a prior version of a post, kept when an edit replaced it.
+type PostRevision struct {
+	PostID     int64    `json:"post_id"`
+	Version    int64    `json:"version"`
+	Title      string   `json:"title"`
+	Content    string   `json:"content"`
+	Tags       []string `json:"tags"`
+	ReplacedBy *int64   `json:"replaced_by"`
+	ReplacedAt string   `json:"replaced_at"`
+}
+
+// GetRevisions lists the prior versions of a post, newest first.
+func (store *PostStore) GetRevisions(ctx context.Context, postID int64, page PaginationQuery) ([]PostRevision, error) {
+	query := `
+		SELECT post_id, version, title, content, tags, replaced_by, replaced_at
+		FROM post_revisions
+		WHERE post_id = $1
+		ORDER BY version DESC
+		LIMIT $2 OFFSET $3
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, postID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []PostRevision{}
+	for rows.Next() {
+		var revision PostRevision
+		if err := rows.Scan(
+			&revision.PostID,
+			&revision.Version,
+			&revision.Title,
+			&revision.Content,
+			pq.Array(&revision.Tags),
+			&revision.ReplacedBy,
+			&revision.ReplacedAt,
+		); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (store *PostStore) GetRevision(ctx context.Context, postID, version int64) (*PostRevision, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return getRevision(ctx, store.db, postID, version)
+}
+
+// RestoreRevision makes a prior version the current one. Like Update, the
+// version it replaces is kept as a revision and post.Version must be current,
+// ErrVersionMismatch is returned otherwise.
+func (store *PostStore) RestoreRevision(ctx context.Context, post *Post, version, editorID int64) error {
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		revision, err := getRevision(ctx, tx, post.ID, version)
+		if err != nil {
+			return err
+		}
+
+		post.Title = revision.Title
+		post.Content = revision.Content
+		post.Tags = revision.Tags
+
+		return updatePost(ctx, tx, post, editorID)
+	})
+}
+
+type queryRower interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}
+
+func getRevision(ctx context.Context, db queryRower, postID, version int64) (*PostRevision, error) {
+	query := `
+		SELECT post_id, version, title, content, tags, replaced_by, replaced_at
+		FROM post_revisions
+		WHERE post_id = $1 AND version = $2
+		`
+
+	var revision PostRevision
+	err := db.QueryRowContext(ctx, query, postID, version).Scan(
+		&revision.PostID,
+		&revision.Version,
+		&revision.Title,
+		&revision.Content,
+		pq.Array(&revision.Tags),
+		&revision.ReplacedBy,
+		&revision.ReplacedAt,
+	)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &revision, nil
+}
+
+// lockPostVersion locks the post for the rest of the transaction and checks
+// it is still at the given version.
+func lockPostVersion(ctx context.Context, tx *sql.Tx, id, version int64) error {
+	var current int64
+	err := tx.QueryRowContext(ctx, `SELECT version FROM posts WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&current)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return ErrorNotFound
+		default:
+			return err
+		}
+	}
+	if current != version {
+		return ErrVersionMismatch
+	}
+
+	return nil
+}
+
+// updatePost keeps the current version of the post as a revision and writes
+// the new one. The row is locked first, so of two edits made from the same
+// version only the first goes through and the other gets ErrVersionMismatch.
+// Drafts and scheduled posts are edited in place, revisions and edited_at only
+// start once the post is published. Users newly mentioned in a live post are
+// notified.
+func updatePost(ctx context.Context, tx *sql.Tx, post *Post, editorID int64) error {
+	if err := lockPostVersion(ctx, tx, post.ID, post.Version); err != nil {
+		return err
+	}
+
+	archive := `
+		INSERT INTO post_revisions (post_id, version, title, content, tags, replaced_by)
+		SELECT id, version, title, content, tags, $2
+		FROM posts
+		WHERE id = $1 AND status = 'published'
+		`
+
+	if _, err := tx.ExecContext(ctx, archive, post.ID, editorID); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE posts
+		SET title = $1, content = $2, tags = $3, version = version + 1,
+			status = $5, publish_at = $6, visibility = $7,
+			published_at = COALESCE(published_at, CASE WHEN $5 = 'published' THEN now() END),
+			edited_at = CASE WHEN status = 'published' THEN now() ELSE edited_at END,
+			updated_at = now()
+		WHERE id = $4
+		RETURNING version, edited_at, updated_at, published_at
+		`
+
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		post.Title,
+		post.Content,
+		pq.Array(post.Tags),
+		post.ID,
+		post.Status,
+		post.PublishAt,
+		post.Visibility,
+	).Scan(
+		&post.Version,
+		&post.EditedAt,
+		&post.UpdatedAt,
+		&post.PublishedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := setPostMentions(ctx, tx, post.ID, post.Content); err != nil {
+		return err
+	}
+
+	return notifyPostMentions(ctx, tx, post.ID)
+}
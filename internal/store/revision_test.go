@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestPostRevisions(t *testing.T) {
+	db := newTestDB(t)
+	store := &PostStore{db: db}
+	ctx := context.Background()
+
+	author := insertUser(t, db, "author")
+	editor := insertUser(t, db, "editor")
+	id := insertPost(t, db, author, "public")
+
+	edit := func(t *testing.T, content string, tags []string) *Post {
+		t.Helper()
+
+		post, err := store.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		post.Content = content
+		post.Tags = tags
+		if err := store.Update(ctx, post, editor); err != nil {
+			t.Fatal(err)
+		}
+		return post
+	}
+
+	edit(t, "second", []string{"go"})
+	post := edit(t, "third", []string{"web"})
+	if post.Version != 2 || post.EditedAt == nil {
+		t.Fatalf("expected an edited version 2, got %+v", post)
+	}
+
+	t.Run("should list the prior versions", func(t *testing.T) {
+		revisions, err := store.GetRevisions(ctx, id, PaginationQuery{Limit: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := []string{}
+		for _, revision := range revisions {
+			got = append(got, fmt.Sprint(revision.Version, revision.Content, revision.Tags, *revision.ReplacedBy))
+		}
+		want := []string{
+			fmt.Sprint(1, "second", []string{"go"}, editor),
+			fmt.Sprint(0, "content", []string{}, editor),
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("should not restore over a stale version", func(t *testing.T) {
+		stale := *post
+		stale.Version = 1
+		if err := store.RestoreRevision(ctx, &stale, 0, editor); err != ErrVersionMismatch {
+			t.Errorf("expected ErrVersionMismatch, got %v", err)
+		}
+	})
+
+	t.Run("should not restore a missing version", func(t *testing.T) {
+		if err := store.RestoreRevision(ctx, post, 7, editor); err != ErrorNotFound {
+			t.Errorf("expected ErrorNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should restore a prior version as a new one", func(t *testing.T) {
+		if err := store.RestoreRevision(ctx, post, 0, editor); err != nil {
+			t.Fatal(err)
+		}
+		if post.Version != 3 || post.Content != "content" {
+			t.Errorf("expected version 3 with the first content, got %+v", post)
+		}
+
+		revision, err := store.GetRevision(ctx, id, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revision.Content != "third" {
+			t.Errorf("expected the replaced version to be kept, got %+v", revision)
+		}
+	})
+
+	t.Run("should not keep revisions of drafts", func(t *testing.T) {
+		draft := insertID(t, db, `
+			INSERT INTO posts (user_id, title, content, tags, status)
+			VALUES ($1, 'title', 'content', '{}', 'draft')
+			RETURNING id
+			`, author)
+
+		post, err := store.GetByID(ctx, draft)
+		if err != nil {
+			t.Fatal(err)
+		}
+		post.Content = "edited"
+		if err := store.Update(ctx, post, author); err != nil {
+			t.Fatal(err)
+		}
+
+		revisions, err := store.GetRevisions(ctx, draft, PaginationQuery{Limit: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revisions) != 0 || post.EditedAt != nil {
+			t.Errorf("expected a draft to be edited in place, got %d revisions", len(revisions))
+		}
+	})
+}
@@ -0,0 +1,375 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const (
+	SearchTypePosts    = "posts"
+	SearchTypeComments = "comments"
+	SearchTypeUsers    = "users"
+)
+
+// snippetStart and snippetStop mark the matches in the output of ts_headline.
+// They are private use characters stripped from the text beforehand, so they
+// only come from ts_headline, and become <mark> tags once the rest is escaped.
+const (
+	snippetStart = "\uE000"
+	snippetStop  = "\uE001"
+)
+
+const (
+	headlineOptions = `StartSel="` + snippetStart + `", StopSel="` + snippetStop + `"`
+	headlineTextSQL = `translate(page.body, '` + snippetStart + snippetStop + `', '')`
+)
+
+var snippetMarks = strings.NewReplacer(snippetStart, "<mark>", snippetStop, "</mark>")
+
+// SearchResult is a post, comment or user matching a search, with a snippet of
+// the match. The snippet is HTML, the text escaped and the matches in <mark>.
+// Media and polls are left out, clients load the post to show them.
+type SearchResult struct {
+	Type      string   `json:"type"`
+	ID        int64    `json:"id"`
+	PostID    int64    `json:"post_id,omitempty"`
+	UserID    int64    `json:"user_id"`
+	Username  string   `json:"username"`
+	Title     string   `json:"title,omitempty"`
+	Snippet   string   `json:"snippet"`
+	Tags      []string `json:"tags,omitempty"`
+	Rank      float64  `json:"rank"`
+	CreatedAt string   `json:"created_at"`
+}
+
+type SearchQuery struct {
+	Query string `json:"q" validate:"required,max=200"`
+	Type  string `json:"type" validate:"omitempty,oneof=posts comments users"`
+	Limit int64  `json:"limit" validate:"gte=1,lte=50"`
+	// Offset is bounded so that a page stays within what the search backends
+	// scan to fill it.
+	Offset int64 `json:"offset" validate:"gte=0,lte=1000"`
+	// ViewerID is the user searching, results are limited to what they may see.
+	ViewerID int64 `json:"-"`
+}
+
+func (sq SearchQuery) Parse(r *http.Request) (SearchQuery, error) {
+	query := r.URL.Query()
+
+	sq.Query = strings.TrimSpace(query.Get("q"))
+
+	searchType := query.Get("type")
+	if searchType != "" {
+		sq.Type = searchType
+	}
+
+	limit := query.Get("limit")
+	if limit != "" {
+		l, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			return sq, err
+		}
+		sq.Limit = l
+	}
+
+	offset := query.Get("offset")
+	if offset != "" {
+		o, err := strconv.ParseInt(offset, 10, 64)
+		if err != nil {
+			return sq, err
+		}
+		sq.Offset = o
+	}
+
+	return sq, nil
+}
+
+// ParsedSearch splits a raw query into the part handed to websearch_to_tsquery
+// (plain words, "quoted phrases" and -exclusions) and the #tag / @user filters.
+// Terms, Phrases and Exclude break Text down for backends that are not Postgres.
+type ParsedSearch struct {
+	Text    string
+	Tags    []string
+	Users   []string
+	Terms   []string
+	Phrases []string
+	Exclude []string
+}
+
+func ParseSearch(raw string) ParsedSearch {
+	var parsed ParsedSearch
+	var text []string
+
+	for _, token := range splitSearchTokens(raw) {
+		switch {
+		case len(token) > 1 && token[0] == '#':
+			parsed.Tags = append(parsed.Tags, NormalizeTag(token))
+		case len(token) > 1 && token[0] == '@':
+			parsed.Users = append(parsed.Users, token[1:])
+		case len(token) > 1 && token[0] == '-':
+			parsed.Exclude = append(parsed.Exclude, strings.Trim(token[1:], `"`))
+			text = append(text, token)
+		case len(token) > 1 && token[0] == '"':
+			parsed.Phrases = append(parsed.Phrases, strings.Trim(token, `"`))
+			text = append(text, token)
+		default:
+			parsed.Terms = append(parsed.Terms, token)
+			text = append(text, token)
+		}
+	}
+
+	parsed.Text = strings.Join(text, " ")
+	if parsed.Tags == nil {
+		parsed.Tags = []string{}
+	}
+	if parsed.Users == nil {
+		parsed.Users = []string{}
+	}
+
+	return parsed
+}
+
+// splitSearchTokens splits on whitespace but keeps "quoted phrases" (including
+// a leading minus) together as a single token.
+func splitSearchTokens(raw string) []string {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case (r == ' ' || r == '\t' || r == '\n') && !inQuotes:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens
+}
+
+type SearchStore struct {
+	db *sql.DB
+}
+
+func (store *SearchStore) Search(ctx context.Context, sq SearchQuery) ([]SearchResult, error) {
+	parsed := ParseSearch(sq.Query)
+
+	query := `
+		WITH q AS (
+			SELECT websearch_to_tsquery('english', $1) AS query
+		),
+		page AS (
+			SELECT type, id, post_id, user_id, username, title, body, tags, rank, created_at
+			FROM (
+				SELECT
+					'posts' AS type, p.id, p.id AS post_id, p.user_id, u.username, p.title,
+					p.content AS body,
+					p.tags,
+					ts_rank_cd(p.search_vector, q.query) AS rank,
+					p.created_at
+				FROM posts p
+				JOIN users u ON p.user_id = u.id
+				CROSS JOIN q
+				WHERE ($4 = '' OR $4 = 'posts')
+					AND p.kind <> 'repost'
+					AND ` + livePostSQL("p") + `
+					AND ` + canViewPostSQL("p", "$7") + `
+					AND ($1 = '' OR p.search_vector @@ q.query)
+					AND (p.tags @> $2 OR $2 = '{}')
+					AND (u.username = ANY($3) OR $3 = '{}')
+
+				UNION ALL
+
+				SELECT
+					'comments' AS type, c.id, c.post_id, c.user_id, u.username, '' AS title,
+					c.content AS body,
+					'{}'::TEXT[] AS tags,
+					ts_rank_cd(c.search_vector, q.query) AS rank,
+					c.created_at
+				FROM comments c
+				JOIN users u ON c.user_id = u.id
+				JOIN posts p ON c.post_id = p.id
+				CROSS JOIN q
+				WHERE ($4 = '' OR $4 = 'comments')
+					AND ` + livePostSQL("p") + `
+					AND ` + canViewPostSQL("p", "$7") + `
+					AND ` + notBlockedSQL("c.user_id", "$7") + `
+					AND c.deleted_at IS NULL
+					AND ($1 = '' OR c.search_vector @@ q.query)
+					AND (p.tags @> $2 OR $2 = '{}')
+					AND (u.username = ANY($3) OR $3 = '{}')
+
+				UNION ALL
+
+				SELECT
+					'users' AS type, u.id, 0 AS post_id, u.id AS user_id, u.username, '' AS title,
+					u.username AS body,
+					'{}'::TEXT[] AS tags,
+					ts_rank_cd(u.search_vector, q.query) AS rank,
+					u.created_at
+				FROM users u
+				CROSS JOIN q
+				WHERE ($4 = '' OR $4 = 'users')
+					AND u.is_activated = true
+					AND ` + notBlockedSQL("u.id", "$7") + `
+					AND $2 = '{}'
+					AND (
+						($1 <> '' AND u.search_vector @@ q.query)
+						OR u.username = ANY($3)
+					)
+			) matches
+			ORDER BY rank DESC, created_at DESC
+			LIMIT $5 OFFSET $6
+		)
+		-- headlines are costly, they are only made for the page
+		SELECT
+			page.type, page.id, page.post_id, page.user_id, page.username, page.title,
+			CASE page.type
+				WHEN 'users' THEN ts_headline('simple', ` + headlineTextSQL + `, q.query, '` + headlineOptions + `')
+				ELSE ts_headline('english', ` + headlineTextSQL + `, q.query, '` + headlineOptions + `, MaxFragments=2, MaxWords=30, MinWords=10')
+			END AS snippet,
+			page.tags, page.rank, page.created_at
+		FROM page
+		CROSS JOIN q
+		ORDER BY page.rank DESC, page.created_at DESC
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(
+		ctx,
+		query,
+		parsed.Text,
+		pq.Array(parsed.Tags),
+		pq.Array(parsed.Users),
+		sq.Type,
+		sq.Limit,
+		sq.Offset,
+		sq.ViewerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() {
+		var result SearchResult
+		if err := rows.Scan(
+			&result.Type,
+			&result.ID,
+			&result.PostID,
+			&result.UserID,
+			&result.Username,
+			&result.Title,
+			&result.Snippet,
+			pq.Array(&result.Tags),
+			&result.Rank,
+			&result.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		result.Snippet = snippetMarks.Replace(html.EscapeString(result.Snippet))
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Visible keeps the results, found by a search backend other than Postgres,
+// that the viewer may see. The index does not know who can see what, so posts,
+// comments and users are checked against the database like the Postgres
+// search does.
+func (store *SearchStore) Visible(ctx context.Context, results []SearchResult, viewerID int64) ([]SearchResult, error) {
+	var postIDs, commentIDs, userIDs []int64
+	for _, result := range results {
+		switch result.Type {
+		case SearchTypePosts:
+			postIDs = append(postIDs, result.ID)
+		case SearchTypeComments:
+			commentIDs = append(commentIDs, result.ID)
+		case SearchTypeUsers:
+			userIDs = append(userIDs, result.ID)
+		}
+	}
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	query := `
+		SELECT 'posts', p.id
+		FROM posts p
+		WHERE p.id = ANY($1)
+			AND ` + livePostSQL("p") + `
+			AND ` + canViewPostSQL("p", "$3") + `
+		UNION ALL
+		SELECT 'comments', c.id
+		FROM comments c
+		JOIN posts p ON c.post_id = p.id
+		WHERE c.id = ANY($2)
+			AND c.deleted_at IS NULL
+			AND ` + livePostSQL("p") + `
+			AND ` + canViewPostSQL("p", "$3") + `
+			AND ` + notBlockedSQL("c.user_id", "$3") + `
+		UNION ALL
+		SELECT 'users', u.id
+		FROM users u
+		WHERE u.id = ANY($4)
+			AND ` + notBlockedSQL("u.id", "$3") + `
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, pq.Array(postIDs), pq.Array(commentIDs), viewerID, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	visible := make(map[string]bool)
+	for rows.Next() {
+		var resultType string
+		var id int64
+		if err := rows.Scan(&resultType, &id); err != nil {
+			return nil, err
+		}
+		visible[fmt.Sprintf("%s:%d", resultType, id)] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	kept := []SearchResult{}
+	for _, result := range results {
+		if visible[fmt.Sprintf("%s:%d", result.Type, result.ID)] {
+			kept = append(kept, result)
+		}
+	}
+
+	return kept, nil
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseSearch(t *testing.T) {
+	parsed := ParseSearch(`gopher #Go #ＣＡＦＥ @alice "go fmt" -java`)
+
+	if got := fmt.Sprint(parsed.Tags); got != "[go cafe]" {
+		t.Errorf("expected the tags to be normalized, got %s", got)
+	}
+	if got := fmt.Sprint(parsed.Users); got != "[alice]" {
+		t.Errorf("expected [alice], got %s", got)
+	}
+	if parsed.Text != `gopher "go fmt" -java` {
+		t.Errorf("expected the tags and users left out of the text, got %s", parsed.Text)
+	}
+}
+
+func TestSearchVisible(t *testing.T) {
+	db := newTestDB(t)
+	store := &SearchStore{db: db}
+
+	author := insertUser(t, db, "author")
+	follower := insertUser(t, db, "follower")
+	stranger := insertUser(t, db, "stranger")
+	mentioned := insertUser(t, db, "mentioned")
+	blocked := insertUser(t, db, "blocked")
+	locked := insertUser(t, db, "locked")
+
+	mustExec(t, db, `UPDATE users SET is_private = true WHERE id = $1`, locked)
+	mustExec(t, db, `INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)`, author, follower)
+	mustExec(t, db, `INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)`, author, blocked)
+
+	public := insertPost(t, db, author, "public")
+	followers := insertPost(t, db, author, "followers")
+	mention := insertPost(t, db, author, "mentioned")
+	private := insertPost(t, db, author, "private")
+	draft := insertPost(t, db, author, "public")
+	deleted := insertPost(t, db, author, "public")
+	lockedPost := insertPost(t, db, locked, "public")
+
+	mustExec(t, db, `INSERT INTO post_mentions (post_id, user_id) VALUES ($1, $2)`, mention, mentioned)
+	mustExec(t, db, `UPDATE posts SET status = 'draft' WHERE id = $1`, draft)
+	mustExec(t, db, `UPDATE posts SET deleted_at = now() WHERE id = $1`, deleted)
+
+	onPublic := insertComment(t, db, public, stranger)
+	onPrivate := insertComment(t, db, private, author)
+	byBlocked := insertComment(t, db, lockedPost, blocked)
+
+	results := []SearchResult{}
+	for _, id := range []int64{public, followers, mention, private, draft, deleted, lockedPost} {
+		results = append(results, SearchResult{Type: SearchTypePosts, ID: id})
+	}
+	for _, id := range []int64{onPublic, onPrivate, byBlocked} {
+		results = append(results, SearchResult{Type: SearchTypeComments, ID: id})
+	}
+	for _, id := range []int64{author, blocked} {
+		results = append(results, SearchResult{Type: SearchTypeUsers, ID: id})
+	}
+
+	key := func(resultType string, id int64) string {
+		return fmt.Sprintf("%s:%d", resultType, id)
+	}
+
+	tests := map[string]struct {
+		viewer int64
+		want   []string
+	}{
+		"author": {author, []string{
+			key("posts", public), key("posts", followers), key("posts", mention), key("posts", private),
+			key("comments", onPublic), key("comments", onPrivate),
+			key("users", author),
+		}},
+		"follower": {follower, []string{
+			key("posts", public), key("posts", followers),
+			key("comments", onPublic),
+			key("users", author), key("users", blocked),
+		}},
+		"mentioned": {mentioned, []string{
+			key("posts", public), key("posts", mention),
+			key("comments", onPublic),
+			key("users", author), key("users", blocked),
+		}},
+		"stranger": {stranger, []string{
+			key("posts", public),
+			key("comments", onPublic),
+			key("users", author), key("users", blocked),
+		}},
+		"blocked": {blocked, []string{
+			key("users", blocked),
+		}},
+		"locked": {locked, []string{
+			key("posts", public), key("posts", lockedPost),
+			key("comments", onPublic), key("comments", byBlocked),
+			key("users", author), key("users", blocked),
+		}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			visible, err := store.Visible(context.Background(), results, tt.viewer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, result := range visible {
+				got = append(got, key(result.Type, result.ID))
+			}
+			sort.Strings(got)
+			sort.Strings(tt.want)
+
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSearchSnippet(t *testing.T) {
+	db := newTestDB(t)
+	store := &SearchStore{db: db}
+
+	author := insertUser(t, db, "author")
+	post := insertPost(t, db, author, "public")
+	mustExec(t, db, `UPDATE posts SET content = $1 WHERE id = $2`, "<img src=x onerror=alert(1)> gophers \uE000\uE001", post)
+
+	results, err := store.Search(context.Background(), SearchQuery{Query: "gophers", Type: SearchTypePosts, Limit: 10, ViewerID: author})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected a result, got %d", len(results))
+	}
+
+	snippet := results[0].Snippet
+	if !strings.Contains(snippet, "&lt;img") || strings.Contains(snippet, "<img") {
+		t.Errorf("expected the content to be escaped, got %s", snippet)
+	}
+	if strings.Count(snippet, "<mark>") != 1 || strings.Count(snippet, "</mark>") != 1 {
+		t.Errorf("expected only the match to be marked, got %s", snippet)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 var (
@@ -13,48 +15,173 @@ var (
 	QueryTimeoutDuration   = time.Second * 5
 	ErrorDuplicateEmail    = errors.New("email already used")
 	ErrorDuplicateUsername = errors.New("username already exists")
+	ErrBlocked             = errors.New("interaction blocked between users")
+	ErrVersionMismatch     = errors.New("resource was modified since it was read")
 )
 
 type Storage struct {
 	Post interface {
 		Create(context.Context, *Post) (*CreatePostResponse, error)
 		GetByID(context.Context, int64) (*Post, error)
-		Delete(context.Context, int64) error
-		Update(context.Context, *Post) error
+		GetVisibleByID(context.Context, int64, int64) (*Post, error)
+		Repost(context.Context, int64, int64) (*Post, error)
+		Quote(context.Context, int64, *Post) error
+		DeleteRepost(context.Context, int64, int64) (int64, error)
+		Delete(context.Context, int64, int64, int64) error
+		Update(context.Context, *Post, int64) error
+		GetRevisions(context.Context, int64, PaginationQuery) ([]PostRevision, error)
+		GetRevision(context.Context, int64, int64) (*PostRevision, error)
+		RestoreRevision(context.Context, *Post, int64, int64) error
 		GetByUserId(context.Context, int64, PaginationFeedQuery) ([]*PostWithMetadata, error)
+		SetModerated(context.Context, int64, int64, bool) error
+		GetDrafts(context.Context, int64, PaginationQuery) ([]Post, error)
+		PublishScheduled(context.Context, time.Time, int) ([]*Post, error)
 	}
 
 	User interface {
 		Create(context.Context, *sql.Tx, *User) error
 		GetById(context.Context, int64) (*User, error)
+		GetProfile(context.Context, int64, int64) (*UserProfile, error)
 		CreateAndInvite(context.Context, *User, string, time.Duration) error
-		Activate(context.Context, string) error
+		Activate(context.Context, string) (*User, error)
 		Delete(context.Context, int64) error
 		GetByEmail(context.Context, string) (*User, error)
+		SetPrivacy(context.Context, int64, bool) error
 	}
 
 	Comment interface {
-		GetCommentByPostId(context.Context, int64) ([]Comment, error)
+		GetByID(context.Context, int64, int64, int64) (*Comment, error)
+		GetShownByID(context.Context, int64, int64, int64) (*Comment, error)
+		GetCommentByPostId(context.Context, int64, int64, CursorQuery) ([]*Comment, error)
+		GetReplies(context.Context, int64, int64, int64, CursorQuery) ([]*Comment, error)
+		GetLiveByPostID(context.Context, int64) ([]*Comment, error)
 		Create(context.Context, *Comment) error
+		Update(context.Context, *Comment) error
+		Delete(context.Context, int64, int64) error
 	}
 
 	Follow interface {
-		Follow(context.Context, int64, int64) error
+		Follow(context.Context, int64, int64) (string, error)
 		Unfollow(context.Context, int64, int64) error
+		GetRequests(context.Context, int64, PaginationQuery) ([]FollowRequest, error)
+		ApproveRequest(context.Context, int64, int64) error
+		RejectRequest(context.Context, int64, int64) error
+		GetFollowers(context.Context, int64, int64, PaginationQuery) ([]FollowListEntry, error)
+		GetFollowing(context.Context, int64, int64, PaginationQuery) ([]FollowListEntry, error)
+	}
+
+	Block interface {
+		Block(context.Context, int64, int64) error
+		Unblock(context.Context, int64, int64) error
+		Mute(context.Context, int64, int64) error
+		Unmute(context.Context, int64, int64) error
+		GetBlocked(context.Context, int64, PaginationQuery) ([]BlockedUser, error)
+		GetMuted(context.Context, int64, PaginationQuery) ([]BlockedUser, error)
+	}
+
+	MuteFilter interface {
+		Create(context.Context, *MuteFilter) error
+		Delete(context.Context, int64, int64) error
+		GetByUserID(context.Context, int64) ([]MuteFilter, error)
+	}
+
+	Suggestions interface {
+		Stale(context.Context, time.Time, time.Duration, int) ([]int64, error)
+		Compute(context.Context, []int64, time.Time) error
+		GetByUserID(context.Context, int64) ([]Suggestion, error)
+	}
+
+	Reactions interface {
+		AddToPost(context.Context, int64, int64, string) error
+		RemoveFromPost(context.Context, int64, int64, string) error
+		GetPostReactors(context.Context, int64, int64, ReactionQuery) ([]Reactor, error)
+		AddToComment(context.Context, int64, int64, int64, string) error
+		RemoveFromComment(context.Context, int64, int64, string) error
+		GetCommentReactors(context.Context, int64, int64, int64, ReactionQuery) ([]Reactor, error)
+	}
+
+	Bookmarks interface {
+		Save(context.Context, int64, int64, *int64) (*Bookmark, error)
+		Remove(context.Context, int64, int64) error
+		List(context.Context, int64, BookmarkQuery) ([]Bookmark, error)
+		CreateCollection(context.Context, *BookmarkCollection) error
+		GetCollections(context.Context, int64) ([]BookmarkCollection, error)
+		DeleteCollection(context.Context, int64, int64) error
+	}
+
+	Tags interface {
+		Autocomplete(context.Context, TagQuery) ([]Tag, error)
+	}
+
+	TagFollow interface {
+		Follow(context.Context, int64, string) error
+		Unfollow(context.Context, int64, string) error
+		GetByUserID(context.Context, int64) ([]TagFollow, error)
 	}
 
 	Roles interface {
 		GetByName(context.Context, string) (*Role, error)
 	}
+
+	Search interface {
+		Search(context.Context, SearchQuery) ([]SearchResult, error)
+		Visible(context.Context, []SearchResult, int64) ([]SearchResult, error)
+	}
+
+	Trends interface {
+		Compute(context.Context, time.Time) error
+		Prune(context.Context, time.Time) error
+		GetTags(context.Context, TrendQuery) ([]TrendingTag, error)
+		GetPosts(context.Context, TrendQuery, int64) ([]TrendingPost, error)
+	}
+
+	Trash interface {
+		List(context.Context, int64, time.Time, PaginationQuery) ([]TrashItem, error)
+		Get(context.Context, string, int64, time.Time) (*TrashItem, error)
+		Restore(context.Context, string, int64, time.Time) error
+		Purge(context.Context, time.Time, int) (int64, error)
+	}
+
+	Media interface {
+		Create(context.Context, *Media) error
+		GetOrphans(context.Context, time.Time, int) ([]Media, error)
+		GetPending(context.Context, int) ([]Media, error)
+		SetProcessed(context.Context, *Media) error
+		SetAttemptFailed(context.Context, int64) error
+		Delete(context.Context, int64) ([]string, error)
+	}
+
+	Polls interface {
+		Vote(context.Context, int64, int64, []int64) error
+		Close(context.Context, time.Time) (int64, error)
+	}
+
+	Notifications interface {
+		List(context.Context, int64, CursorQuery) ([]Notification, error)
+		MarkRead(context.Context, int64, int64) error
+	}
 }
 
 func NewStorage(db *sql.DB) *Storage {
 	return &Storage{
-		Post:    &PostStore{db: db},
-		User:    &UserStore{db: db},
-		Comment: &CommentStore{db: db},
-		Follow:  &FollowStore{db: db},
-		Roles:   &RoleStore{db: db},
+		Post:          &PostStore{db: db},
+		User:          &UserStore{db: db},
+		Comment:       &CommentStore{db: db},
+		Follow:        &FollowStore{db: db},
+		Tags:          &TagStore{db: db},
+		TagFollow:     &TagFollowStore{db: db},
+		Block:         &BlockStore{db: db},
+		MuteFilter:    &MuteFilterStore{db: db},
+		Suggestions:   &SuggestionStore{db: db},
+		Reactions:     &ReactionStore{db: db},
+		Bookmarks:     &BookmarkStore{db: db},
+		Roles:         &RoleStore{db: db},
+		Search:        &SearchStore{db: db},
+		Trends:        &TrendStore{db: db},
+		Trash:         &TrashStore{db: db},
+		Media:         &MediaStore{db: db},
+		Polls:         &PollStore{db: db},
+		Notifications: &NotificationStore{db: db},
 	}
 }
 
@@ -72,3 +199,33 @@ func withTx(db *sql.DB, ctx context.Context, fn func(tx *sql.Tx) error) error {
 	}
 	return tx.Commit()
 }
+
+// tryAdvisoryLock takes the advisory lock id for the rest of the transaction,
+// unless another transaction holds it, and tells whether it did.
+func tryAdvisoryLock(ctx context.Context, tx *sql.Tx, id int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var locked bool
+	err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, id).Scan(&locked)
+	return locked, err
+}
+
+// constraintError maps the violations of a unique constraint to ErrConflict,
+// and of a foreign key to ErrorNotFound.
+func constraintError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code {
+		case "23505":
+			return ErrConflict
+		case "23503":
+			return ErrorNotFound
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,242 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SuggestionsPerUser is the number of suggestions kept per user.
+const SuggestionsPerUser = 50
+
+// suggestionsLockID is the advisory lock held while computing suggestions, so
+// the API instances running the job do not replace the same ones at once.
+const suggestionsLockID = 7_284_011_034
+
+type Suggestion struct {
+	ID              int64   `json:"id"`
+	Username        string  `json:"username"`
+	FollowersCount  int64   `json:"followers_count"`
+	Score           float64 `json:"score"`
+	MutualCount     int64   `json:"mutual_count"`
+	SharedTagsCount int64   `json:"shared_tags_count"`
+}
+
+type SuggestionStore struct {
+	db *sql.DB
+}
+
+// Stale returns the users whose suggestions need a refresh: never computed,
+// older than maxAge, or who followed someone or a tag since the last run.
+// Users never computed come first.
+func (store *SuggestionStore) Stale(ctx context.Context, now time.Time, maxAge time.Duration, limit int) ([]int64, error) {
+	query := `
+		SELECT u.id
+		FROM users u
+		LEFT JOIN follow_suggestion_runs r ON r.user_id = u.id
+		WHERE u.is_activated = true
+			AND (
+				r.computed_at IS NULL
+				OR r.computed_at < $1::timestamptz - make_interval(secs => $2)
+				OR EXISTS (SELECT 1 FROM followers f WHERE f.follower_id = u.id AND f.created_at > r.computed_at)
+				OR EXISTS (SELECT 1 FROM user_tag_follows tf WHERE tf.user_id = u.id AND tf.created_at > r.computed_at)
+			)
+		ORDER BY r.computed_at NULLS FIRST, u.id
+		LIMIT $3
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, now, maxAge.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Compute replaces the suggestions of the given users. Candidates are friends
+// of friends, authors recently posting with tags the user follows and the most
+// active authors of the week, so new users get suggestions too. They are
+// ranked by mutual follows, shared tags, recent activity and popularity.
+// Nothing is computed while another instance is computing.
+func (store *SuggestionStore) Compute(ctx context.Context, userIDs []int64, now time.Time) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		WITH targets AS (
+			SELECT unnest($1::BIGINT[]) AS id
+		),
+		candidates AS (
+			SELECT mine.follower_id AS user_id, theirs.user_id AS suggested_id
+			FROM followers mine
+			JOIN followers theirs ON theirs.follower_id = mine.user_id
+			WHERE mine.follower_id IN (SELECT id FROM targets)
+			UNION
+			SELECT tf.user_id, p.user_id
+			FROM user_tag_follows tf
+			JOIN posts p ON p.published_at >= $2::timestamptz - interval '30 days' AND p.deleted_at IS NULL
+			WHERE tf.user_id IN (SELECT id FROM targets)
+				AND EXISTS (SELECT 1 FROM unnest(p.tags) AS pt(tag) WHERE pt.tag = tf.tag)
+			UNION
+			SELECT t.id, a.user_id
+			FROM targets t
+			CROSS JOIN (
+				SELECT p.user_id
+				FROM posts p
+				WHERE p.published_at >= $2::timestamptz - interval '7 days' AND p.deleted_at IS NULL
+				GROUP BY p.user_id
+				ORDER BY COUNT(*) DESC
+				LIMIT 50
+			) a
+		),
+		scored AS (
+			SELECT
+				c.user_id,
+				c.suggested_id,
+				(
+					SELECT COUNT(*)
+					FROM followers mine
+					JOIN followers theirs ON theirs.follower_id = mine.user_id
+					WHERE mine.follower_id = c.user_id AND theirs.user_id = c.suggested_id
+				) AS mutual_count,
+				(
+					SELECT COUNT(DISTINCT tf.tag)
+					FROM user_tag_follows tf
+					JOIN posts p ON p.user_id = c.suggested_id
+						AND p.published_at >= $2::timestamptz - interval '30 days'
+						AND p.deleted_at IS NULL,
+					unnest(p.tags) AS pt(tag)
+					WHERE tf.user_id = c.user_id AND tf.tag = pt.tag
+				) AS shared_tags_count,
+				(
+					SELECT COUNT(*)
+					FROM posts p
+					WHERE p.user_id = c.suggested_id
+						AND p.published_at >= $2::timestamptz - interval '7 days'
+						AND p.deleted_at IS NULL
+				) AS recent_posts,
+				u.followers_count
+			FROM candidates c
+			JOIN users u ON u.id = c.suggested_id
+			WHERE c.user_id <> c.suggested_id
+				AND u.is_activated = true
+				AND NOT EXISTS (SELECT 1 FROM followers f WHERE f.user_id = c.suggested_id AND f.follower_id = c.user_id)
+				AND ` + notBlockedSQL("c.user_id", "c.suggested_id") + `
+				AND ` + notMutedSQL("c.suggested_id", "c.user_id") + `
+		),
+		ranked AS (
+			SELECT
+				*,
+				mutual_count * 3 + shared_tags_count * 2 + LEAST(recent_posts, 10) * 0.5 + ln(1 + followers_count) AS score
+			FROM scored
+		),
+		limited AS (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY score DESC, suggested_id) AS position
+			FROM ranked
+		)
+		INSERT INTO follow_suggestions (user_id, suggested_id, score, mutual_count, shared_tags_count, computed_at)
+		SELECT user_id, suggested_id, score, mutual_count, shared_tags_count, $2
+		FROM limited
+		WHERE position <= $3
+		`
+
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		locked, err := tryAdvisoryLock(ctx, tx, suggestionsLockID)
+		if err != nil || !locked {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`DELETE FROM follow_suggestions WHERE user_id = ANY($1)`,
+			pq.Array(userIDs),
+		); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, query, pq.Array(userIDs), now, SuggestionsPerUser); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO follow_suggestion_runs (user_id, computed_at)
+			SELECT unnest($1::BIGINT[]), $2
+			ON CONFLICT (user_id) DO UPDATE SET computed_at = EXCLUDED.computed_at`,
+			pq.Array(userIDs),
+			now,
+		)
+		return err
+	})
+}
+
+// GetByUserID returns the stored suggestions of a user, best first. Users
+// followed, requested, blocked or muted since the last run are left out.
+func (store *SuggestionStore) GetByUserID(ctx context.Context, userID int64) ([]Suggestion, error) {
+	query := `
+		SELECT u.id, u.username, u.followers_count, s.score, s.mutual_count, s.shared_tags_count
+		FROM follow_suggestions s
+		JOIN users u ON s.suggested_id = u.id
+		WHERE s.user_id = $1
+			AND u.is_activated = true
+			AND NOT EXISTS (SELECT 1 FROM followers f WHERE f.user_id = u.id AND f.follower_id = $1)
+			AND NOT EXISTS (SELECT 1 FROM follow_requests fr WHERE fr.user_id = u.id AND fr.requester_id = $1)
+			AND ` + notBlockedSQL("u.id", "$1") + `
+			AND ` + notMutedSQL("u.id", "$1") + `
+		ORDER BY s.score DESC, u.id
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []Suggestion{}
+	for rows.Next() {
+		var suggestion Suggestion
+		if err := rows.Scan(
+			&suggestion.ID,
+			&suggestion.Username,
+			&suggestion.FollowersCount,
+			&suggestion.Score,
+			&suggestion.MutualCount,
+			&suggestion.SharedTagsCount,
+		); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSuggestions(t *testing.T) {
+	db := newTestDB(t)
+	store := &SuggestionStore{db: db}
+	follows := &FollowStore{db: db}
+	ctx := context.Background()
+
+	user := insertUser(t, db, "user")
+	friend := insertUser(t, db, "friend")
+	friendOfFriend := insertUser(t, db, "friend_of_friend")
+	tagged := insertUser(t, db, "tagged")
+	blocked := insertUser(t, db, "blocked")
+	muted := insertUser(t, db, "muted")
+
+	for _, follow := range [][2]int64{
+		{friend, user},
+		{friendOfFriend, friend},
+		{blocked, friend},
+		{muted, friend},
+	} {
+		mustExec(t, db, `INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)`, follow[0], follow[1])
+	}
+	mustExec(t, db, `INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)`, blocked, user)
+	mustExec(t, db, `INSERT INTO user_mutes (muter_id, muted_id) VALUES ($1, $2)`, user, muted)
+	mustExec(t, db, `INSERT INTO user_tag_follows (user_id, tag) VALUES ($1, 'go')`, user)
+	mustExec(t, db, `
+		INSERT INTO posts (user_id, title, content, tags, published_at)
+		VALUES ($1, 'title', 'content', '{go}', now())
+		`, tagged)
+
+	// the follows above predate the run, only the ones made after it make the
+	// suggestions stale
+	mustExec(t, db, `UPDATE followers SET created_at = created_at - interval '1 hour'`)
+	mustExec(t, db, `UPDATE user_tag_follows SET created_at = created_at - interval '1 hour'`)
+	computedAt := time.Now().Add(-time.Minute)
+	if err := store.Compute(ctx, []int64{user}, computedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	suggestions, err := store.GetByUserID(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type entry struct {
+		id         int64
+		mutual     int64
+		sharedTags int64
+	}
+	got := []entry{}
+	for _, suggestion := range suggestions {
+		got = append(got, entry{suggestion.ID, suggestion.MutualCount, suggestion.SharedTagsCount})
+	}
+	want := []entry{{friendOfFriend, 1, 0}, {tagged, 0, 1}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	stale, err := store.Stale(ctx, time.Now(), time.Hour, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(stale, user) {
+		t.Errorf("expected the suggestions of the user to be fresh, got %v", stale)
+	}
+
+	if _, err := follows.Follow(ctx, friendOfFriend, user); err != nil {
+		t.Fatal(err)
+	}
+
+	suggestions, err = store.GetByUserID(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions) != 1 || suggestions[0].ID != tagged {
+		t.Errorf("expected the followed suggestion to be left out, got %+v", suggestions)
+	}
+
+	stale, err = store.Stale(ctx, time.Now(), time.Hour, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(stale, user) {
+		t.Errorf("expected following someone to make the suggestions stale, got %v", stale)
+	}
+}
+
+func TestSuggestionsLock(t *testing.T) {
+	db := newTestDB(t)
+	store := &SuggestionStore{db: db}
+	user := insertUser(t, db, "user")
+	insertUser(t, db, "other")
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRow(`SELECT pg_try_advisory_xact_lock($1)`, int64(suggestionsLockID)).Scan(&locked); err != nil {
+		t.Fatal(err)
+	}
+	if !locked {
+		t.Fatal("expected to take the suggestions lock")
+	}
+
+	if err := store.Compute(context.Background(), []int64{user}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	var runs int64
+	if err := db.QueryRow(`SELECT COUNT(*) FROM follow_suggestion_runs`).Scan(&runs); err != nil {
+		t.Fatal(err)
+	}
+	if runs != 0 {
+		t.Errorf("expected no run while the lock is held, got %d", runs)
+	}
+}
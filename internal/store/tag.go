@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MaxPostTags is how many tags a post can carry, hashtags included.
+	MaxPostTags = 10
+	// MaxTagLength is the length of a tag in Unicode code points.
+	MaxTagLength = 50
+)
+
+var ErrInvalidTag = errors.New("tags are made of letters, digits and underscores, not only digits")
+
+// hashtagPattern matches #tag, not when glued to a word or within an HTML
+// entity or a URL fragment.
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{M}\p{N}_#&/])#([\p{L}\p{M}\p{N}_]+)`)
+
+type Tag struct {
+	Tag        string `json:"tag"`
+	UsageCount int64  `json:"usage_count"`
+}
+
+// TagQuery looks up the tags starting with Prefix, for autocomplete.
+type TagQuery struct {
+	Prefix string `json:"q" validate:"required,max=50"`
+	Limit  int64  `json:"limit" validate:"gte=1,lte=20"`
+}
+
+func (tq TagQuery) Parse(r *http.Request) (TagQuery, error) {
+	query := r.URL.Query()
+
+	tq.Prefix = NormalizeTag(query.Get("q"))
+
+	limit := query.Get("limit")
+	if limit != "" {
+		l, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			return tq, err
+		}
+		tq.Limit = l
+	}
+
+	return tq, nil
+}
+
+// NormalizeTag folds the compatibility forms and the case of a tag, so #Café,
+// #café and #ＣＡＦＥ́ are the same tag. A leading # is dropped. Stored tags are
+// in this form. The normalize_tag SQL function of the migrations is the same
+// on a UTF-8 database with a C.UTF-8 or ICU ctype, which lower() folds case by.
+func NormalizeTag(tag string) string {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	return strings.ToLower(norm.NFKC.String(tag))
+}
+
+// validTag reports whether a normalized tag could be written as a hashtag.
+func validTag(tag string) bool {
+	if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength {
+		return false
+	}
+
+	digits := true
+	for _, r := range tag {
+		switch {
+		case unicode.IsDigit(r):
+		case unicode.IsLetter(r), unicode.IsMark(r), unicode.IsNumber(r), r == '_':
+			digits = false
+		default:
+			return false
+		}
+	}
+	return !digits
+}
+
+// hashtags returns the distinct normalized tags written as #tag in text, in
+// order. Hashtags that are not valid tags stay plain text.
+func hashtags(text string) []string {
+	tags := []string{}
+	seen := make(map[string]bool)
+	for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		tag := NormalizeTag(match[1])
+		if seen[tag] || !validTag(tag) {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// PostTags merges the tags given explicitly with the hashtags of the content,
+// normalized and without duplicates. ErrInvalidTag is returned for an invalid
+// explicit tag, and an error when there are more than MaxPostTags.
+func PostTags(explicit []string, content string) ([]string, error) {
+	tags := []string{}
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	for _, tag := range explicit {
+		tag = NormalizeTag(tag)
+		if !validTag(tag) {
+			return nil, ErrInvalidTag
+		}
+		add(tag)
+	}
+	for _, tag := range hashtags(content) {
+		add(tag)
+	}
+
+	if len(tags) > MaxPostTags {
+		return nil, fmt.Errorf("a post can carry at most %d tags, hashtags included", MaxPostTags)
+	}
+	return tags, nil
+}
+
+// ExplicitTags returns the tags of a post that do not come from the hashtags
+// of its content, so they are kept when the content is edited. They are
+// normalized, and tags stored before they were checked are dropped when they
+// are not valid, so editing the content never fails on them.
+func ExplicitTags(tags []string, content string) []string {
+	seen := make(map[string]bool)
+	for _, tag := range hashtags(content) {
+		seen[tag] = true
+	}
+
+	explicit := []string{}
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if seen[tag] || !validTag(tag) {
+			continue
+		}
+		seen[tag] = true
+		explicit = append(explicit, tag)
+	}
+	return explicit
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+type TagStore struct {
+	db *sql.DB
+}
+
+// Autocomplete returns the tags in use starting with the normalized prefix,
+// the most used first. Usage counts are maintained by a trigger on posts.
+func (store *TagStore) Autocomplete(ctx context.Context, tq TagQuery) ([]Tag, error) {
+	query := `
+		SELECT tag, usage_count
+		FROM tags
+		WHERE tag LIKE $1 || '%' AND usage_count > 0
+		ORDER BY usage_count DESC, tag
+		LIMIT $2
+		`
+
+	// escape the LIKE wildcards, tags can contain underscores
+	pattern := likeEscaper.Replace(tq.Prefix)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, pattern, tq.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Tag, &tag.UsageCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+type TagFollow struct {
+	UserID    int64  `json:"user_id"`
+	Tag       string `json:"tag"`
+	CreatedAt string `json:"created_at"`
+}
+
+type TagFollowStore struct {
+	db *sql.DB
+}
+
+func (store *TagFollowStore) Follow(ctx context.Context, userID int64, tag string) error {
+	query := `
+		INSERT INTO user_tag_follows (user_id, tag)
+		VALUES ($1, $2)
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := store.db.ExecContext(ctx, query, userID, NormalizeTag(tag))
+	return constraintError(err)
+}
+
+func (store *TagFollowStore) Unfollow(ctx context.Context, userID int64, tag string) error {
+	query := `
+		DELETE FROM user_tag_follows
+		WHERE user_id = $1 AND tag = $2
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := store.db.ExecContext(ctx, query, userID, NormalizeTag(tag))
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func (store *TagFollowStore) GetByUserID(ctx context.Context, userID int64) ([]TagFollow, error) {
+	query := `
+		SELECT user_id, tag, created_at
+		FROM user_tag_follows
+		WHERE user_id = $1
+		ORDER BY tag
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	follows := []TagFollow{}
+	for rows.Next() {
+		var follow TagFollow
+		if err := rows.Scan(&follow.UserID, &follow.Tag, &follow.CreatedAt); err != nil {
+			return nil, err
+		}
+		follows = append(follows, follow)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return follows, nil
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestFeedFollowedTags(t *testing.T) {
+	db := newTestDB(t)
+	tags := &TagFollowStore{db: db}
+	posts := &PostStore{db: db}
+	ctx := context.Background()
+
+	reader := insertUser(t, db, "reader")
+	friend := insertUser(t, db, "friend")
+	stranger := insertUser(t, db, "stranger")
+	mustExec(t, db, `INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)`, friend, reader)
+
+	insertTagged := func(userID int64, tags, visibility string) int64 {
+		return insertID(t, db, `
+			INSERT INTO posts (user_id, title, content, tags, visibility, published_at)
+			VALUES ($1, 'title', 'content', $2, $3, now())
+			RETURNING id
+			`, userID, tags, visibility)
+	}
+
+	own := insertTagged(reader, "{}", "public")
+	followed := insertTagged(friend, "{go}", "public")
+	tagged := insertTagged(stranger, "{go,web}", "public")
+	insertTagged(stranger, "{rust}", "public")
+	insertTagged(stranger, "{go}", "followers")
+
+	if err := tags.Follow(ctx, reader, "#Go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tags.Follow(ctx, reader, "go"); err != ErrConflict {
+		t.Errorf("expected following a tag twice to be a conflict, got %v", err)
+	}
+
+	feed, err := posts.GetByUserId(ctx, reader, PaginationFeedQuery{Limit: 20, Sort: "desc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type entry struct {
+		reason  string
+		matched string
+	}
+	want := map[int64]entry{
+		own:      {FeedReasonOwn, "[]"},
+		followed: {FeedReasonFollowing, "[go]"},
+		tagged:   {FeedReasonFollowedTag, "[go]"},
+	}
+
+	got := map[int64]entry{}
+	for _, post := range feed {
+		got[post.ID] = entry{post.FeedReason, fmt.Sprint(post.MatchedTags)}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if err := tags.Unfollow(ctx, reader, "GO"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tags.Unfollow(ctx, reader, "go"); err != ErrorNotFound {
+		t.Errorf("expected ErrorNotFound, got %v", err)
+	}
+}
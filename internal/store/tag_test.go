@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+func TestExplicitTags(t *testing.T) {
+	tests := map[string]struct {
+		tags    []string
+		content string
+		want    []string
+	}{
+		"no hashtags": {
+			tags:    []string{"go", "web"},
+			content: "content",
+			want:    []string{"go", "web"},
+		},
+		"hashtags of the content": {
+			tags:    []string{"go", "web"},
+			content: "written in #Go",
+			want:    []string{"web"},
+		},
+		"not normalized": {
+			tags:    []string{"Café", "#WEB", "web"},
+			content: "content",
+			want:    []string{"café", "web"},
+		},
+		"invalid": {
+			tags:    []string{"c++", "2024", "", "go"},
+			content: "content",
+			want:    []string{"go"},
+		},
+		"none": {
+			tags:    nil,
+			content: "#go",
+			want:    []string{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := ExplicitTags(tt.tags, tt.content)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) || got == nil {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTagCounts(t *testing.T) {
+	db := newTestDB(t)
+	author := insertUser(t, db, "author")
+
+	count := func(t *testing.T, tag string) int64 {
+		t.Helper()
+
+		var usage int64
+		err := db.QueryRow(`SELECT usage_count FROM tags WHERE tag = $1`, tag).Scan(&usage)
+		if err == sql.ErrNoRows {
+			return 0
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return usage
+	}
+
+	check := func(t *testing.T, want map[string]int64) {
+		t.Helper()
+
+		for tag, usage := range want {
+			if got := count(t, tag); got != usage {
+				t.Errorf("expected %s to be used %d times, got %d", tag, usage, got)
+			}
+		}
+	}
+
+	first := insertPost(t, db, author, "public")
+	second := insertPost(t, db, author, "public")
+
+	t.Run("should count a tag once per post whatever its form", func(t *testing.T) {
+		mustExec(t, db, `UPDATE posts SET tags = '{Go,go,rust}' WHERE id = $1`, first)
+		mustExec(t, db, `UPDATE posts SET tags = '{ＧＯ}' WHERE id = $1`, second)
+
+		check(t, map[string]int64{"go": 2, "rust": 1})
+	})
+
+	t.Run("should move the count to the new tags", func(t *testing.T) {
+		mustExec(t, db, `UPDATE posts SET tags = '{go,zig}' WHERE id = $1`, first)
+
+		check(t, map[string]int64{"go": 2, "rust": 0, "zig": 1})
+	})
+
+	t.Run("should not count posts that are not live", func(t *testing.T) {
+		mustExec(t, db, `UPDATE posts SET status = 'draft' WHERE id = $1`, first)
+		mustExec(t, db, `UPDATE posts SET deleted_at = now() WHERE id = $1`, second)
+
+		check(t, map[string]int64{"go": 0, "zig": 0})
+	})
+
+	t.Run("should count posts again once live", func(t *testing.T) {
+		mustExec(t, db, `UPDATE posts SET status = 'published' WHERE id = $1`, first)
+		mustExec(t, db, `UPDATE posts SET deleted_at = NULL WHERE id = $1`, second)
+
+		check(t, map[string]int64{"go": 2, "zig": 1})
+	})
+
+	t.Run("should uncount deleted rows", func(t *testing.T) {
+		mustExec(t, db, `DELETE FROM posts WHERE id = $1`, second)
+
+		check(t, map[string]int64{"go": 1, "zig": 1})
+	})
+}
+
+func TestTagAutocomplete(t *testing.T) {
+	db := newTestDB(t)
+	store := &TagStore{db: db}
+
+	mustExec(t, db, `
+		INSERT INTO tags (tag, usage_count)
+		VALUES ('go', 5), ('golang', 9), ('go_lang', 1), ('gopher', 5), ('goroutine', 0), ('rust', 7)
+		`)
+
+	tests := map[string]struct {
+		prefix string
+		limit  int64
+		want   []string
+	}{
+		"most used first": {"go", 10, []string{"golang", "go", "gopher", "go_lang"}},
+		"limited":         {"go", 2, []string{"golang", "go"}},
+		"underscore":      {"go_", 10, []string{"go_lang"}},
+		"percent sign":    {"%", 10, []string{}},
+		"no match":        {"py", 10, []string{}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tags, err := store.Autocomplete(context.Background(), TagQuery{Prefix: tt.prefix, Limit: tt.limit})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, tag := range tags {
+				got = append(got, tag.Tag)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	TrashKindPost    = "post"
+	TrashKindComment = "comment"
+)
+
+// TrashItem is a deleted post or comment that can still be restored. Title is
+// empty for comments, PostID is the post itself for posts.
+type TrashItem struct {
+	Kind      string `json:"kind"`
+	ID        int64  `json:"id"`
+	PostID    int64  `json:"post_id"`
+	UserID    int64  `json:"user_id"`
+	Title     string `json:"title"`
+	Content   string `json:"content"`
+	DeletedAt string `json:"deleted_at"`
+	// DeletedBy is the author or the moderator who deleted it, unset once that
+	// user is gone and for comments deleted before it was recorded.
+	DeletedBy *int64 `json:"deleted_by"`
+}
+
+type TrashStore struct {
+	db *sql.DB
+}
+
+// trashSQL lists posts and comments in the trash since $1, a bound time.
+const trashSQL = `
+	SELECT 'post' AS kind, p.id, p.id AS post_id, p.user_id, p.title, p.content, p.deleted_at, p.deleted_by
+	FROM posts p
+	WHERE p.deleted_at >= $1
+	UNION ALL
+	SELECT 'comment', c.id, c.post_id, c.user_id, '', c.content, c.deleted_at, c.deleted_by
+	FROM comments c
+	WHERE c.deleted_at >= $1
+	`
+
+// List returns the posts and comments of a user deleted since the given time,
+// most recently deleted first.
+func (store *TrashStore) List(ctx context.Context, userID int64, since time.Time, page PaginationQuery) ([]TrashItem, error) {
+	query := `
+		SELECT kind, id, post_id, user_id, title, content, deleted_at, deleted_by
+		FROM (` + trashSQL + `) t
+		WHERE t.user_id = $2
+		ORDER BY t.deleted_at DESC, t.id DESC
+		LIMIT $3 OFFSET $4
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, since, userID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []TrashItem{}
+	for rows.Next() {
+		var item TrashItem
+		if err := rows.Scan(
+			&item.Kind,
+			&item.ID,
+			&item.PostID,
+			&item.UserID,
+			&item.Title,
+			&item.Content,
+			&item.DeletedAt,
+			&item.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Get returns a post or comment deleted since the given time, ErrorNotFound
+// once it is out of the trash.
+func (store *TrashStore) Get(ctx context.Context, kind string, id int64, since time.Time) (*TrashItem, error) {
+	query := `
+		SELECT kind, id, post_id, user_id, title, content, deleted_at, deleted_by
+		FROM (` + trashSQL + `) t
+		WHERE t.kind = $2 AND t.id = $3
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var item TrashItem
+	err := store.db.QueryRowContext(ctx, query, since, kind, id).Scan(
+		&item.Kind,
+		&item.ID,
+		&item.PostID,
+		&item.UserID,
+		&item.Title,
+		&item.Content,
+		&item.DeletedAt,
+		&item.DeletedBy,
+	)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &item, nil
+}
+
+// Restore takes a post or comment deleted since the given time out of the
+// trash. A post comes back with its comments, which were hidden along with it.
+// A repost made again since it was deleted cannot come back, ErrConflict is
+// returned instead.
+func (store *TrashStore) Restore(ctx context.Context, kind string, id int64, since time.Time) error {
+	query := `UPDATE posts SET deleted_at = NULL, deleted_by = NULL WHERE id = $1 AND deleted_at >= $2`
+	if kind == TrashKindComment {
+		query = `UPDATE comments SET deleted_at = NULL, deleted_by = NULL WHERE id = $1 AND deleted_at >= $2`
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := store.db.ExecContext(ctx, query, id, since)
+	if err := constraintError(err); err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrorNotFound
+	}
+	return nil
+}
+
+// Purge hard-deletes up to limit posts and up to limit comments deleted before
+// the given time, oldest first, and returns how many rows went. Callers purge
+// batch after batch until nothing is left. A deleted comment still holding
+// replies waits until they are purged too, so replies restorable on their own
+// are kept.
+func (store *TrashStore) Purge(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var purged int64
+
+	err := withTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		queries := []string{
+			`DELETE FROM posts
+			WHERE id IN (
+				SELECT id FROM posts
+				WHERE deleted_at < $1
+				ORDER BY deleted_at
+				LIMIT $2
+			)`,
+			`DELETE FROM comments
+			WHERE id IN (
+				SELECT c.id FROM comments c
+				WHERE c.deleted_at < $1
+					AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_comment_id = c.id)
+				ORDER BY c.deleted_at
+				LIMIT $2
+			)`,
+		}
+
+		for _, query := range queries {
+			result, err := tx.ExecContext(ctx, query, before, limit)
+			if err != nil {
+				return err
+			}
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			purged += rowsAffected
+		}
+
+		return nil
+	})
+
+	return purged, err
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTrashPurge(t *testing.T) {
+	db := newTestDB(t)
+	store := &TrashStore{db: db}
+	ctx := context.Background()
+
+	author := insertUser(t, db, "author")
+	kept := insertPost(t, db, author, "public")
+	for range 3 {
+		post := insertPost(t, db, author, "public")
+		mustExec(t, db, `UPDATE posts SET deleted_at = now() - interval '2 days' WHERE id = $1`, post)
+	}
+
+	parent := insertComment(t, db, kept, author)
+	reply := insertComment(t, db, kept, author)
+	mustExec(t, db, `UPDATE comments SET parent_comment_id = $1 WHERE id = $2`, parent, reply)
+	mustExec(t, db, `UPDATE comments SET deleted_at = now() - interval '2 days' WHERE id IN ($1, $2)`, parent, reply)
+
+	count := func(t *testing.T, table string) int64 {
+		t.Helper()
+
+		var n int64
+		if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	before := time.Now().Add(-24 * time.Hour)
+
+	// the first batch takes 2 posts and the reply, the parent waits for it
+	purged, err := store.Purge(ctx, before, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 3 || count(t, "posts") != 2 || count(t, "comments") != 1 {
+		t.Errorf("expected a first batch of 2 posts and 1 comment, got %d rows", purged)
+	}
+
+	purged, err = store.Purge(ctx, before, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 2 || count(t, "posts") != 1 || count(t, "comments") != 0 {
+		t.Errorf("expected a second batch of 1 post and 1 comment, got %d rows", purged)
+	}
+
+	purged, err = store.Purge(ctx, before, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 0 {
+		t.Errorf("expected nothing left to purge, got %d rows", purged)
+	}
+}
@@ -0,0 +1,336 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TrendWindows are the rolling windows trends are computed over.
+var TrendWindows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+// TrendSnapshotSize is the number of tags and posts kept per window in a snapshot.
+const TrendSnapshotSize = 50
+
+// trendsLockID is the advisory lock held while computing trends, so the API
+// instances running the job do not compute snapshots at the same time.
+const trendsLockID = 7_284_011_028
+
+type TrendingTag struct {
+	Tag           string  `json:"tag"`
+	Score         float64 `json:"score"`
+	PostsCount    int64   `json:"posts_count"`
+	CommentsCount int64   `json:"comments_count"`
+	Rank          int     `json:"rank"`
+	ComputedAt    string  `json:"computed_at"`
+}
+
+type TrendingPost struct {
+	Post          Post    `json:"post"`
+	Score         float64 `json:"score"`
+	CommentsCount int64   `json:"comments_count"`
+	Rank          int     `json:"rank"`
+	ComputedAt    string  `json:"computed_at"`
+	// HiddenBy is set when one of the viewer's mute filters asks to warn about the post.
+	HiddenBy *MuteFilterMatch `json:"hidden_by,omitempty"`
+}
+
+type TrendQuery struct {
+	Window string `json:"window" validate:"oneof=1h 24h 7d"`
+	Limit  int64  `json:"limit" validate:"gte=1,lte=50"`
+}
+
+func (tq TrendQuery) Parse(r *http.Request) (TrendQuery, error) {
+	query := r.URL.Query()
+
+	window := query.Get("window")
+	if window != "" {
+		tq.Window = window
+	}
+
+	limit := query.Get("limit")
+	if limit != "" {
+		l, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			return tq, err
+		}
+		tq.Limit = l
+	}
+
+	return tq, nil
+}
+
+type TrendStore struct {
+	db *sql.DB
+}
+
+// Compute stores a new snapshot of trending tags and posts for every window.
+// Posts hidden by a moderator and reposts never contribute to trends. Nothing
+// is computed while another instance is computing.
+func (store *TrendStore) Compute(ctx context.Context, now time.Time) error {
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		locked, err := tryAdvisoryLock(ctx, tx, trendsLockID)
+		if err != nil || !locked {
+			return err
+		}
+
+		for window, duration := range TrendWindows {
+			if err := store.computeTags(ctx, tx, window, duration, now); err != nil {
+				return err
+			}
+
+			if err := store.computePosts(ctx, tx, window, duration, now); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (store *TrendStore) computeTags(ctx context.Context, tx *sql.Tx, window string, duration time.Duration, now time.Time) error {
+	query := `
+		WITH recent_posts AS (
+			SELECT normalize_tag(t.tag) AS tag, COUNT(DISTINCT p.id) AS posts_count
+			FROM posts p, unnest(p.tags) AS t(tag)
+			WHERE p.published_at >= $3::timestamptz - make_interval(secs => $2)
+				AND p.moderated_at IS NULL
+				AND p.kind <> 'repost'
+				AND p.visibility = 'public'
+				AND ` + livePostSQL("p") + `
+			GROUP BY 1
+		),
+		recent_comments AS (
+			SELECT normalize_tag(t.tag) AS tag, COUNT(DISTINCT c.id) AS comments_count
+			FROM comments c
+			JOIN posts p ON c.post_id = p.id, unnest(p.tags) AS t(tag)
+			WHERE c.created_at >= $3::timestamptz - make_interval(secs => $2)
+				AND c.deleted_at IS NULL
+				AND p.moderated_at IS NULL
+				AND p.kind <> 'repost'
+				AND p.visibility = 'public'
+				AND ` + livePostSQL("p") + `
+			GROUP BY 1
+		),
+		scored AS (
+			SELECT
+				COALESCE(rp.tag, rc.tag) AS tag,
+				COALESCE(rp.posts_count, 0) AS posts_count,
+				COALESCE(rc.comments_count, 0) AS comments_count,
+				COALESCE(rp.posts_count, 0) * 2 + COALESCE(rc.comments_count, 0) AS score
+			FROM recent_posts rp
+			FULL OUTER JOIN recent_comments rc ON rp.tag = rc.tag
+		)
+		INSERT INTO trending_tags (time_window, tag, score, posts_count, comments_count, rank, computed_at)
+		SELECT $1, tag, score, posts_count, comments_count, ROW_NUMBER() OVER (ORDER BY score DESC, tag), $3
+		FROM scored
+		ORDER BY score DESC, tag
+		LIMIT $4
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, window, duration.Seconds(), now, TrendSnapshotSize)
+	return err
+}
+
+// computePosts scores posts by comment activity inside the window, decayed by
+// age so a steady old post does not outrank a new one picking up speed.
+func (store *TrendStore) computePosts(ctx context.Context, tx *sql.Tx, window string, duration time.Duration, now time.Time) error {
+	query := `
+		WITH activity AS (
+			SELECT p.id, p.published_at AS created_at, COUNT(c.id) AS comments_count
+			FROM posts p
+			LEFT JOIN comments c ON c.post_id = p.id
+				AND c.created_at >= $3::timestamptz - make_interval(secs => $2)
+				AND c.deleted_at IS NULL
+			WHERE p.moderated_at IS NULL
+				AND p.kind <> 'repost'
+				AND p.visibility = 'public'
+				AND ` + livePostSQL("p") + `
+				AND (p.published_at >= $3::timestamptz - make_interval(secs => $2) OR c.id IS NOT NULL)
+			GROUP BY p.id
+		),
+		scored AS (
+			SELECT
+				id,
+				comments_count,
+				(comments_count + 1) / power(EXTRACT(EPOCH FROM ($3::timestamptz - created_at)) / 3600 + 2, 1.5) AS score
+			FROM activity
+		)
+		INSERT INTO trending_posts (time_window, post_id, score, comments_count, rank, computed_at)
+		SELECT $1, id, score, comments_count, ROW_NUMBER() OVER (ORDER BY score DESC, id DESC), $3
+		FROM scored
+		ORDER BY score DESC, id DESC
+		LIMIT $4
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, window, duration.Seconds(), now, TrendSnapshotSize)
+	return err
+}
+
+// Prune removes snapshots computed before the given time.
+func (store *TrendStore) Prune(ctx context.Context, before time.Time) error {
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM trending_tags WHERE computed_at < $1`, before); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM trending_posts WHERE computed_at < $1`, before); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (store *TrendStore) GetTags(ctx context.Context, tq TrendQuery) ([]TrendingTag, error) {
+	query := `
+		SELECT tag, score, posts_count, comments_count, rank, computed_at
+		FROM trending_tags
+		WHERE time_window = $1
+			AND computed_at = (SELECT MAX(computed_at) FROM trending_tags WHERE time_window = $1)
+		ORDER BY rank
+		LIMIT $2
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, tq.Window, tq.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []TrendingTag{}
+	for rows.Next() {
+		var tag TrendingTag
+		if err := rows.Scan(
+			&tag.Tag,
+			&tag.Score,
+			&tag.PostsCount,
+			&tag.CommentsCount,
+			&tag.Rank,
+			&tag.ComputedAt,
+		); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// GetPosts returns the latest snapshot of trending posts as seen by the viewer,
+// with their reactions and mentions: posts of blocked or muted users and posts
+// caught by a hiding mute filter are left out.
+func (store *TrendStore) GetPosts(ctx context.Context, tq TrendQuery, viewerID int64) ([]TrendingPost, error) {
+	query := `
+		SELECT
+			p.id, p.title, p.content, p.user_id, p.tags, p.created_at, p.updated_at, p.version, p.kind,
+			p.edited_at, p.status, p.published_at, p.visibility,
+			p.reaction_counts,
+			ARRAY(SELECT r.reaction FROM post_reactions r WHERE r.post_id = p.id AND r.user_id = $3 ORDER BY r.reaction),
+			EXISTS (SELECT 1 FROM bookmarks bm WHERE bm.post_id = p.id AND bm.user_id = $3),
+			u.id, u.username,
+			` + postMediaSQL("p") + `,
+			` + postPollSQL("p", "$3") + `,
+			` + mentionedUsersSQL("post_mentions", "post_id", "p.id") + `,
+			t.score, t.comments_count, t.rank, t.computed_at,
+			mf.id, mf.kind, mf.value
+		FROM trending_posts t
+		JOIN posts p ON t.post_id = p.id
+		JOIN users u ON p.user_id = u.id
+		` + muteFilterJoinSQL("$3", "p.title || ' ' || p.content", "p.tags") + `
+		WHERE t.time_window = $1
+			AND t.computed_at = (SELECT MAX(computed_at) FROM trending_posts WHERE time_window = $1)
+			AND p.moderated_at IS NULL
+			AND p.deleted_at IS NULL
+			AND p.visibility = 'public'
+			AND NOT u.is_private
+			AND ` + notBlockedSQL("p.user_id", "$3") + `
+			AND ` + notMutedSQL("p.user_id", "$3") + `
+			AND ` + notHiddenByFilterSQL + `
+		ORDER BY t.rank
+		LIMIT $2
+		`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := store.db.QueryContext(ctx, query, tq.Window, tq.Limit, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []TrendingPost{}
+	for rows.Next() {
+		var trending TrendingPost
+		var filter muteFilterColumns
+		var poll []byte
+		var mentions mentionedUsers
+		trending.Post.User = &User{}
+		if err := rows.Scan(append([]any{
+			&trending.Post.ID,
+			&trending.Post.Title,
+			&trending.Post.Content,
+			&trending.Post.UserID,
+			pq.Array(&trending.Post.Tags),
+			&trending.Post.CreatedAt,
+			&trending.Post.UpdatedAt,
+			&trending.Post.Version,
+			&trending.Post.Kind,
+			&trending.Post.EditedAt,
+			&trending.Post.Status,
+			&trending.Post.PublishedAt,
+			&trending.Post.Visibility,
+			&trending.Post.ReactionCounts,
+			pq.Array(&trending.Post.MyReactions),
+			&trending.Post.Bookmarked,
+			&trending.Post.User.ID,
+			&trending.Post.User.Username,
+			&trending.Post.Media,
+			&poll,
+			&mentions,
+			&trending.Score,
+			&trending.CommentsCount,
+			&trending.Rank,
+			&trending.ComputedAt,
+		}, filter.dest()...)...); err != nil {
+			return nil, err
+		}
+		trending.HiddenBy = filter.match()
+		trending.Post.Mentions = mentions.entities(trending.Post.Content)
+		if trending.Post.Poll, err = scanPoll(poll); err != nil {
+			return nil, err
+		}
+		posts = append(posts, trending)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
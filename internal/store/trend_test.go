@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTrendingTagsReposts(t *testing.T) {
+	db := newTestDB(t)
+	store := &TrendStore{db: db}
+	author := insertUser(t, db, "author")
+	reposter := insertUser(t, db, "reposter")
+
+	original := insertID(t, db, `
+		INSERT INTO posts (user_id, title, content, tags, published_at)
+		VALUES ($1, 'title', 'content', '{go}', now())
+		RETURNING id
+		`, author)
+	mustExec(t, db, `
+		INSERT INTO posts (user_id, title, content, tags, kind, original_id, published_at)
+		VALUES ($1, 'title', 'content', '{go}', 'repost', $2, now())
+		`, reposter, original)
+
+	if err := store.Compute(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	var postsCount int64
+	err := db.QueryRow(`
+		SELECT posts_count FROM trending_tags WHERE time_window = '24h' AND tag = 'go'
+		`).Scan(&postsCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if postsCount != 1 {
+		t.Errorf("expected the tag to count 1 post, got %d", postsCount)
+	}
+}
+
+func TestTrendsLock(t *testing.T) {
+	db := newTestDB(t)
+	store := &TrendStore{db: db}
+	author := insertUser(t, db, "author")
+
+	mustExec(t, db, `
+		INSERT INTO posts (user_id, title, content, tags, published_at)
+		VALUES ($1, 'title', 'content', '{go}', now())
+		`, author)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRow(`SELECT pg_try_advisory_xact_lock($1)`, int64(trendsLockID)).Scan(&locked); err != nil {
+		t.Fatal(err)
+	}
+	if !locked {
+		t.Fatal("expected to take the trends lock")
+	}
+
+	if err := store.Compute(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	var snapshots int64
+	if err := db.QueryRow(`SELECT COUNT(*) FROM trending_tags`).Scan(&snapshots); err != nil {
+		t.Fatal(err)
+	}
+	if snapshots != 0 {
+		t.Errorf("expected no snapshot while the lock is held, got %d tags", snapshots)
+	}
+}
+
+func TestTrendingPosts(t *testing.T) {
+	db := newTestDB(t)
+	store := &TrendStore{db: db}
+	author := insertUser(t, db, "author")
+
+	post := insertID(t, db, `
+		INSERT INTO posts (user_id, title, content, tags, published_at, edited_at)
+		VALUES ($1, 'title', 'content', '{go}', now(), now())
+		RETURNING id
+		`, author)
+	mustExec(t, db, `
+		INSERT INTO trending_posts (time_window, post_id, score, rank, computed_at)
+		VALUES ('24h', $1, 1, 1, now())
+		`, post)
+
+	trending, err := store.GetPosts(context.Background(), TrendQuery{Window: "24h", Limit: 10}, author)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trending) != 1 {
+		t.Fatalf("expected a trending post, got %d", len(trending))
+	}
+
+	got := trending[0].Post
+	if got.Status != PostStatusPublished || got.Visibility != PostVisibilityPublic || got.PublishedAt == nil || got.EditedAt == nil {
+		t.Errorf("expected the post as GET /posts returns it, got %+v", got)
+	}
+}
@@ -18,10 +18,23 @@ type User struct {
 	CreatedAt   string   `json:"created_at"`
 	UpdatedAt   string   `json:"updated_at"`
 	IsActivated bool     `json:"is_activated"`
+	IsPrivate   bool     `json:"is_private"`
 	RoleID      int64    `json:"role_id"`
 	Role        Role     `json:"role"`
 }
 
+// UserProfile is a user as seen by another user. The counters are maintained
+// by triggers on followers and posts, IsFollowing is relative to the viewer.
+type UserProfile struct {
+	User
+	FollowersCount int64 `json:"followers_count"`
+	FollowingCount int64 `json:"following_count"`
+	PostsCount     int64 `json:"posts_count"`
+	IsFollowing    bool  `json:"is_following"`
+	IsRequested    bool  `json:"is_requested"`
+	IsMuted        bool  `json:"is_muted"`
+}
+
 type password struct {
 	text *string
 	hash []byte
@@ -99,7 +112,7 @@ func (store *UserStore) Create(ctx context.Context, tx *sql.Tx, user *User) erro
 
 func (store *UserStore) GetById(ctx context.Context, id int64) (*User, error) {
 	query := `
-		SELECT u.id, u.username, u.email, u.created_at, u.updated_at, r.id, r.name, r.level 
+		SELECT u.id, u.username, u.email, u.created_at, u.updated_at, u.is_private, r.id, r.name, r.level
 		FROM users u
 		JOIN roles r ON u.role_id = r.id
 		WHERE u.id = $1
@@ -120,6 +133,7 @@ func (store *UserStore) GetById(ctx context.Context, id int64) (*User, error) {
 		&user.Email,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.IsPrivate,
 		&user.Role.ID,
 		&user.Role.Name,
 		&user.Role.Level,
@@ -136,6 +150,102 @@ func (store *UserStore) GetById(ctx context.Context, id int64) (*User, error) {
 	return user, nil
 }
 
+func (store *UserStore) GetProfile(ctx context.Context, id, viewerID int64) (*UserProfile, error) {
+	query := `
+		SELECT u.id, u.username, u.email, u.created_at, u.updated_at, u.is_private, r.id, r.name, r.level,
+			u.followers_count, u.following_count, u.posts_count,
+			EXISTS (SELECT 1 FROM followers f WHERE f.user_id = u.id AND f.follower_id = $2),
+			EXISTS (SELECT 1 FROM follow_requests fr WHERE fr.user_id = u.id AND fr.requester_id = $2),
+			EXISTS (SELECT 1 FROM user_mutes m WHERE m.muter_id = $2 AND m.muted_id = u.id)
+		FROM users u
+		JOIN roles r ON u.role_id = r.id
+		WHERE u.id = $1
+		AND u.is_activated = true
+		AND ` + notBlockedSQL("u.id", "$2") + `
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	profile := &UserProfile{}
+	err := store.db.QueryRowContext(
+		ctx,
+		query,
+		id,
+		viewerID,
+	).Scan(
+		&profile.ID,
+		&profile.Username,
+		&profile.Email,
+		&profile.CreatedAt,
+		&profile.UpdatedAt,
+		&profile.IsPrivate,
+		&profile.Role.ID,
+		&profile.Role.Name,
+		&profile.Role.Level,
+		&profile.FollowersCount,
+		&profile.FollowingCount,
+		&profile.PostsCount,
+		&profile.IsFollowing,
+		&profile.IsRequested,
+		&profile.IsMuted,
+	)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return profile, nil
+}
+
+// SetPrivacy switches an account between public and private. Going public
+// approves every pending follow request, since there is nothing left to gate.
+func (store *UserStore) SetPrivacy(ctx context.Context, userID int64, isPrivate bool) error {
+	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE users SET is_private = $2, updated_at = now() WHERE id = $1`,
+			userID,
+			isPrivate,
+		)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrorNotFound
+		}
+
+		if isPrivate {
+			return nil
+		}
+
+		query := `
+			WITH approved AS (
+				DELETE FROM follow_requests
+				WHERE user_id = $1
+				RETURNING user_id, requester_id
+			)
+			INSERT INTO followers (user_id, follower_id)
+			SELECT user_id, requester_id FROM approved
+			ON CONFLICT DO NOTHING
+			`
+
+		_, err = tx.ExecContext(ctx, query, userID)
+		return err
+	})
+}
+
 func (store *UserStore) CreateAndInvite(ctx context.Context, user *User, hashedToken string, invitationExp time.Duration) error {
 	return withTx(store.db, ctx, func(tx *sql.Tx) error {
 		// create a user
@@ -152,10 +262,14 @@ func (store *UserStore) CreateAndInvite(ctx context.Context, user *User, hashedT
 	})
 }
 
-func (store *UserStore) Activate(ctx context.Context, token string) error {
-	return withTx(store.db, ctx, func(tx *sql.Tx) error {
+func (store *UserStore) Activate(ctx context.Context, token string) (*User, error) {
+	var user *User
+
+	err := withTx(store.db, ctx, func(tx *sql.Tx) error {
+		var err error
+
 		// find the user that the token belongs to
-		user, err := store.getUserFromInvitation(ctx, tx, token)
+		user, err = store.getUserFromInvitation(ctx, tx, token)
 		if err != nil {
 			log.Print("error get user")
 			return err
@@ -177,7 +291,11 @@ func (store *UserStore) Activate(ctx context.Context, token string) error {
 		return nil
 
 	})
+	if err != nil {
+		return nil, err
+	}
 
+	return user, nil
 }
 
 func (store *UserStore) update(ctx context.Context, tx *sql.Tx, user *User) error {
@@ -0,0 +1,64 @@
+package store
+
+import "fmt"
+
+// canViewAuthorSQL returns a predicate telling whether the viewer may see
+// content written by the author: never when either blocked the other, always
+// for their own content and for public accounts, and for private accounts only
+// once a follow request was approved.
+//
+// Both arguments are SQL expressions, e.g. canViewAuthorSQL("p.user_id", "$1").
+func canViewAuthorSQL(author, viewer string) string {
+	return fmt.Sprintf(`(
+		%[3]s
+		AND (
+			%[1]s = %[2]s
+			OR NOT EXISTS (SELECT 1 FROM users pu WHERE pu.id = %[1]s AND pu.is_private)
+			OR EXISTS (SELECT 1 FROM followers vf WHERE vf.user_id = %[1]s AND vf.follower_id = %[2]s)
+		)
+	)`, author, viewer, notBlockedSQL(author, viewer))
+}
+
+// canViewPostSQL returns a predicate telling whether the viewer may see the
+// post, on top of the rules of canViewAuthorSQL: public posts follow the
+// account rules, followers-only posts need an approved follow, mentioned-only
+// posts a mention and private posts are for their author alone.
+//
+// post is the alias of the posts table, e.g. canViewPostSQL("p", "$1").
+func canViewPostSQL(post, viewer string) string {
+	return fmt.Sprintf(`(
+		%[3]s
+		AND (
+			%[1]s.user_id = %[2]s
+			OR (%[1]s.visibility = 'public' AND %[4]s)
+			OR (%[1]s.visibility = 'followers'
+				AND EXISTS (SELECT 1 FROM followers vf WHERE vf.user_id = %[1]s.user_id AND vf.follower_id = %[2]s))
+			OR (%[1]s.visibility = 'mentioned'
+				AND EXISTS (SELECT 1 FROM post_mentions vm WHERE vm.post_id = %[1]s.id AND vm.user_id = %[2]s))
+		)
+	)`, post, viewer, notBlockedSQL(post+".user_id", viewer), canViewAuthorSQL(post+".user_id", viewer))
+}
+
+// notBlockedSQL is true when neither user blocked the other.
+func notBlockedSQL(a, b string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM user_blocks ub
+		WHERE (ub.blocker_id = %[1]s AND ub.blocked_id = %[2]s)
+			OR (ub.blocker_id = %[2]s AND ub.blocked_id = %[1]s)
+	)`, a, b)
+}
+
+// notMutedSQL is true when the viewer did not mute the author.
+func notMutedSQL(author, viewer string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM user_mutes um
+		WHERE um.muter_id = %[2]s AND um.muted_id = %[1]s
+	)`, author, viewer)
+}
+
+// livePostSQL is true for published posts that are not in the trash. Drafts
+// and scheduled posts are only ever shown to their author, deleted posts only
+// in the trash.
+func livePostSQL(post string) string {
+	return fmt.Sprintf(`(%s.status = 'published' AND %s.deleted_at IS NULL)`, post, post)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"sync"
 )
 
@@ -13,11 +14,25 @@ type UpdatePostPayload struct {
 	Content *string `json:"content"`
 }
 
-func updatePost(postID int, p UpdatePostPayload, wg *sync.WaitGroup) {
-	defer wg.Done()
+// getETag fetches the post to learn the version both users start editing from.
+func getETag(url string) (string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("TOKEN"))
 
-	// Construct the URL for the update endpoint
-	url := fmt.Sprintf("http://localhost:8080/v1/posts/%d", postID)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	return resp.Header.Get("ETag"), nil
+}
+
+func updatePost(url, etag string, p UpdatePostPayload, wg *sync.WaitGroup) {
+	defer wg.Done()
 
 	// Create the JSON payload
 	b, _ := json.Marshal(p)
@@ -30,6 +45,9 @@ func updatePost(postID int, p UpdatePostPayload, wg *sync.WaitGroup) {
 
 	// Set headers as needed, for example:
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("TOKEN"))
+	// Both updates claim the same version, only one of them can win
+	req.Header.Set("If-Match", etag)
 
 	// Send the request
 	client := &http.Client{}
@@ -49,12 +67,22 @@ func main() {
 	// Assuming the post ID to update is 1
 	postID := 4
 
-	// Simulate User A and User B updating the same post concurrently
+	// Construct the URL for the update endpoint
+	url := fmt.Sprintf("http://localhost:8080/v1/posts/%d", postID)
+
+	etag, err := getETag(url)
+	if err != nil {
+		fmt.Println("Error fetching post:", err)
+		return
+	}
+
+	// Simulate User A and User B updating the same post concurrently,
+	// expect one 200 OK and one 412 Precondition Failed
 	wg.Add(2)
 	content := "NEW CONTENT FROM USER B"
 	title := "NEW TITLE FROM USER A"
 
-	go updatePost(postID, UpdatePostPayload{Title: &title}, &wg)
-	go updatePost(postID, UpdatePostPayload{Content: &content}, &wg)
+	go updatePost(url, etag, UpdatePostPayload{Title: &title}, &wg)
+	go updatePost(url, etag, UpdatePostPayload{Content: &content}, &wg)
 	wg.Wait()
 }